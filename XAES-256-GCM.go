@@ -1,14 +1,21 @@
 // Package xaes256gcm implements the [XAES-256-GCM] extended-nonce AEAD, an
 // efficient combination of a NIST SP 800-108r1 KDF and AES-256-GCM.
 //
+// The AEADs returned by this package hold no mutable state after they are
+// created, and are safe for concurrent use by multiple goroutines. There is no
+// need to clone them or to create one per goroutine: the per-message AES-256
+// key is derived and discarded on each call to Seal and Open anyway.
+//
 // [XAES-256-GCM]: https://c2sp.org/XAES-256-GCM
 package xaes256gcm
 
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/rand"
 	"crypto/subtle"
 	"errors"
+	"io"
 )
 
 // KeySize is the size of XAES-256-GCM keys.
@@ -27,25 +34,26 @@ const OverheadWithManualNonces = 16
 // randomly-generated and automatically-managed nonce.
 const Overhead = 40
 
-type xaes256gcm struct {
+// Cipher is an XAES-256-GCM instance with manual nonces, like the one returned
+// by [NewWithManualNonces], as a value type. It implements [cipher.AEAD].
+//
+// A Cipher holds the expanded AES key behind the cipher.Block interface and
+// the 16-byte KDF subkey, so it can be embedded in other structs and copied
+// cheaply, without an extra pointer indirection. The zero value is not usable,
+// use [NewCipher]. Copies share the immutable expanded key, and are safe for
+// concurrent use.
+type Cipher struct {
 	c  cipher.Block
 	k1 [aes.BlockSize]byte
 }
 
-// NewWithManualNonces returns a new XAES-256-GCM instance that expects 24-byte
-// nonces to be passed to Open and Seal. nonces can be safely generated with
-// [crypto/rand.Read]. key must be exactly 32 bytes long.
-//
-// Most applications should use [New] instead, which automatically generates
-// random nonces and prepends them to the ciphertext. (Note that New is not
-// implemented yet.)
-func NewWithManualNonces(key []byte) (cipher.AEAD, error) {
+// NewCipher returns a Cipher for key, which must be exactly 32 bytes long.
+func NewCipher(key []byte) (Cipher, error) {
 	if len(key) != KeySize {
-		return nil, errors.New("xaes256gcm: bad key length")
+		return Cipher{}, errors.New("xaes256gcm: bad key length")
 	}
 
-	x := new(xaes256gcm)
-
+	var x Cipher
 	x.c, _ = aes.NewCipher(key)
 	x.c.Encrypt(x.k1[:], x.k1[:])
 
@@ -59,16 +67,107 @@ func NewWithManualNonces(key []byte) (cipher.AEAD, error) {
 	return x, nil
 }
 
-func (*xaes256gcm) NonceSize() int {
+// NonceSize returns [NonceSize].
+func (Cipher) NonceSize() int {
 	return NonceSize
 }
 
-func (*xaes256gcm) Overhead() int {
+// Overhead returns [OverheadWithManualNonces].
+func (Cipher) Overhead() int {
 	return OverheadWithManualNonces
 }
 
-func (x *xaes256gcm) deriveKey(nonce []byte) []byte {
-	k := make([]byte, 0, 2*aes.BlockSize)
+// Seal is like the Seal method of the AEAD returned by [NewWithManualNonces].
+func (x Cipher) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != NonceSize {
+		panic("xaes256gcm: bad nonce length")
+	}
+
+	return x.gcm(nonce, OverheadWithManualNonces).Seal(dst, nonce[12:], plaintext, additionalData)
+}
+
+// Open is like the Open method of the AEAD returned by [NewWithManualNonces].
+func (x Cipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		return nil, errors.New("xaes256gcm: bad nonce length")
+	}
+
+	return x.gcm(nonce, OverheadWithManualNonces).Open(dst, nonce[12:], ciphertext, additionalData)
+}
+
+type xaes256gcm struct {
+	Cipher
+	tagSize int
+}
+
+// NewWithManualNonces returns a new XAES-256-GCM instance that expects 24-byte
+// nonces to be passed to Open and Seal. nonces can be safely generated with
+// [crypto/rand.Read]. key must be exactly 32 bytes long.
+//
+// Most applications should use [New] instead, which automatically generates
+// random nonces and prepends them to the ciphertext.
+func NewWithManualNonces(key []byte) (cipher.AEAD, error) {
+	c, err := NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &xaes256gcm{Cipher: c, tagSize: OverheadWithManualNonces}, nil
+}
+
+// NewWithTagSize is like [NewWithManualNonces], but generates and expects
+// authentication tags of tagSize bytes, which must be between 12 and 16.
+// Overhead returns tagSize.
+//
+// Shorter tags save space in protocols with tight per-record overhead, at the
+// cost of making forgeries more likely. Only use NewWithTagSize if required.
+// Ciphertexts are not compatible with AEADs that use a different tag size.
+func NewWithTagSize(key []byte, tagSize int) (cipher.AEAD, error) {
+	if tagSize < 12 || tagSize > 16 {
+		return nil, errors.New("xaes256gcm: bad tag size")
+	}
+	a, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	x := a.(*xaes256gcm)
+	x.tagSize = tagSize
+	return x, nil
+}
+
+func (*xaes256gcm) NonceSize() int {
+	return NonceSize
+}
+
+func (x *xaes256gcm) Overhead() int {
+	return x.tagSize
+}
+
+func (x *xaes256gcm) gcm(nonce []byte) cipher.AEAD {
+	return x.Cipher.gcm(nonce, x.tagSize)
+}
+
+func (x Cipher) gcm(nonce []byte, tagSize int) cipher.AEAD {
+	c, _ := aes.NewCipher(x.deriveKey(nonce[:12]))
+	if tagSize != OverheadWithManualNonces {
+		a, _ := cipher.NewGCMWithTagSize(c, tagSize)
+		return a
+	}
+	a, _ := cipher.NewGCM(c)
+	return a
+}
+
+func (x Cipher) deriveKey(nonce []byte) []byte {
+	return x.deriveKeyInto(make([]byte, 0, 2*aes.BlockSize), nonce)
+}
+
+// deriveKeyInto is like deriveKey, but uses k as the backing array if it has
+// enough capacity.
+//
+// The two blocks are encrypted with separate calls because crypto/aes exposes
+// no multi-block ECB API. CTR can't generate the KDF inputs, and CBC encryption
+// is serial, so pipelining across messages would require assembly.
+func (x Cipher) deriveKeyInto(k, nonce []byte) []byte {
+	k = k[:0]
 	k = append(k, 0, 1, 'X', 0)
 	k = append(k, nonce...)
 	k = append(k, 0, 2, 'X', 0)
@@ -85,10 +184,7 @@ func (x *xaes256gcm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 		panic("xaes256gcm: bad nonce length")
 	}
 
-	k, n := x.deriveKey(nonce[:12]), nonce[12:]
-	c, _ := aes.NewCipher(k)
-	a, _ := cipher.NewGCM(c)
-	return a.Seal(dst, n, plaintext, additionalData)
+	return x.gcm(nonce).Seal(dst, nonce[12:], plaintext, additionalData)
 }
 
 var errOpen = errors.New("xaes256gcm: message authentication failed")
@@ -98,8 +194,193 @@ func (x *xaes256gcm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte
 		return nil, errors.New("xaes256gcm: bad nonce length")
 	}
 
-	k, n := x.deriveKey(nonce[:12]), nonce[12:]
-	c, _ := aes.NewCipher(k)
-	a, _ := cipher.NewGCM(c)
-	return a.Open(dst, n, ciphertext, additionalData)
+	return x.gcm(nonce).Open(dst, nonce[12:], ciphertext, additionalData)
+}
+
+// New returns a new XAES-256-GCM instance that generates a random 24-byte
+// nonce for each message, and prepends it to the ciphertext. key must be
+// exactly 32 bytes long.
+//
+// The nonce passed to Seal and Open must be empty, and NonceSize returns zero.
+// Overhead returns [Overhead].
+func New(key []byte) (cipher.AEAD, error) {
+	x, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	return &randomNonces{x.(*xaes256gcm), rand.Reader}, nil
+}
+
+// NewWithRand is like [New], but reads the random nonces from rand instead of
+// [crypto/rand.Reader].
+//
+// It's meant for platforms where crypto/rand is not available, such as some
+// microcontrollers supported by TinyGo, where rand can be a hardware random
+// number generator. rand must be a cryptographically secure source: nonces
+// that repeat or are predictable compromise security. Since Seal can't return
+// an error, it panics if reading from rand fails. Applications that need to
+// handle entropy failures should use [TrySeal] instead.
+func NewWithRand(key []byte, rand io.Reader) (cipher.AEAD, error) {
+	x, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	return &randomNonces{x.(*xaes256gcm), rand}, nil
+}
+
+// NewWithNonceFunc is like [New], but Seal calls nonce to obtain the nonce of
+// each message, instead of generating a random one.
+//
+// It's meant for tests and simulations that need reproducible ciphertexts.
+// nonce must never return the same value twice for the same key, or security
+// is lost. Production code should use [New].
+func NewWithNonceFunc(key []byte, nonce func() [NonceSize]byte) (cipher.AEAD, error) {
+	x, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	return &funcNonces{randomNonces{x.(*xaes256gcm), nil}, nonce}, nil
+}
+
+type funcNonces struct {
+	randomNonces
+	nonce func() [NonceSize]byte
+}
+
+func (f *funcNonces) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != 0 {
+		panic("xaes256gcm: non-empty nonce passed to Seal with automatic nonces")
+	}
+
+	ret, _ := f.seal(dst, plaintext, additionalData, func(n []byte) error {
+		next := f.nonce()
+		copy(n, next[:])
+		return nil
+	})
+	return ret
+}
+
+// MustNew is like [New], but panics if key is not 32 bytes long. It's meant
+// for package-level variables and tests, with keys of fixed size.
+func MustNew(key []byte) cipher.AEAD {
+	a, err := New(key)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+type randomNonces struct {
+	x    *xaes256gcm
+	rand io.Reader
+}
+
+func (*randomNonces) NonceSize() int {
+	return 0
+}
+
+func (*randomNonces) Overhead() int {
+	return Overhead
+}
+
+func (r *randomNonces) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != 0 {
+		panic("xaes256gcm: non-empty nonce passed to Seal with automatic nonces")
+	}
+
+	ret, err := r.trySeal(dst, plaintext, additionalData)
+	if err != nil {
+		panic(err.Error())
+	}
+	return ret
+}
+
+func (r *randomNonces) trySeal(dst, plaintext, additionalData []byte) ([]byte, error) {
+	return r.seal(dst, plaintext, additionalData, func(n []byte) error {
+		if _, err := io.ReadFull(r.rand, n); err != nil {
+			return errors.New("xaes256gcm: failed to generate random nonce: " + err.Error())
+		}
+		return nil
+	})
+}
+
+// seal appends the nonce generated by fill and the encrypted plaintext to dst.
+//
+// If plaintext is being encrypted in place, as in Seal(plaintext[:0], nil,
+// plaintext, additionalData), it's first moved after the nonce, so that fill
+// doesn't overwrite it and the GCM output aliases it exactly.
+func (r *randomNonces) seal(dst, plaintext, additionalData []byte, fill func(nonce []byte) error) ([]byte, error) {
+	ret, out := sliceForAppend(dst, len(plaintext)+Overhead)
+	if len(plaintext) > 0 && &out[0] == &plaintext[0] {
+		copy(out[NonceSize:], plaintext)
+		plaintext = out[NonceSize : NonceSize+len(plaintext)]
+	}
+	n := out[:NonceSize]
+	if err := fill(n); err != nil {
+		return nil, err
+	}
+	return r.x.Seal(ret[:len(dst)+NonceSize], n, plaintext, additionalData), nil
+}
+
+// TrySeal is like the Seal method of an AEAD returned by [New] or
+// [NewWithRand], but returns an error if reading the random nonce fails,
+// instead of panicking. It panics if aead was not returned by New or
+// NewWithRand.
+//
+// It's meant for platforms where the random source can fail at runtime, such
+// as hardware random number generators on microcontrollers. If plaintext is
+// being encrypted in place and TrySeal fails, plaintext is overwritten.
+func TrySeal(aead cipher.AEAD, dst, plaintext, additionalData []byte) ([]byte, error) {
+	r, ok := aead.(*randomNonces)
+	if !ok {
+		panic("xaes256gcm: TrySeal requires an AEAD returned by New or NewWithRand")
+	}
+	return r.trySeal(dst, plaintext, additionalData)
+}
+
+func (r *randomNonces) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != 0 {
+		return nil, errors.New("xaes256gcm: non-empty nonce passed to Open with automatic nonces")
+	}
+	if len(ciphertext) < Overhead {
+		return nil, errOpen
+	}
+
+	var n [NonceSize]byte
+	copy(n[:], ciphertext)
+	// If ciphertext is being decrypted in place, as in Open(ciphertext[:0],
+	// nil, ciphertext, additionalData), move it over the nonce, so that the
+	// GCM output aliases it exactly.
+	if cap(dst) > len(dst) && &dst[:len(dst)+1][len(dst)] == &ciphertext[0] {
+		ciphertext = ciphertext[:copy(ciphertext, ciphertext[NonceSize:])]
+	} else {
+		ciphertext = ciphertext[NonceSize:]
+	}
+	return r.x.Open(dst, n[:], ciphertext, additionalData)
+}
+
+// BoringEnabled reports whether the AES and AES-GCM operations are performed by
+// BoringCrypto, which is the case when building with GOEXPERIMENT=boringcrypto
+// on supported platforms.
+//
+// This package uses crypto/aes and crypto/cipher for all AES operations, both
+// in the key derivation and in AES-256-GCM, so it uses BoringCrypto whenever
+// the standard library does, without any configuration. The rest of the key
+// derivation only involves XOR and shift operations.
+func BoringEnabled() bool {
+	return boringEnabled()
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and a
+// second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
 }
@@ -2,6 +2,7 @@ package xaes256gcm_test
 
 import (
 	"bytes"
+	"crypto/cipher"
 	"encoding/hex"
 	"testing"
 
@@ -47,6 +48,85 @@ func TestVectors(t *testing.T) {
 	}
 }
 
+func TestRandomNonces(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	plaintext := []byte("XAES-256-GCM")
+	aad := []byte("c2sp.org/XAES-256-GCM")
+	c, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NonceSize() != 0 {
+		t.Errorf("NonceSize() = %d", c.NonceSize())
+	}
+	prefix := []byte("prefix")
+	ciphertext := c.Seal(prefix, nil, plaintext, aad)
+	if !bytes.HasPrefix(ciphertext, prefix) {
+		t.Errorf("Seal didn't append to dst")
+	}
+	ciphertext = ciphertext[len(prefix):]
+	if len(ciphertext) != len(plaintext)+xaes256gcm.Overhead {
+		t.Errorf("unexpected ciphertext length %d", len(ciphertext))
+	}
+	if again := c.Seal(nil, nil, plaintext, aad); bytes.Equal(again[:xaes256gcm.NonceSize], ciphertext[:xaes256gcm.NonceSize]) {
+		t.Errorf("nonce reused")
+	}
+
+	// The prepended nonce works with the manual nonces API.
+	m, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decrypted, err := m.Open(nil, ciphertext[:xaes256gcm.NonceSize], ciphertext[xaes256gcm.NonceSize:], aad); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(plaintext, decrypted) {
+		t.Errorf("plaintext and decrypted are not equal")
+	}
+
+	if decrypted, err := c.Open(nil, nil, ciphertext, aad); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(plaintext, decrypted) {
+		t.Errorf("plaintext and decrypted are not equal")
+	}
+	if _, err := c.Open(nil, nil, ciphertext[:xaes256gcm.Overhead-1], aad); err == nil {
+		t.Errorf("short ciphertext opened")
+	}
+	ciphertext[0] ^= 1
+	if _, err := c.Open(nil, nil, ciphertext, aad); err == nil {
+		t.Errorf("modified nonce opened")
+	}
+}
+
+func TestRandomNoncesInPlace(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	aad := []byte("c2sp.org/XAES-256-GCM")
+	c, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, size := range []int{0, 1, 16, 100, 1000} {
+		plaintext := bytes.Repeat([]byte{'A'}, size)
+		buf := make([]byte, size, size+xaes256gcm.Overhead)
+		copy(buf, plaintext)
+
+		ciphertext := c.Seal(buf[:0], nil, buf, aad)
+		if size > 0 && &ciphertext[0] != &buf[0] {
+			t.Errorf("%d: Seal didn't reuse the plaintext's storage", size)
+		}
+		if decrypted, err := xaes256gcm.MustNew(key).Open(nil, nil, ciphertext, aad); err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("%d: in-place Seal produced a bad ciphertext: %v", size, err)
+		}
+
+		decrypted, err := c.Open(ciphertext[:0], nil, ciphertext, aad)
+		if err != nil {
+			t.Fatalf("%d: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("%d: plaintext and decrypted are not equal", size)
+		}
+	}
+}
+
 func TestAccumulated(t *testing.T) {
 	iterations := 10_000
 	expected := "e6b9edf2df6cec60c8cbd864e2211b597fb69a529160cd040d56c0c210081939"
@@ -88,3 +168,152 @@ func TestAccumulated(t *testing.T) {
 		t.Errorf("got: %s", got)
 	}
 }
+
+func TestTagSize(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	nonce := []byte("ABCDEFGHIJKLMNOPQRSTUVWX")
+	plaintext := []byte("XAES-256-GCM")
+	full, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := full.Seal(nil, nonce, plaintext, nil)
+	for tagSize := 12; tagSize <= 16; tagSize++ {
+		c, err := xaes256gcm.NewWithTagSize(key, tagSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.Overhead() != tagSize {
+			t.Errorf("Overhead() = %d, expected %d", c.Overhead(), tagSize)
+		}
+		// GCM tags of shorter sizes are truncations of the full tag.
+		ciphertext := c.Seal(nil, nonce, plaintext, nil)
+		if !bytes.Equal(ciphertext, expected[:len(plaintext)+tagSize]) {
+			t.Errorf("tag size %d: got %x", tagSize, ciphertext)
+		}
+		if decrypted, err := c.Open(nil, nonce, ciphertext, nil); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(plaintext, decrypted) {
+			t.Errorf("plaintext and decrypted are not equal")
+		}
+		if tagSize != 16 {
+			if _, err := full.Open(nil, nonce, ciphertext, nil); err == nil {
+				t.Errorf("tag size %d: opened with full tag size", tagSize)
+			}
+		}
+	}
+	for _, tagSize := range []int{0, 11, 17} {
+		if _, err := xaes256gcm.NewWithTagSize(key, tagSize); err == nil {
+			t.Errorf("tag size %d accepted", tagSize)
+		}
+	}
+}
+
+type fixedReader byte
+
+func (r fixedReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(r)
+	}
+	return len(p), nil
+}
+
+func TestNewWithRand(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, err := xaes256gcm.NewWithRand(key, fixedReader('A'))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := c.Seal(nil, nil, []byte("hello"), nil)
+	if !bytes.Equal(ciphertext[:xaes256gcm.NonceSize], bytes.Repeat([]byte("A"), xaes256gcm.NonceSize)) {
+		t.Errorf("nonce not read from rand: %x", ciphertext[:xaes256gcm.NonceSize])
+	}
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Open(nil, nil, ciphertext, nil); err != nil {
+		t.Errorf("ciphertext not compatible with New: %v", err)
+	}
+
+	c, err = xaes256gcm.NewWithRand(key, bytes.NewReader(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xaes256gcm.TrySeal(c, nil, []byte("hello"), nil); err == nil {
+		t.Errorf("TrySeal didn't fail on entropy failure")
+	}
+	if ciphertext, err := xaes256gcm.TrySeal(a, nil, []byte("hello"), nil); err != nil {
+		t.Errorf("TrySeal: %v", err)
+	} else if _, err := a.Open(nil, nil, ciphertext, nil); err != nil {
+		t.Errorf("TrySeal ciphertext not compatible with New: %v", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Seal didn't panic on entropy failure")
+		}
+	}()
+	c.Seal(nil, nil, []byte("hello"), nil)
+}
+
+func TestNewWithNonceFunc(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	newAEAD := func() cipher.AEAD {
+		var counter byte
+		c, err := xaes256gcm.NewWithNonceFunc(key, func() (n [xaes256gcm.NonceSize]byte) {
+			counter++
+			n[0] = counter
+			return n
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+	a, b := newAEAD(), newAEAD()
+	for i := 1; i <= 3; i++ {
+		ca, cb := a.Seal(nil, nil, []byte("hello"), nil), b.Seal(nil, nil, []byte("hello"), nil)
+		if !bytes.Equal(ca, cb) {
+			t.Errorf("ciphertexts are not reproducible")
+		}
+		if ca[0] != byte(i) {
+			t.Errorf("nonce not from function: %x", ca[:xaes256gcm.NonceSize])
+		}
+		if _, err := xaes256gcm.MustNew(key).Open(nil, nil, ca, nil); err != nil {
+			t.Errorf("ciphertext not compatible with New: %v", err)
+		}
+	}
+}
+
+func TestCipher(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	nonce := []byte("ABCDEFGHIJKLMNOPQRSTUVWX")
+	c, err := xaes256gcm.NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var _ cipher.AEAD = c
+
+	// Copies are independent values that work like the original.
+	type server struct {
+		name string
+		c    xaes256gcm.Cipher
+	}
+	s := server{"test", c}
+	ciphertext := s.c.Seal(nil, nonce, []byte("XAES-256-GCM"), nil)
+	expected := "ce546ef63c9cc60765923609b33a9a1974e96e52daf2fcf7075e2271"
+	if got := hex.EncodeToString(ciphertext); got != expected {
+		t.Errorf("got: %s", got)
+	}
+	if plaintext, err := c.Open(nil, nonce, ciphertext, nil); err != nil {
+		t.Fatal(err)
+	} else if string(plaintext) != "XAES-256-GCM" {
+		t.Errorf("got %q", plaintext)
+	}
+	if c.NonceSize() != xaes256gcm.NonceSize || c.Overhead() != xaes256gcm.OverheadWithManualNonces {
+		t.Errorf("NonceSize() = %d, Overhead() = %d", c.NonceSize(), c.Overhead())
+	}
+	if _, err := xaes256gcm.NewCipher(key[:16]); err == nil {
+		t.Errorf("short key accepted")
+	}
+}
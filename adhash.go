@@ -0,0 +1,53 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"io"
+)
+
+// ADHashSize is the size of the additional data returned by [HashAD].
+const ADHashSize = sha256.Size
+
+const adHashLabel = "filippo.io/xaes256gcm AD hash\x00"
+
+// HashAD reads r until EOF, and returns a hash of its contents to be used as
+// additional data, so that a ciphertext can be bound to data that is too
+// large to hold in memory, such as a multi-gigabyte companion file.
+//
+// The hash is SHA-256 of a domain separation label followed by the contents of
+// r, so it never collides with a hash of the same data computed for another
+// purpose.
+func HashAD(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	io.WriteString(h, adHashLabel)
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// SealWithADReader is like the Seal method of aead, which must use automatic
+// nonces like the AEADs returned by [New], but authenticates the contents of
+// additionalData, hashed with [HashAD]. It returns an error only if reading
+// additionalData fails.
+//
+// The result can be opened with [OpenWithADReader], or with the Open method of
+// aead with the output of HashAD as additional data.
+func SealWithADReader(aead cipher.AEAD, dst, plaintext []byte, additionalData io.Reader) ([]byte, error) {
+	ad, err := HashAD(additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(dst, nil, plaintext, ad), nil
+}
+
+// OpenWithADReader opens a ciphertext produced by [SealWithADReader]. It reads
+// additionalData completely before decrypting.
+func OpenWithADReader(aead cipher.AEAD, dst, ciphertext []byte, additionalData io.Reader) ([]byte, error) {
+	ad, err := HashAD(additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Open(dst, nil, ciphertext, ad)
+}
@@ -0,0 +1,52 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestADReader(t *testing.T) {
+	aead, err := xaes256gcm.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	companion := strings.Repeat("large companion file ", 100000)
+
+	ciphertext, err := xaes256gcm.SealWithADReader(aead, nil, []byte("manifest"), strings.NewReader(companion))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := xaes256gcm.OpenWithADReader(aead, nil, ciphertext, strings.NewReader(companion))
+	if err != nil || string(got) != "manifest" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := xaes256gcm.OpenWithADReader(aead, nil, ciphertext, strings.NewReader(companion+"x")); err == nil {
+		t.Errorf("opened with modified additional data")
+	}
+
+	ad, err := xaes256gcm.HashAD(strings.NewReader(companion))
+	if err != nil || len(ad) != xaes256gcm.ADHashSize {
+		t.Fatalf("HashAD: %x, %v", ad, err)
+	}
+	if got, err := aead.Open(nil, nil, ciphertext, ad); err != nil || string(got) != "manifest" {
+		t.Errorf("Open with HashAD: %q, %v", got, err)
+	}
+	if plain := sha256.Sum256([]byte(companion)); bytes.Equal(ad, plain[:]) {
+		t.Errorf("HashAD is not domain separated")
+	}
+
+	readErr := errors.New("read error")
+	if _, err := xaes256gcm.SealWithADReader(aead, nil, nil, iotest.ErrReader(readErr)); !errors.Is(err, readErr) {
+		t.Errorf("expected read error, got %v", err)
+	}
+	if _, err := xaes256gcm.OpenWithADReader(aead, nil, ciphertext, io.MultiReader(strings.NewReader("x"), iotest.ErrReader(readErr))); !errors.Is(err, readErr) {
+		t.Errorf("expected read error, got %v", err)
+	}
+}
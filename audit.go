@@ -0,0 +1,52 @@
+package xaes256gcm
+
+import (
+	"bytes"
+	"crypto/cipher"
+)
+
+// OpenFailure describes a call to Open that failed, for audit purposes. It
+// never includes any plaintext or key material.
+type OpenFailure struct {
+	// Nonce is the nonce of the message. For AEADs returned by [New], it's the
+	// nonce prepended to the ciphertext, or nil if the ciphertext is too short.
+	Nonce []byte
+	// AdditionalData is the additional data passed to Open.
+	AdditionalData []byte
+	// CiphertextLength is the length of the ciphertext passed to Open.
+	CiphertextLength int
+}
+
+// WithOpenFailureHook returns an AEAD that wraps aead, and calls hook whenever
+// Open fails, which might indicate tampering or misconfiguration.
+//
+// hook is called synchronously before Open returns, and must be safe for
+// concurrent use if the AEAD is used concurrently. The slices in OpenFailure
+// are copies, and can be retained.
+func WithOpenFailureHook(aead cipher.AEAD, hook func(*OpenFailure)) cipher.AEAD {
+	return &auditAEAD{aead, hook}
+}
+
+type auditAEAD struct {
+	cipher.AEAD
+	hook func(*OpenFailure)
+}
+
+func (a *auditAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	out, err := a.AEAD.Open(dst, nonce, ciphertext, additionalData)
+	if err != nil {
+		f := &OpenFailure{
+			Nonce:            bytes.Clone(nonce),
+			AdditionalData:   bytes.Clone(additionalData),
+			CiphertextLength: len(ciphertext),
+		}
+		if a.AEAD.NonceSize() == 0 {
+			f.Nonce = nil
+			if len(ciphertext) >= NonceSize {
+				f.Nonce = bytes.Clone(ciphertext[:NonceSize])
+			}
+		}
+		a.hook(f)
+	}
+	return out, err
+}
@@ -0,0 +1,44 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestOpenFailureHook(t *testing.T) {
+	c, err := xaes256gcm.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var failures []*xaes256gcm.OpenFailure
+	c = xaes256gcm.WithOpenFailureHook(c, func(f *xaes256gcm.OpenFailure) {
+		failures = append(failures, f)
+	})
+
+	ciphertext := c.Seal(nil, nil, []byte("hello"), []byte("aad"))
+	if _, err := c.Open(nil, nil, ciphertext, []byte("aad")); err != nil {
+		t.Fatal(err)
+	}
+	if len(failures) != 0 {
+		t.Fatalf("hook called on success")
+	}
+	if _, err := c.Open(nil, nil, ciphertext, []byte("wrong")); err == nil {
+		t.Fatal("Open succeeded with wrong additional data")
+	}
+	if _, err := c.Open(nil, nil, ciphertext[:10], nil); err == nil {
+		t.Fatal("Open succeeded with short ciphertext")
+	}
+
+	if len(failures) != 2 {
+		t.Fatalf("hook called %d times", len(failures))
+	}
+	if f := failures[0]; !bytes.Equal(f.Nonce, ciphertext[:xaes256gcm.NonceSize]) ||
+		string(f.AdditionalData) != "wrong" || f.CiphertextLength != len(ciphertext) {
+		t.Errorf("unexpected failure %+v", f)
+	}
+	if f := failures[1]; f.Nonce != nil || f.CiphertextLength != 10 {
+		t.Errorf("unexpected failure %+v", f)
+	}
+}
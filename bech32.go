@@ -0,0 +1,135 @@
+package xaes256gcm
+
+import (
+	"errors"
+	"strings"
+)
+
+// Bech32Prefix is the human-readable prefix of keys encoded with
+// [Key.Bech32].
+const Bech32Prefix = "xaes"
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Bech32 returns the key encoded as a lowercase Bech32m string (BIP-350) with
+// the human-readable prefix "xaes", such as "xaes1qqqsyqcyq5rqwzqf...".
+//
+// The encoding is case-insensitive and has a checksum that detects any error
+// in up to four characters. Since it uses a single case and avoids ambiguous
+// characters, it's suitable for transcription and for voice transmission.
+// When encoding it as a QR code, convert it to uppercase to make use of the
+// more compact alphanumeric mode.
+//
+// The returned string is the key material, and must be kept secret.
+func (k Key) Bech32() string {
+	data := make([]byte, 0, (KeySize*8+4)/5+6)
+	var acc, bits uint
+	for _, b := range k.k {
+		acc = acc<<8 | uint(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			data = append(data, byte(acc>>bits&31))
+		}
+	}
+	if bits > 0 {
+		data = append(data, byte(acc<<(5-bits)&31))
+	}
+	data = append(data, bech32Checksum(Bech32Prefix, data)...)
+	var s strings.Builder
+	s.WriteString(Bech32Prefix + "1")
+	for _, d := range data {
+		s.WriteByte(bech32Charset[d])
+	}
+	return s.String()
+}
+
+// ParseBech32Key parses a key encoded with [Key.Bech32]. s must be either all
+// lowercase or all uppercase, and its checksum, prefix, and padding are
+// strictly checked.
+func ParseBech32Key(s string) (Key, error) {
+	k, err := parseBech32Key(s)
+	if err != nil {
+		return Key{}, errors.New("xaes256gcm: " + err.Error())
+	}
+	return k, nil
+}
+
+func parseBech32Key(s string) (Key, error) {
+	if strings.ToLower(s) != s && strings.ToUpper(s) != s {
+		return Key{}, errors.New("Bech32 key has mixed case")
+	}
+	s = strings.ToLower(s)
+	if !strings.HasPrefix(s, Bech32Prefix+"1") {
+		return Key{}, errors.New("not a Bech32 key")
+	}
+	chars := s[len(Bech32Prefix)+1:]
+	if len(chars) != (KeySize*8+4)/5+6 {
+		return Key{}, errors.New("Bech32 key has the wrong length")
+	}
+	data := make([]byte, len(chars))
+	for i := 0; i < len(chars); i++ {
+		d := strings.IndexByte(bech32Charset, chars[i])
+		if d < 0 {
+			return Key{}, errors.New("Bech32 key has an invalid character")
+		}
+		data[i] = byte(d)
+	}
+	if bech32Polymod(Bech32Prefix, data) != bech32mConst {
+		return Key{}, errors.New("Bech32 key has an invalid checksum")
+	}
+	data = data[:len(data)-6]
+	var k Key
+	var acc, bits uint
+	var n int
+	for _, d := range data {
+		acc = acc<<5 | uint(d)
+		bits += 5
+		if bits >= 8 {
+			bits -= 8
+			k.k[n] = byte(acc >> bits)
+			n++
+		}
+	}
+	if acc&(1<<bits-1) != 0 {
+		return Key{}, errors.New("Bech32 key has non-zero padding")
+	}
+	return k, nil
+}
+
+const bech32mConst = 0x2bc830a3
+
+func bech32Polymod(hrp string, data []byte) uint32 {
+	gen := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	step := func(v byte) {
+		b := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if b>>i&1 == 1 {
+				chk ^= gen[i]
+			}
+		}
+	}
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] >> 5)
+	}
+	step(0)
+	for i := 0; i < len(hrp); i++ {
+		step(hrp[i] & 31)
+	}
+	for _, d := range data {
+		step(d)
+	}
+	return chk
+}
+
+func bech32Checksum(hrp string, data []byte) []byte {
+	values := append(append([]byte(nil), data...), 0, 0, 0, 0, 0, 0)
+	mod := bech32Polymod(hrp, values) ^ bech32mConst
+	checksum := make([]byte, 6)
+	for i := range checksum {
+		checksum[i] = byte(mod >> (5 * (5 - i)) & 31)
+	}
+	return checksum
+}
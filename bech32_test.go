@@ -0,0 +1,49 @@
+package xaes256gcm_test
+
+import (
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestBech32(t *testing.T) {
+	key := make([]byte, xaes256gcm.KeySize)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	k := xaes256gcm.MustKey(key)
+	expected := "xaes1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0sp377yp"
+	if got := k.Bech32(); got != expected {
+		t.Errorf("got %s", got)
+	}
+	for _, s := range []string{expected, strings.ToUpper(expected)} {
+		parsed, err := xaes256gcm.ParseBech32Key(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed != k {
+			t.Errorf("%s: parsed the wrong key", s)
+		}
+	}
+
+	random := xaes256gcm.GenerateKey()
+	if parsed, err := xaes256gcm.ParseBech32Key(random.Bech32()); err != nil || parsed != random {
+		t.Errorf("random key didn't round-trip: %v", err)
+	}
+
+	for _, s := range []string{
+		"",
+		"Xaes1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0sp377yp",  // mixed case
+		"xaes1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0sp377yq",  // checksum
+		"xaes1pqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0sp377yp",  // data
+		"xaes1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0sp377y",   // length
+		"xaes1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0sp377ybp", // invalid character
+		"age1qqqsyqcyq5rqwzqfpg9scrgwpugpzysnzs23v9ccrydpk8qarc0sp377yp",   // prefix
+		"xaes1qqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqqp4npfe2", // padding
+	} {
+		if _, err := xaes256gcm.ParseBech32Key(s); err == nil {
+			t.Errorf("parsed invalid key %q", s)
+		}
+	}
+}
@@ -0,0 +1,7 @@
+//go:build boringcrypto
+
+package xaes256gcm
+
+import "crypto/boring"
+
+func boringEnabled() bool { return boring.Enabled() }
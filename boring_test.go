@@ -0,0 +1,16 @@
+//go:build boringcrypto
+
+package xaes256gcm_test
+
+import (
+	"crypto/boring"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestBoringEnabled(t *testing.T) {
+	if xaes256gcm.BoringEnabled() != boring.Enabled() {
+		t.Errorf("BoringEnabled() = %v, boring.Enabled() = %v", xaes256gcm.BoringEnabled(), boring.Enabled())
+	}
+}
@@ -0,0 +1,44 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+)
+
+// WithBoundAD returns an AEAD that wraps aead, and prepends a context prefix to
+// the additional data of every call to Seal and Open. prefix must be at most
+// 65535 bytes long.
+//
+// This provides domain separation between applications or services that share
+// a key, without relying on each call site to include the context.
+//
+// The additional data passed to aead is the 2-byte big-endian length of prefix,
+// followed by prefix and by the additional data passed to the returned AEAD,
+// so that different prefixes never produce the same additional data.
+func WithBoundAD(aead cipher.AEAD, prefix []byte) cipher.AEAD {
+	if len(prefix) > 0xffff {
+		panic("xaes256gcm: bound additional data prefix too long")
+	}
+	p := binary.BigEndian.AppendUint16(nil, uint16(len(prefix)))
+	p = append(p, prefix...)
+	return &boundADAEAD{aead, p}
+}
+
+type boundADAEAD struct {
+	cipher.AEAD
+	prefix []byte // with the length
+}
+
+func (a *boundADAEAD) ad(additionalData []byte) []byte {
+	ad := make([]byte, 0, len(a.prefix)+len(additionalData))
+	ad = append(ad, a.prefix...)
+	return append(ad, additionalData...)
+}
+
+func (a *boundADAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return a.AEAD.Seal(dst, nonce, plaintext, a.ad(additionalData))
+}
+
+func (a *boundADAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return a.AEAD.Open(dst, nonce, ciphertext, a.ad(additionalData))
+}
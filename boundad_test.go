@@ -0,0 +1,40 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestBoundAD(t *testing.T) {
+	c, err := xaes256gcm.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := xaes256gcm.WithBoundAD(c, []byte("service A"))
+	b := xaes256gcm.WithBoundAD(c, []byte("service B"))
+
+	ciphertext := a.Seal(nil, nil, []byte("hello"), []byte("aad"))
+	if plaintext, err := a.Open(nil, nil, ciphertext, []byte("aad")); err != nil {
+		t.Fatal(err)
+	} else if string(plaintext) != "hello" {
+		t.Errorf("unexpected plaintext %q", plaintext)
+	}
+	if _, err := b.Open(nil, nil, ciphertext, []byte("aad")); err == nil {
+		t.Errorf("opened with a different prefix")
+	}
+	if _, err := c.Open(nil, nil, ciphertext, []byte("aad")); err == nil {
+		t.Errorf("opened without a prefix")
+	}
+	if _, err := c.Open(nil, nil, ciphertext, []byte("\x00\x09service Aaad")); err != nil {
+		t.Errorf("unexpected additional data encoding: %v", err)
+	}
+
+	// The prefix length is encoded, so it can't be shifted into the
+	// additional data.
+	p := xaes256gcm.WithBoundAD(c, []byte("service"))
+	if _, err := p.Open(nil, nil, ciphertext, []byte(" Aaad")); err == nil {
+		t.Errorf("opened with a shorter prefix")
+	}
+}
@@ -0,0 +1,155 @@
+//go:build cgo
+
+// Command capi is a C shared library exposing XAES-256-GCM, for C, C++, Rust,
+// Python, and other languages with a C foreign function interface.
+//
+// Build it with
+//
+//	go build -buildmode=c-shared -o libxaes256gcm.so ./capi
+//
+// and include xaes256gcm.h, which declares the stable interface. The header
+// generated by the build is equivalent, but also includes cgo internals.
+//
+// Streams are exposed through opaque handles, which own a file descriptor.
+package main
+
+/*
+#include <stddef.h>
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"unsafe"
+
+	"filippo.io/xaes256gcm"
+)
+
+func main() {}
+
+func goBytes(p *C.uint8_t, n C.size_t) []byte {
+	if n == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(p)), int(n))
+}
+
+//export xaes256gcm_keygen
+func xaes256gcm_keygen(key *C.uint8_t) C.int {
+	copy(goBytes(key, xaes256gcm.KeySize), xaes256gcm.GenerateKey().Bytes())
+	return 0
+}
+
+//export xaes256gcm_seal
+func xaes256gcm_seal(key, plaintext *C.uint8_t, plaintextLen C.size_t,
+	ad *C.uint8_t, adLen C.size_t, out *C.uint8_t) C.int {
+	err := seal(goBytes(key, xaes256gcm.KeySize), goBytes(plaintext, plaintextLen),
+		goBytes(ad, adLen), goBytes(out, plaintextLen+xaes256gcm.Overhead))
+	if err != nil {
+		return -1
+	}
+	return 0
+}
+
+//export xaes256gcm_open
+func xaes256gcm_open(key, ciphertext *C.uint8_t, ciphertextLen C.size_t,
+	ad *C.uint8_t, adLen C.size_t, out *C.uint8_t) C.int {
+	if ciphertextLen < xaes256gcm.Overhead {
+		return -1
+	}
+	err := open(goBytes(key, xaes256gcm.KeySize), goBytes(ciphertext, ciphertextLen),
+		goBytes(ad, adLen), goBytes(out, ciphertextLen-xaes256gcm.Overhead))
+	if err != nil {
+		return -1
+	}
+	return 0
+}
+
+// seal encrypts plaintext into out, which must be exactly Overhead bytes
+// longer. A panic, for example because out overlaps the inputs, is returned
+// as an error, since it would otherwise abort the host process.
+func seal(key, plaintext, ad, out []byte) (err error) {
+	defer catch(&err)
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		return err
+	}
+	a.Seal(out[:0], nil, plaintext, ad)
+	return nil
+}
+
+// open decrypts ciphertext into out, like seal.
+func open(key, ciphertext, ad, out []byte) (err error) {
+	defer catch(&err)
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		return err
+	}
+	_, err = a.Open(out[:0], nil, ciphertext, ad)
+	return err
+}
+
+func catch(err *error) {
+	if r := recover(); r != nil {
+		*err = fmt.Errorf("panic: %v", r)
+	}
+}
+
+//export xaes256gcm_writer_new
+func xaes256gcm_writer_new(key *C.uint8_t, fd C.int) C.uintptr_t {
+	w, err := newWriter(goBytes(key, xaes256gcm.KeySize), uintptr(fd))
+	if err != nil {
+		return 0
+	}
+	return C.uintptr_t(cgo.NewHandle(w))
+}
+
+//export xaes256gcm_writer_write
+func xaes256gcm_writer_write(h C.uintptr_t, data *C.uint8_t, n C.size_t) C.int {
+	w := cgo.Handle(h).Value().(*writer)
+	if _, err := w.w.Write(goBytes(data, n)); err != nil {
+		return -1
+	}
+	return 0
+}
+
+//export xaes256gcm_writer_close
+func xaes256gcm_writer_close(h C.uintptr_t) C.int {
+	w := cgo.Handle(h).Value().(*writer)
+	cgo.Handle(h).Delete()
+	if err := w.close(); err != nil {
+		return -1
+	}
+	return 0
+}
+
+//export xaes256gcm_reader_new
+func xaes256gcm_reader_new(key *C.uint8_t, fd C.int) C.uintptr_t {
+	r, err := newReader(goBytes(key, xaes256gcm.KeySize), uintptr(fd))
+	if err != nil {
+		return 0
+	}
+	return C.uintptr_t(cgo.NewHandle(r))
+}
+
+//export xaes256gcm_reader_read
+func xaes256gcm_reader_read(h C.uintptr_t, buf *C.uint8_t, n C.size_t) C.ptrdiff_t {
+	r := cgo.Handle(h).Value().(*reader)
+	m, err := r.read(goBytes(buf, n))
+	if err != nil {
+		return -1
+	}
+	return C.ptrdiff_t(m)
+}
+
+//export xaes256gcm_reader_close
+func xaes256gcm_reader_close(h C.uintptr_t) C.int {
+	r := cgo.Handle(h).Value().(*reader)
+	cgo.Handle(h).Delete()
+	if err := r.f.Close(); err != nil {
+		return -1
+	}
+	return 0
+}
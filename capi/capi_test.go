@@ -0,0 +1,34 @@
+//go:build cgo
+
+package main
+
+import (
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestSealOpen(t *testing.T) {
+	key := xaes256gcm.GenerateKey().Bytes()
+	ciphertext := make([]byte, 5+xaes256gcm.Overhead)
+	if err := seal(key, []byte("hello"), nil, ciphertext); err != nil {
+		t.Fatal(err)
+	}
+	plaintext := make([]byte, 5)
+	if err := open(key, ciphertext, nil, plaintext); err != nil || string(plaintext) != "hello" {
+		t.Errorf("open: %q, %v", plaintext, err)
+	}
+
+	// Inexactly overlapping buffers make crypto/cipher panic, which must not
+	// escape.
+	buf := make([]byte, 1+100+xaes256gcm.Overhead)
+	if err := seal(key, buf[1:101], nil, buf); err == nil {
+		t.Errorf("overlapping seal succeeded")
+	}
+	if err := seal(key, make([]byte, 100), nil, buf[:100+xaes256gcm.Overhead]); err != nil {
+		t.Fatal(err)
+	}
+	if err := open(key, buf[:100+xaes256gcm.Overhead], nil, buf[1:101]); err == nil {
+		t.Errorf("overlapping open succeeded")
+	}
+}
@@ -0,0 +1,77 @@
+//go:build cgo
+
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+
+	"filippo.io/xaes256gcm/stream"
+)
+
+// writer is the value of a writer handle.
+type writer struct {
+	f *os.File
+	w *stream.Writer
+}
+
+func newWriter(key []byte, fd uintptr) (*writer, error) {
+	f := os.NewFile(fd, "")
+	if f == nil {
+		return nil, errors.New("invalid file descriptor")
+	}
+	w, err := stream.NewWriter(key, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &writer{f: f, w: w}, nil
+}
+
+func (w *writer) close() error {
+	err := w.w.Close()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// reader is the value of a reader handle.
+type reader struct {
+	f *os.File
+	r *stream.Reader
+}
+
+func newReader(key []byte, fd uintptr) (*reader, error) {
+	f := os.NewFile(fd, "")
+	if f == nil {
+		return nil, errors.New("invalid file descriptor")
+	}
+	r, err := stream.NewReader(key, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &reader{f: f, r: r}, nil
+}
+
+// read is like io.Reader.Read, but returns 0 and no error at the end of the
+// stream, and never returns both data and an error.
+func (r *reader) read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	for {
+		n, err := r.r.Read(p)
+		if n > 0 {
+			return n, nil
+		}
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
@@ -0,0 +1,70 @@
+//go:build cgo
+
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestHandles(t *testing.T) {
+	key := xaes256gcm.GenerateKey().Bytes()
+	name := filepath.Join(t.TempDir(), "stream.xaes")
+	fd, err := syscall.Open(name, syscall.O_WRONLY|syscall.O_CREAT, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := newWriter(key, uintptr(fd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := bytes.Repeat([]byte("hello, C\n"), 10000)
+	if _, err := w.w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fd, err = syscall.Open(name, syscall.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := newReader(key, uintptr(fd))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []byte
+	buf := make([]byte, 1000)
+	for {
+		n, err := r.read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			break
+		}
+		got = append(got, buf[:n]...)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted stream doesn't match")
+	}
+	r.f.Close()
+
+	fd, err = syscall.Open(name, syscall.O_RDONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = newReader(xaes256gcm.GenerateKey().Bytes(), uintptr(fd))
+	if err == nil {
+		_, err = r.read(buf)
+		r.f.Close()
+	}
+	if err == nil {
+		t.Errorf("read with the wrong key")
+	}
+}
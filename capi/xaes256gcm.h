@@ -0,0 +1,59 @@
+// xaes256gcm.h is the stable C interface of libxaes256gcm, built from
+// filippo.io/xaes256gcm/capi with
+//
+//     go build -buildmode=c-shared -o libxaes256gcm.so ./capi
+//
+// All functions return 0 on success and -1 on failure, unless noted.
+
+#ifndef XAES256GCM_H
+#define XAES256GCM_H
+
+#include <stddef.h>
+#include <stdint.h>
+
+#define XAES256GCM_KEY_SIZE 32
+#define XAES256GCM_OVERHEAD 40
+
+#ifdef __cplusplus
+extern "C" {
+#endif
+
+// Writes a new random key to key, which must have space for
+// XAES256GCM_KEY_SIZE bytes.
+int xaes256gcm_keygen(uint8_t *key);
+
+// Encrypts plaintext with a random nonce, like xaes256gcm.New, and writes
+// plaintext_len + XAES256GCM_OVERHEAD bytes to out, which must not overlap
+// plaintext or ad. Returns 0 on success, or -1 on failure, including if out
+// overlaps the inputs.
+int xaes256gcm_seal(const uint8_t *key, const uint8_t *plaintext, size_t plaintext_len,
+                    const uint8_t *ad, size_t ad_len, uint8_t *out);
+
+// Decrypts a ciphertext produced by xaes256gcm_seal, and writes
+// ciphertext_len - XAES256GCM_OVERHEAD bytes to out, which must not overlap
+// ciphertext or ad. Returns 0 on success, or -1 on failure.
+int xaes256gcm_open(const uint8_t *key, const uint8_t *ciphertext, size_t ciphertext_len,
+                    const uint8_t *ad, size_t ad_len, uint8_t *out);
+
+// Returns a handle that encrypts data written with xaes256gcm_writer_write to
+// fd, in the format of filippo.io/xaes256gcm/stream, or 0 on failure. The
+// handle takes ownership of fd.
+uintptr_t xaes256gcm_writer_new(const uint8_t *key, int fd);
+int xaes256gcm_writer_write(uintptr_t w, const uint8_t *data, size_t len);
+// Writes the last chunk, closes fd, and frees the handle.
+int xaes256gcm_writer_close(uintptr_t w);
+
+// Returns a handle that decrypts the stream read from fd, or 0 on failure.
+// The handle takes ownership of fd.
+uintptr_t xaes256gcm_reader_new(const uint8_t *key, int fd);
+// Reads up to len bytes of verified plaintext into buf, and returns the
+// number of bytes read, 0 at the end of the stream, or -1 on failure.
+ptrdiff_t xaes256gcm_reader_read(uintptr_t r, uint8_t *buf, size_t len);
+// Closes fd and frees the handle.
+int xaes256gcm_reader_close(uintptr_t r);
+
+#ifdef __cplusplus
+}
+#endif
+
+#endif // XAES256GCM_H
@@ -0,0 +1,338 @@
+// Package channel implements a lightweight encrypted transport over a
+// [net.Conn] using a pre-shared XAES-256-GCM key.
+//
+// When a connection is established, the client and the server each send 32
+// random bytes, and a pair of traffic keys, one per direction, is derived from
+// the pre-shared key and both random values with HKDF-SHA256. The traffic keys
+// are therefore unique to the connection, and recorded connections can't be
+// replayed to either side.
+//
+// Data is then exchanged as records, each made of a 3-byte header (a record
+// type and the big-endian length of the ciphertext) followed by the
+// XAES-256-GCM encryption of up to [MaxRecordSize] bytes, with the header as
+// additional data. The nonce of each record is the big-endian count of records
+// previously sent with the same traffic key.
+//
+// After a number of records or bytes, configurable with [Options], the sender
+// sends an empty key update record, and replaces its traffic secret with one
+// derived from it with HKDF-SHA256, discarding the old one. The receiver does
+// the same when it receives the key update. Long-lived connections therefore
+// never approach the usage limits of a single key, and a compromise of the
+// current traffic secret doesn't reveal the data sent before the last update.
+//
+// The channel provides no authentication beyond the possession of the
+// pre-shared key, and no forward secrecy against a compromise of the
+// pre-shared key.
+package channel
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"filippo.io/xaes256gcm"
+	"golang.org/x/crypto/hkdf"
+)
+
+// MaxRecordSize is the maximum size of the plaintext of a record.
+const MaxRecordSize = 16 * 1024
+
+const (
+	randomSize = 32
+	headerSize = 3
+
+	recordData      = 0
+	recordClose     = 1
+	recordKeyUpdate = 2
+)
+
+// Default key update thresholds, used if the corresponding [Options] field is
+// zero.
+const (
+	DefaultRekeyRecords = 1 << 24
+	DefaultRekeyBytes   = 1 << 30
+)
+
+// Options are optional settings for [ClientWithOptions] and
+// [ServerWithOptions]. A nil *Options is equivalent to the zero value.
+type Options struct {
+	// RekeyRecords is the number of records sent with a traffic key after
+	// which a key update is sent. If zero, DefaultRekeyRecords is used.
+	RekeyRecords uint64
+
+	// RekeyBytes is the number of plaintext bytes sent with a traffic key after
+	// which a key update is sent. If zero, DefaultRekeyBytes is used.
+	RekeyBytes uint64
+}
+
+var errLimit = errors.New("channel: record limit reached")
+
+// Conn is an encrypted connection. It implements [net.Conn].
+//
+// Read and Write can be called concurrently with each other, but not with
+// themselves.
+type Conn struct {
+	conn net.Conn
+
+	in    halfConn
+	inBuf []byte // decrypted but unread plaintext
+	inEOF bool
+
+	outMu        sync.Mutex
+	out          halfConn
+	rekeyRecords uint64
+	rekeyBytes   uint64
+}
+
+type halfConn struct {
+	secret []byte
+	aead   cipher.AEAD
+	seq    uint64
+	bytes  uint64
+	buf    []byte
+}
+
+// setSecret replaces the traffic secret, and resets the sequence number.
+func (h *halfConn) setSecret(secret []byte) error {
+	aead, err := xaes256gcm.NewWithManualNonces(secret)
+	if err != nil {
+		return err
+	}
+	clear(h.secret)
+	h.secret, h.aead, h.seq, h.bytes = secret, aead, 0, 0
+	return nil
+}
+
+// update replaces the traffic secret with the next one.
+func (h *halfConn) update() error {
+	next := make([]byte, xaes256gcm.KeySize)
+	r := hkdf.New(sha256.New, h.secret, nil, []byte("xaes256gcm channel key update"))
+	if _, err := io.ReadFull(r, next); err != nil {
+		return err
+	}
+	return h.setSecret(next)
+}
+
+func (h *halfConn) nonce() ([]byte, error) {
+	if h.seq == 1<<64-1 {
+		return nil, errLimit
+	}
+	nonce := make([]byte, xaes256gcm.NonceSize)
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], h.seq)
+	return nonce, nil
+}
+
+// Client returns a new client side of an encrypted connection over conn,
+// after exchanging random values with the server.
+func Client(conn net.Conn, key []byte) (*Conn, error) {
+	return ClientWithOptions(conn, key, nil)
+}
+
+// ClientWithOptions is like [Client], with optional settings.
+func ClientWithOptions(conn net.Conn, key []byte, opts *Options) (*Conn, error) {
+	clientRandom := make([]byte, randomSize)
+	if _, err := rand.Read(clientRandom); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(clientRandom); err != nil {
+		return nil, err
+	}
+	serverRandom := make([]byte, randomSize)
+	if _, err := io.ReadFull(conn, serverRandom); err != nil {
+		return nil, err
+	}
+	return newConn(conn, key, clientRandom, serverRandom, true, opts)
+}
+
+// Server returns a new server side of an encrypted connection over conn,
+// after exchanging random values with the client.
+func Server(conn net.Conn, key []byte) (*Conn, error) {
+	return ServerWithOptions(conn, key, nil)
+}
+
+// ServerWithOptions is like [Server], with optional settings.
+func ServerWithOptions(conn net.Conn, key []byte, opts *Options) (*Conn, error) {
+	clientRandom := make([]byte, randomSize)
+	if _, err := io.ReadFull(conn, clientRandom); err != nil {
+		return nil, err
+	}
+	serverRandom := make([]byte, randomSize)
+	if _, err := rand.Read(serverRandom); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(serverRandom); err != nil {
+		return nil, err
+	}
+	return newConn(conn, key, clientRandom, serverRandom, false, opts)
+}
+
+func newConn(conn net.Conn, key, clientRandom, serverRandom []byte, isClient bool, opts *Options) (*Conn, error) {
+	if len(key) != xaes256gcm.KeySize {
+		return nil, errors.New("channel: bad key length")
+	}
+	salt := append(clientRandom, serverRandom...)
+	c2s, err := deriveKey(key, salt, "client to server")
+	if err != nil {
+		return nil, err
+	}
+	s2c, err := deriveKey(key, salt, "server to client")
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{conn: conn, rekeyRecords: DefaultRekeyRecords, rekeyBytes: DefaultRekeyBytes}
+	if opts != nil && opts.RekeyRecords != 0 {
+		c.rekeyRecords = opts.RekeyRecords
+	}
+	if opts != nil && opts.RekeyBytes != 0 {
+		c.rekeyBytes = opts.RekeyBytes
+	}
+	out, in := c2s, s2c
+	if !isClient {
+		out, in = s2c, c2s
+	}
+	if err := c.out.setSecret(out); err != nil {
+		return nil, err
+	}
+	if err := c.in.setSecret(in); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func deriveKey(key, salt []byte, direction string) ([]byte, error) {
+	k := make([]byte, xaes256gcm.KeySize)
+	h := hkdf.New(sha256.New, key, salt, []byte("xaes256gcm channel "+direction))
+	if _, err := io.ReadFull(h, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Write encrypts p and writes it to the connection, split into records of at
+// most [MaxRecordSize] bytes.
+func (c *Conn) Write(p []byte) (int, error) {
+	c.outMu.Lock()
+	defer c.outMu.Unlock()
+	var n int
+	for len(p) > 0 {
+		if c.out.seq >= c.rekeyRecords || c.out.bytes >= c.rekeyBytes {
+			if err := c.writeRecord(recordKeyUpdate, nil); err != nil {
+				return n, err
+			}
+			if err := c.out.update(); err != nil {
+				return n, err
+			}
+		}
+		chunk := p[:min(len(p), MaxRecordSize)]
+		if err := c.writeRecord(recordData, chunk); err != nil {
+			return n, err
+		}
+		n += len(chunk)
+		p = p[len(chunk):]
+	}
+	return n, nil
+}
+
+func (c *Conn) writeRecord(typ byte, plaintext []byte) error {
+	nonce, err := c.out.nonce()
+	if err != nil {
+		return err
+	}
+	length := len(plaintext) + c.out.aead.Overhead()
+	record := append(c.out.buf[:0], typ, byte(length>>8), byte(length))
+	record = c.out.aead.Seal(record, nonce, plaintext, record[:headerSize])
+	c.out.buf = record
+	c.out.seq++
+	c.out.bytes += uint64(len(plaintext))
+	_, err = c.conn.Write(record)
+	return err
+}
+
+// Read reads and decrypts data from the connection. It returns [io.EOF] only
+// if the peer closed the connection with [Conn.Close], and
+// [io.ErrUnexpectedEOF] if the underlying connection was closed first.
+func (c *Conn) Read(p []byte) (int, error) {
+	for len(c.inBuf) == 0 {
+		if c.inEOF {
+			return 0, io.EOF
+		}
+		if err := c.readRecord(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.inBuf)
+	c.inBuf = c.inBuf[n:]
+	return n, nil
+}
+
+func (c *Conn) readRecord() error {
+	var header [headerSize]byte
+	if _, err := io.ReadFull(c.conn, header[:]); err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+	length := int(header[1])<<8 | int(header[2])
+	if length < c.in.aead.Overhead() || length > MaxRecordSize+c.in.aead.Overhead() {
+		return errors.New("channel: invalid record length")
+	}
+	if cap(c.in.buf) < length {
+		c.in.buf = make([]byte, MaxRecordSize+c.in.aead.Overhead())
+	}
+	ciphertext := c.in.buf[:length]
+	if _, err := io.ReadFull(c.conn, ciphertext); err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+	nonce, err := c.in.nonce()
+	if err != nil {
+		return err
+	}
+	plaintext, err := c.in.aead.Open(ciphertext[:0], nonce, ciphertext, header[:])
+	if err != nil {
+		return err
+	}
+	c.in.seq++
+	switch header[0] {
+	case recordData:
+		c.inBuf = plaintext
+	case recordClose:
+		if len(plaintext) != 0 {
+			return errors.New("channel: invalid close record")
+		}
+		c.inEOF = true
+	case recordKeyUpdate:
+		if len(plaintext) != 0 {
+			return errors.New("channel: invalid key update record")
+		}
+		return c.in.update()
+	default:
+		return errors.New("channel: unknown record type")
+	}
+	return nil
+}
+
+// Close sends an encrypted close record, which lets the peer distinguish a
+// clean close from a truncation, and closes the underlying connection.
+func (c *Conn) Close() error {
+	c.outMu.Lock()
+	err := c.writeRecord(recordClose, nil)
+	c.outMu.Unlock()
+	if cerr := c.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (c *Conn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
+func (c *Conn) RemoteAddr() net.Addr               { return c.conn.RemoteAddr() }
+func (c *Conn) SetDeadline(t time.Time) error      { return c.conn.SetDeadline(t) }
+func (c *Conn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
@@ -0,0 +1,118 @@
+package channel_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/channel"
+)
+
+func pipe(t *testing.T, clientKey, serverKey []byte) (client, server *channel.Conn, raw net.Conn) {
+	c, s := net.Pipe()
+	errc := make(chan error, 1)
+	go func() {
+		var err error
+		server, err = channel.Server(s, serverKey)
+		errc <- err
+	}()
+	client, err := channel.Client(c, clientKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+	return client, server, c
+}
+
+func TestRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	client, server, _ := pipe(t, key, key)
+
+	msg := bytes.Repeat([]byte("0123456789"), channel.MaxRecordSize/4)
+	go func() {
+		client.Write(msg)
+		client.Close()
+	}()
+	got, err := io.ReadAll(server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Errorf("received data doesn't match")
+	}
+}
+
+func TestTruncation(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	client, server, raw := pipe(t, key, key)
+	go func() {
+		client.Write([]byte("hello"))
+		raw.Close()
+	}()
+	_, err := io.ReadAll(server)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestWrongKey(t *testing.T) {
+	client, server, _ := pipe(t,
+		bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize),
+		bytes.Repeat([]byte{0x02}, xaes256gcm.KeySize))
+	go client.Write([]byte("hello"))
+	if _, err := server.Read(make([]byte, 10)); err == nil {
+		t.Errorf("expected error with wrong key")
+	}
+}
+
+func TestRekey(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, s := net.Pipe()
+	errc := make(chan error, 1)
+	var server *channel.Conn
+	go func() {
+		var err error
+		server, err = channel.ServerWithOptions(s, key, &channel.Options{RekeyBytes: 100})
+		errc <- err
+	}()
+	client, err := channel.ClientWithOptions(c, key, &channel.Options{RekeyRecords: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatal(err)
+	}
+
+	msg := bytes.Repeat([]byte("0123456789"), channel.MaxRecordSize/2)
+	go func() {
+		for i := 0; i < 5; i++ {
+			client.Write(msg)
+		}
+	}()
+	got := make([]byte, 5*len(msg))
+	if _, err := io.ReadFull(server, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, bytes.Repeat(msg, 5)) {
+		t.Errorf("data received by the server doesn't match")
+	}
+
+	reply := []byte("0123456789012345678901234567890123456789")
+	go func() {
+		for i := 0; i < 50; i++ {
+			server.Write(reply)
+		}
+	}()
+	got = make([]byte, 50*len(reply))
+	if _, err := io.ReadFull(client, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, bytes.Repeat(reply, 50)) {
+		t.Errorf("data received by the client doesn't match")
+	}
+}
@@ -0,0 +1,86 @@
+// Command xaes-vectors generates the XAES-256-GCM accumulated test vectors
+// with this implementation, to cross-check other implementations.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/vectors"
+)
+
+const usage = `Usage:
+    xaes-vectors [-n ITERATIONS] [-json]
+
+Options:
+    -n ITERATIONS   Number of iterations of the accumulated test (default 10000).
+    -json           Also print each test case, in the Wycheproof JSON format.
+
+xaes-vectors prints the SHAKE128 digest of the ciphertexts of the accumulated
+test, as specified at https://c2sp.org/XAES-256-GCM. With -json, it prints a
+Wycheproof test vector file to standard output, and the digest to standard
+error.`
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("xaes-vectors: ")
+	flag.Usage = func() { fmt.Fprintf(os.Stderr, "%s\n", usage) }
+	n := flag.Int("n", 10_000, "number of iterations")
+	jsonFlag := flag.Bool("json", false, "print test cases as JSON")
+	flag.Parse()
+	if flag.NArg() > 0 || *n < 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	group := vectors.WycheproofGroup{Type: "AeadTest", KeySize: 256, IVSize: 192, TagSize: 128}
+	var f func(vectors.Vector)
+	if *jsonFlag {
+		f = func(v vectors.Vector) {
+			ct, tag := v.Ciphertext[:len(v.Plaintext)], v.Ciphertext[len(v.Plaintext):]
+			group.Tests = append(group.Tests, vectors.WycheproofCase{
+				TcID:    len(group.Tests) + 1,
+				Comment: "accumulated",
+				Key:     hex.EncodeToString(v.Key),
+				IV:      hex.EncodeToString(v.Nonce),
+				AAD:     hex.EncodeToString(v.AdditionalData),
+				Msg:     hex.EncodeToString(v.Plaintext),
+				CT:      hex.EncodeToString(ct),
+				Tag:     hex.EncodeToString(tag),
+				Result:  "valid",
+				Flags:   []string{},
+			})
+		}
+	}
+	digest, err := vectors.AccumulateCases(xaes256gcm.NewWithManualNonces, *n, f)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if !*jsonFlag {
+		fmt.Printf("%x\n", digest)
+		return
+	}
+	out := bufio.NewWriter(os.Stdout)
+	e := json.NewEncoder(out)
+	e.SetIndent("", "  ")
+	if err := e.Encode(vectors.WycheproofFile{
+		Algorithm:     "XAES-256-GCM",
+		NumberOfTests: len(group.Tests),
+		Header: []string{fmt.Sprintf("Accumulated test vectors, %d iterations,"+
+			" generated by filippo.io/xaes256gcm/cmd/xaes-vectors.", *n)},
+		TestGroups: []vectors.WycheproofGroup{group},
+	}); err != nil {
+		log.Fatal(err)
+	}
+	if err := out.Flush(); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Fprintf(os.Stderr, "%x\n", digest)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+// Armored files are PEM files with a single block of type armorType, without
+// headers. They are produced and parsed in a streaming fashion, since they
+// can be arbitrarily large.
+const (
+	armorType      = "XAES ENCRYPTED FILE"
+	armorHeader    = "-----BEGIN " + armorType + "-----"
+	armorFooter    = "-----END " + armorType + "-----"
+	armorLineBytes = 48 // 64 base64 characters
+)
+
+type armorWriter struct {
+	dst    *bufio.Writer
+	buf    []byte
+	line   []byte
+	closed bool
+}
+
+func newArmorWriter(dst io.Writer) (*armorWriter, error) {
+	bw := bufio.NewWriter(dst)
+	if _, err := bw.WriteString(armorHeader + "\n"); err != nil {
+		return nil, err
+	}
+	return &armorWriter{dst: bw, buf: make([]byte, 0, armorLineBytes),
+		line: make([]byte, base64.StdEncoding.EncodedLen(armorLineBytes)+1)}, nil
+}
+
+func (w *armorWriter) Write(p []byte) (int, error) {
+	total := len(p)
+	for len(p) > 0 {
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf, p = w.buf[:len(w.buf)+n], p[n:]
+		if len(w.buf) == armorLineBytes {
+			if err := w.flushLine(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return total, nil
+}
+
+func (w *armorWriter) flushLine() error {
+	n := base64.StdEncoding.EncodedLen(len(w.buf))
+	base64.StdEncoding.Encode(w.line, w.buf)
+	w.line[n] = '\n'
+	w.buf = w.buf[:0]
+	_, err := w.dst.Write(w.line[:n+1])
+	return err
+}
+
+// Close writes the last line and the footer. It does not close the underlying
+// Writer.
+func (w *armorWriter) Close() error {
+	if w.closed {
+		return errors.New("armored writer already closed")
+	}
+	w.closed = true
+	if len(w.buf) > 0 {
+		if err := w.flushLine(); err != nil {
+			return err
+		}
+	}
+	if _, err := w.dst.WriteString(armorFooter + "\n"); err != nil {
+		return err
+	}
+	return w.dst.Flush()
+}
+
+type armorReader struct {
+	src    *bufio.Reader
+	unread []byte
+	buf    []byte
+	err    error
+}
+
+func newArmorReader(src *bufio.Reader) (*armorReader, error) {
+	line, err := readArmorLine(src)
+	if err != nil {
+		return nil, err
+	}
+	if string(line) != armorHeader {
+		return nil, errors.New("invalid armor header")
+	}
+	return &armorReader{src: src, buf: make([]byte, armorLineBytes)}, nil
+}
+
+func readArmorLine(r *bufio.Reader) ([]byte, error) {
+	line, err := r.ReadSlice('\n')
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err == bufio.ErrBufferFull {
+		return nil, errors.New("invalid armor: line too long")
+	} else if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r")), nil
+}
+
+func (r *armorReader) Read(p []byte) (int, error) {
+	for len(r.unread) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		line, err := readArmorLine(r.src)
+		if err != nil {
+			r.err = err
+			return 0, err
+		}
+		if string(line) == armorFooter {
+			r.err = io.EOF
+			if rest, _ := io.ReadAll(r.src); len(bytes.TrimSpace(rest)) != 0 {
+				r.err = errors.New("invalid armor: trailing data")
+			}
+			continue
+		}
+		if base64.StdEncoding.DecodedLen(len(line)) > armorLineBytes {
+			r.err = errors.New("invalid armor: line too long")
+			return 0, r.err
+		}
+		n, err := base64.StdEncoding.Strict().Decode(r.buf, line)
+		if err != nil {
+			r.err = errors.New("invalid armor: malformed base64")
+			return 0, r.err
+		}
+		r.unread = r.buf[:n]
+	}
+	n := copy(p, r.unread)
+	r.unread = r.unread[n:]
+	return n, nil
+}
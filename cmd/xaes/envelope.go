@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Envelope files start with a header line containing a random data encryption
+// key (DEK) wrapped with the key file, the key encryption key (KEK), with
+// [xaes256gcm.WrapKey], followed by the stream encrypted with the DEK.
+//
+//	$xaes-envelope$<base64 wrapped DEK>
+//
+// The header has a fixed size, so the KEK can be changed by rewriting it in
+// place, without re-encrypting the stream.
+const envelopePrefix = "$xaes-envelope$"
+
+var envelopeHeaderSize = len(envelopePrefix) + b64.EncodedLen(xaes256gcm.WrappedKeySize) + 1
+
+func envelopeHeader(kek, dek xaes256gcm.Key) []byte {
+	return []byte(envelopePrefix + b64.EncodeToString(xaes256gcm.WrapKey(kek, dek)) + "\n")
+}
+
+// parseEnvelopeHeader returns the DEK wrapped in header with kek.
+func parseEnvelopeHeader(header []byte, kek xaes256gcm.Key) (xaes256gcm.Key, error) {
+	if len(header) != envelopeHeaderSize || !bytes.HasPrefix(header, []byte(envelopePrefix)) ||
+		header[len(header)-1] != '\n' {
+		return xaes256gcm.Key{}, errors.New("input is not an envelope file")
+	}
+	wrapped, err := b64.DecodeString(string(header[len(envelopePrefix) : len(header)-1]))
+	if err != nil {
+		return xaes256gcm.Key{}, errors.New("malformed envelope header")
+	}
+	dek, err := xaes256gcm.UnwrapKey(kek, wrapped)
+	if err != nil {
+		return xaes256gcm.Key{}, errors.New("failed to unwrap the data key (wrong key file?)")
+	}
+	return dek, nil
+}
+
+func sealWithEnvelope(kek []byte, in io.Reader, out io.Writer, armor bool) error {
+	k, err := xaes256gcm.NewKey(kek)
+	if err != nil {
+		return err
+	}
+	dek := xaes256gcm.GenerateKey()
+	return seal(dek.Bytes(), envelopeHeader(k, dek), in, out, armor)
+}
+
+func openEnvelope(kek []byte, r *bufio.Reader, out io.Writer) error {
+	k, err := xaes256gcm.NewKey(kek)
+	if err != nil {
+		return err
+	}
+	header, _ := r.Peek(envelopeHeaderSize)
+	dek, err := parseEnvelopeHeader(header, k)
+	if err != nil {
+		return err
+	}
+	r.Discard(envelopeHeaderSize)
+	return decrypt(dek.Bytes(), r, out, "corrupted file")
+}
+
+const rewrapUsage = `Usage:
+    xaes rewrap --old-kek PATH --new-kek PATH FILE...
+
+rewrap changes the key file of envelope files, produced by "xaes seal -e",
+by rewriting only their header in place. The encrypted data is not touched,
+so it's fast regardless of file size. Armored files are not supported.`
+
+func rewrapMain(args []string) {
+	fs := flag.NewFlagSet("rewrap", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintf(os.Stderr, "%s\n", rewrapUsage) }
+	var oldFlag, newFlag string
+	fs.StringVar(&oldFlag, "old-kek", "", "current key file")
+	fs.StringVar(&newFlag, "new-kek", "", "new key file")
+	fs.Parse(args)
+	if oldFlag == "" || newFlag == "" {
+		errorf("rewrap requires --old-kek and --new-kek")
+	}
+	if fs.NArg() == 0 {
+		errorf("rewrap requires at least one FILE")
+	}
+	oldKEK, newKEK := loadKEK(oldFlag), loadKEK(newFlag)
+	for _, name := range fs.Args() {
+		if err := rewrap(name, oldKEK, newKEK); err != nil {
+			errorf("failed to rewrap %q: %v", name, err)
+		}
+		fmt.Fprintf(os.Stderr, "xaes: rewrapped %q\n", name)
+	}
+}
+
+func loadKEK(name string) xaes256gcm.Key {
+	key, err := loadKey(name)
+	if err != nil {
+		errorf("%v", err)
+	}
+	k, err := xaes256gcm.NewKey(key)
+	if err != nil {
+		errorf("%v", err)
+	}
+	return k
+}
+
+// rewrap replaces the header of the envelope file at name, wrapping its DEK
+// with newKEK instead of oldKEK.
+func rewrap(name string, oldKEK, newKEK xaes256gcm.Key) error {
+	f, err := os.OpenFile(name, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	header := make([]byte, envelopeHeaderSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return errors.New("input is not an envelope file")
+	}
+	dek, err := parseEnvelopeHeader(header, oldKEK)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteAt(envelopeHeader(newKEK, dek), 0); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/keyset"
+	"filippo.io/xaes256gcm/stream"
+)
+
+// inspect prints what can be learned about an encrypted file without the key:
+// its encoding, the passphrase parameters, the stream nonce prefix and
+// metadata, and the sizes. Nothing is authenticated, so the output is only a
+// debugging aid.
+//
+// The version and features of a stream, such as compression, are encoded in
+// the flag bits of the chunk nonces, which are authenticated but not stored,
+// so they can only be learned by decrypting. The chunk size is fixed.
+func inspect(in io.Reader, out io.Writer) error {
+	br := bufio.NewReader(in)
+	start, _ := br.Peek(len(armorHeader))
+	armored := string(start) == armorHeader
+	r, err := dearmor(br)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "format:          filippo.io/xaes256gcm/stream, %d-byte chunks\n", stream.ChunkSize)
+	fmt.Fprintf(out, "armored:         %v\n", armored)
+
+	wrapped := false // key derived from a passphrase or wrapped in the header
+	if start, _ := r.Peek(len(passphrasePrefix)); string(start) == passphrasePrefix {
+		p, err := readHeader(r)
+		if err != nil {
+			return err
+		}
+		wrapped = true
+		fmt.Fprintf(out, "key:             passphrase, Argon2id m=%d,t=%d,p=%d, salt %x\n",
+			p.memory, p.time, p.threads, p.salt)
+	} else if start, _ := r.Peek(len(envelopePrefix)); string(start) == envelopePrefix {
+		if _, err := r.Discard(envelopeHeaderSize); err != nil {
+			return errors.New("input is too short to be an envelope file")
+		}
+		wrapped = true
+		fmt.Fprintf(out, "key:             data key wrapped with a key file\n")
+	} else if start, _ := r.Peek(len(pluginPrefix)); string(start) == pluginPrefix {
+		name, config, _, err := readPluginHeader(r)
+		if err != nil {
+			return err
+		}
+		wrapped = true
+		fmt.Fprintf(out, "key:             data key wrapped by plugin %q, config %q\n", name, config)
+	} else {
+		fmt.Fprintf(out, "key:             key file or keyset\n")
+	}
+
+	header := make([]byte, stream.HeaderSize+5)
+	n, err := io.ReadFull(r, header)
+	if n < stream.HeaderSize {
+		return errors.New("input is too short to be a stream")
+	}
+	header = header[:n]
+	fmt.Fprintf(out, "nonce prefix:    %x\n", header[:stream.HeaderSize])
+
+	// Random chunk bytes only look like a valid metadata record header once in
+	// about 2²³ streams, so its presence is detected with high probability.
+	metadata := "none"
+	const overhead = xaes256gcm.OverheadWithManualNonces
+	total := int64(n)
+	if err == nil {
+		typ, length := header[stream.HeaderSize], binary.BigEndian.Uint32(header[stream.HeaderSize+1:])
+		if typ <= 0x01 && length >= overhead && length <= stream.MaxMetadataSize+overhead {
+			record := make([]byte, length)
+			if _, err := io.ReadFull(r, record); err != nil {
+				return fmt.Errorf("failed to read metadata: %v", err)
+			}
+			total += int64(length)
+			if typ == 0x01 {
+				metadata = fmt.Sprintf("encrypted, %d bytes", length-overhead)
+			} else {
+				metadata = "public, " + strconv.Quote(string(record[:length-overhead]))
+			}
+		}
+	}
+	fmt.Fprintf(out, "metadata:        %s\n", metadata)
+
+	rest, err := io.Copy(io.Discard, r)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %v", err)
+	}
+	total += rest
+	fmt.Fprintf(out, "encrypted size:  %d bytes\n", total)
+	if size, err := stream.DecryptedSize(total); metadata == "none" && err == nil {
+		fmt.Fprintf(out, "plaintext size:  %d bytes, unless compressed\n", size)
+	} else {
+		fmt.Fprintf(out, "plaintext size:  unknown, compressed, with metadata, or truncated\n")
+	}
+
+	if !armored && !wrapped && total >= keyset.Overhead {
+		fmt.Fprintf(out, "keyset key ID:   %08x, if this is a keyset ciphertext\n", binary.BigEndian.Uint32(header))
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/keyset"
+)
+
+const keysetUsage = `Usage:
+    xaes keyset rotate [-k PATH] [-l DURATION] [-r DIRECTORY] KEYSET
+
+Options:
+    -k, --key PATH          Encrypt the keyset with the key file at PATH.
+    -l, --lifetime DURATION Expire the new primary key after DURATION.
+    -r, --reencrypt DIRECTORY
+                            Re-encrypt the files in DIRECTORY with the new
+                            primary key.
+
+rotate adds a new primary key to the keyset at KEYSET, creating it if it
+doesn't exist. The previous keys are kept, so existing ciphertexts can still
+be decrypted, until they are re-encrypted with -r.
+
+Files in DIRECTORY must be ciphertexts produced by a filippo.io/xaes256gcm/keyset
+Keyset, without additional data. Each re-encrypted file is logged to
+standard error, and is atomically replaced.
+
+Example:
+    $ xaes keygen -o kek.txt
+    $ xaes keyset rotate -k kek.txt -l 2160h -r secrets/ keyset.json`
+
+func keysetMain(args []string) {
+	if len(args) < 1 || args[0] != "rotate" {
+		fmt.Fprintf(os.Stderr, "%s\n", keysetUsage)
+		os.Exit(1)
+	}
+	fs := flag.NewFlagSet("keyset rotate", flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintf(os.Stderr, "%s\n", keysetUsage) }
+	var keyFlag, dirFlag string
+	var lifetimeFlag time.Duration
+	fs.StringVar(&keyFlag, "k", "", "key file")
+	fs.StringVar(&keyFlag, "key", "", "key file")
+	fs.DurationVar(&lifetimeFlag, "l", 0, "lifetime of the new key")
+	fs.DurationVar(&lifetimeFlag, "lifetime", 0, "lifetime of the new key")
+	fs.StringVar(&dirFlag, "r", "", "directory to re-encrypt")
+	fs.StringVar(&dirFlag, "reencrypt", "", "directory to re-encrypt")
+	fs.Parse(args[1:])
+	if fs.NArg() != 1 {
+		errorf("rotate requires a single KEYSET argument")
+	}
+	if lifetimeFlag < 0 {
+		errorf("lifetime must be positive")
+	}
+	var w keyset.Wrapper
+	if keyFlag != "" {
+		key, err := loadKey(keyFlag)
+		if err != nil {
+			errorf("%v", err)
+		}
+		kek, err := xaes256gcm.NewKey(key)
+		if err != nil {
+			errorf("%v", err)
+		}
+		w = keyset.KeyWrapper(kek)
+	}
+	if err := rotateKeyset(fs.Arg(0), w, lifetimeFlag, dirFlag, os.Stderr); err != nil {
+		errorf("%v", err)
+	}
+}
+
+// rotateKeyset adds a new primary key to the keyset at name, encrypted with w
+// if not nil, and then re-encrypts the files in dir, if not empty. Actions are
+// logged to log.
+func rotateKeyset(name string, w keyset.Wrapper, lifetime time.Duration, dir string, log io.Writer) error {
+	ks, err := readKeyset(name, w)
+	if errors.Is(err, fs.ErrNotExist) {
+		ks, err = keyset.New(), nil
+		fmt.Fprintf(log, "xaes: creating keyset %q\n", name)
+	}
+	if err != nil {
+		return err
+	}
+	old, hadPrimary := ks.Primary()
+	id, err := ks.Rotate(lifetime)
+	if err != nil {
+		return err
+	}
+	if err := writeKeyset(name, ks, w); err != nil {
+		return err
+	}
+	if hadPrimary {
+		fmt.Fprintf(log, "xaes: demoted key %08x (%s)\n", old.ID, old.Fingerprint)
+	}
+	primary, _ := ks.Primary()
+	fmt.Fprintf(log, "xaes: added primary key %08x (%s)\n", id, primary.Fingerprint)
+
+	if dir == "" {
+		return nil
+	}
+	// The keyset is written before re-encrypting, so that a failure can't
+	// leave files encrypted with a key that was not saved.
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		ciphertext, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		from, err := keyset.KeyID(ciphertext)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt %q: %v", path, err)
+		}
+		if from == id {
+			return nil
+		}
+		plaintext, err := ks.Open(nil, ciphertext, nil)
+		if err != nil {
+			return fmt.Errorf("failed to re-encrypt %q: %v", path, err)
+		}
+		ciphertext, err = ks.Seal(nil, plaintext, nil)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(path, ciphertext, info.Mode().Perm()); err != nil {
+			return err
+		}
+		fmt.Fprintf(log, "xaes: re-encrypted %q from key %08x to key %08x\n", path, from, id)
+		return nil
+	})
+}
+
+func readKeyset(name string, w keyset.Wrapper) (*keyset.Keyset, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	if w != nil {
+		return keyset.Decrypt(data, w)
+	}
+	return keyset.Parse(data)
+}
+
+func writeKeyset(name string, ks *keyset.Keyset, w keyset.Wrapper) error {
+	var data []byte
+	var err error
+	if w != nil {
+		data, err = ks.Encrypt(w)
+	} else {
+		data, err = ks.Marshal()
+	}
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(name, append(data, '\n'), 0600)
+}
+
+// writeFileAtomic replaces the file at name with data, by writing it to a
+// temporary file and renaming it.
+func writeFileAtomic(name string, data []byte, perm os.FileMode) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(name), "."+filepath.Base(name)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := f.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
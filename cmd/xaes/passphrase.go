@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+
+	"filippo.io/xaes256gcm"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/term"
+)
+
+// Passphrase-encrypted files start with a header line in the PHC string
+// format, encoding the Argon2id parameters and salt used to derive the key,
+// followed by a newline. The header line is not included in the additional
+// data, but any change to it changes the derived key, and non-canonical
+// encodings are rejected.
+//
+//	$argon2id$v=19$m=65536,t=3,p=4$<base64 salt>
+//
+// Parameters are the second recommended option of RFC 9106, Section 4.
+const (
+	argon2Memory  = 64 * 1024
+	argon2Time    = 3
+	argon2Threads = 4
+	saltSize      = 16
+
+	maxArgon2Memory  = 2 * 1024 * 1024
+	maxArgon2Time    = 16
+	maxHeaderSize    = 128
+	passphrasePrefix = "$argon2id$"
+)
+
+var b64 = base64.RawStdEncoding.Strict()
+
+type argon2Params struct {
+	memory, time uint32
+	threads      uint8
+	salt         []byte
+}
+
+func (p *argon2Params) header() []byte {
+	return []byte(fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s\n", argon2.Version,
+		p.memory, p.time, p.threads, b64.EncodeToString(p.salt)))
+}
+
+func (p *argon2Params) key(passphrase []byte) []byte {
+	return argon2.IDKey(passphrase, p.salt, p.time, p.memory, p.threads, xaes256gcm.KeySize)
+}
+
+func newArgon2Params() (*argon2Params, error) {
+	p := &argon2Params{
+		memory:  argon2Memory,
+		time:    argon2Time,
+		threads: argon2Threads,
+		salt:    make([]byte, saltSize),
+	}
+	if _, err := rand.Read(p.salt); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// readHeader reads and parses the header line from r.
+func readHeader(r *bufio.Reader) (*argon2Params, error) {
+	header, err := r.Peek(maxHeaderSize)
+	if err != nil && len(header) == 0 {
+		return nil, fmt.Errorf("failed to read header: %v", err)
+	}
+	i := bytes.IndexByte(header, '\n')
+	if !bytes.HasPrefix(header, []byte(passphrasePrefix)) || i < 0 {
+		return nil, errors.New("input is not passphrase-encrypted")
+	}
+	header = bytes.Clone(header[:i+1])
+	r.Discard(len(header))
+
+	p := &argon2Params{}
+	var version int
+	var salt string
+	if _, err := fmt.Sscanf(string(header), "$argon2id$v=%d$m=%d,t=%d,p=%d$%s\n",
+		&version, &p.memory, &p.time, &p.threads, &salt); err != nil {
+		return nil, fmt.Errorf("malformed header: %v", err)
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported Argon2 version %d", version)
+	}
+	if p.memory > maxArgon2Memory || p.time > maxArgon2Time {
+		return nil, errors.New("Argon2id parameters are too expensive")
+	}
+	if p.memory == 0 || p.time == 0 || p.threads == 0 {
+		return nil, errors.New("invalid Argon2id parameters")
+	}
+	if p.salt, err = b64.DecodeString(salt); err != nil || len(p.salt) < saltSize {
+		return nil, errors.New("invalid Argon2id salt")
+	}
+	if !bytes.Equal(p.header(), header) {
+		return nil, errors.New("non-canonical header")
+	}
+	return p, nil
+}
+
+func readPassphrase(prompt string) ([]byte, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open terminal: %v", err)
+	}
+	defer tty.Close()
+	fmt.Fprintf(tty, "%s", prompt)
+	defer fmt.Fprintf(tty, "\n")
+	p, err := term.ReadPassword(int(tty.Fd()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read passphrase: %v", err)
+	}
+	if len(p) == 0 {
+		return nil, errors.New("empty passphrase")
+	}
+	return p, nil
+}
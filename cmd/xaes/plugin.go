@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/plugin"
+)
+
+// Plugin envelope files start with a header line naming the plugin that
+// wrapped the data encryption key, its configuration, and the wrapped key,
+// followed by the stream encrypted with the data key.
+//
+//	$xaes-plugin$<name>$<base64 config>$<base64 wrapped DEK>
+//
+// open finds the plugin by name, so it doesn't need a key.
+const pluginPrefix = "$xaes-plugin$"
+
+var pluginUI = &plugin.UI{
+	DisplayMessage: func(name, message string) error {
+		fmt.Fprintf(os.Stderr, "xaes: %s: %s\n", name, message)
+		return nil
+	},
+	RequestSecret: func(name, prompt string) (string, error) {
+		secret, err := readPassphraseFunc(name + ": " + prompt)
+		return string(secret), err
+	},
+}
+
+// parsePluginRef parses a --plugin value of the form NAME[:CONFIG].
+func parsePluginRef(ref string) (name, config string, err error) {
+	name, config, _ = strings.Cut(ref, ":")
+	if !plugin.ValidName(name) {
+		return "", "", fmt.Errorf("invalid plugin name %q", name)
+	}
+	return name, config, nil
+}
+
+func sealWithPlugin(ref string, in io.Reader, out io.Writer, armor bool) error {
+	name, config, err := parsePluginRef(ref)
+	if err != nil {
+		return err
+	}
+	p, err := plugin.NewProvider(name, config, pluginUI)
+	if err != nil {
+		return err
+	}
+	dek := xaes256gcm.GenerateKey()
+	wrapped, err := p.WrapKey(dek)
+	if err != nil {
+		return err
+	}
+	header := pluginPrefix + name + "$" + b64.EncodeToString([]byte(config)) +
+		"$" + b64.EncodeToString(wrapped) + "\n"
+	return seal(dek.Bytes(), []byte(header), in, out, armor)
+}
+
+// readPluginHeader reads a plugin envelope header from r, and returns the
+// plugin name, configuration, and wrapped key.
+func readPluginHeader(r *bufio.Reader) (name, config string, wrapped []byte, err error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil || !strings.HasPrefix(string(line), pluginPrefix) {
+		return "", "", nil, errors.New("malformed plugin header")
+	}
+	fields := strings.Split(strings.TrimSuffix(string(line[len(pluginPrefix):]), "\n"), "$")
+	if len(fields) != 3 || !plugin.ValidName(fields[0]) {
+		return "", "", nil, errors.New("malformed plugin header")
+	}
+	c, err := b64.DecodeString(fields[1])
+	if err != nil {
+		return "", "", nil, errors.New("malformed plugin header")
+	}
+	wrapped, err = b64.DecodeString(fields[2])
+	if err != nil {
+		return "", "", nil, errors.New("malformed plugin header")
+	}
+	return fields[0], string(c), wrapped, nil
+}
+
+func openWithPlugin(r *bufio.Reader, out io.Writer) error {
+	name, config, wrapped, err := readPluginHeader(r)
+	if err != nil {
+		return err
+	}
+	p, err := plugin.NewProvider(name, config, pluginUI)
+	if err != nil {
+		return err
+	}
+	dek, err := p.UnwrapKey(wrapped)
+	if err != nil {
+		return err
+	}
+	return decrypt(dek.Bytes(), r, out, "corrupted file")
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"crypto/cipher"
+	"io"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaesjson"
+)
+
+// sealValues encrypts the values of the JSON document read from in, leaving
+// its structure and keys readable, with [xaesjson.EncryptValues].
+func sealValues(key []byte, in io.Reader, out io.Writer) error {
+	return transformValues(key, in, out, xaesjson.EncryptValues)
+}
+
+// openValues decrypts the values of a JSON document produced by sealValues.
+func openValues(key []byte, in io.Reader, out io.Writer) error {
+	return transformValues(key, in, out, xaesjson.DecryptValues)
+}
+
+func transformValues(key []byte, in io.Reader, out io.Writer, f func(cipher.AEAD, []byte) ([]byte, error)) error {
+	aead, err := xaes256gcm.New(key)
+	if err != nil {
+		return err
+	}
+	doc, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	doc, err = f(aead, doc)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(doc)
+	return err
+}
@@ -0,0 +1,413 @@
+// Command xaes encrypts and decrypts files with XAES-256-GCM.
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/stream"
+)
+
+const usage = `Usage:
+    xaes keygen [-o OUTPUT]
+    xaes seal (-k PATH [-e] | -p | --plugin NAME[:CONFIG]) [-a] [-o OUTPUT] [INPUT]
+    xaes seal (-k PATH | -p | --plugin NAME[:CONFIG]) [-a] [-o OUTPUT] -r DIRECTORY
+    xaes seal -k PATH -j [-o OUTPUT] [INPUT]
+    xaes open (-k PATH | -p) [-o OUTPUT] [INPUT]
+    xaes open (-k PATH | -p) -u -o DIRECTORY [INPUT]
+    xaes open -k PATH -j [-o OUTPUT] [INPUT]
+    xaes inspect [INPUT]
+    xaes rewrap --old-kek PATH --new-kek PATH FILE...
+    xaes keyset rotate [-k PATH] [-l DURATION] [-r DIRECTORY] KEYSET
+
+Options:
+    -k, --key PATH          Use the key file at PATH.
+    -p, --passphrase        Use a key derived from a passphrase.
+    -e, --envelope          Encrypt with a random data key, wrapped with
+                            the key file. See "xaes rewrap -h".
+    --plugin NAME[:CONFIG]  Encrypt with a random data key, wrapped by the
+                            xaes-plugin-NAME program in $PATH.
+    -a, --armor             Encrypt to a PEM encoded format.
+    -o, --output OUTPUT     Write the result to the file at path OUTPUT.
+    -r, --recursive         Encrypt a tar archive of DIRECTORY.
+    -u, --unpack            Extract the decrypted tar archive to DIRECTORY,
+                            which must not exist.
+    -j, --json-values       Encrypt only the values of a JSON document.
+
+INPUT defaults to standard input, and OUTPUT defaults to standard output.
+If OUTPUT exists, it will be overwritten, except by keygen.
+
+inspect prints the format details of an encrypted file that can be read
+without the key, such as the nonce prefix, the metadata, and the sizes, to
+help debug files that fail to open. Nothing it prints is authenticated.
+
+Key files contain a 32-byte key encoded as 64 hexadecimal characters.
+
+Encrypted files use the chunked format of filippo.io/xaes256gcm/stream, so
+inputs of any size are processed in constant memory, and corruption and
+truncation are detected chunk by chunk. If open fails, OUTPUT might contain
+the plaintext preceding the first invalid chunk.
+
+Archives only contain regular files and directories, and are extracted
+as they are decrypted, so a failure might leave a partially extracted
+DIRECTORY behind.
+
+With --json-values, strings, numbers, and booleans are replaced by
+"xaes:" strings bound to their path in the document, and the structure and
+keys are left readable, for example to review changes to configuration
+files. The values are not chunked, and are decrypted with open -j.
+
+With --passphrase, files are preceded by a line encoding the Argon2id
+parameters, and with --envelope by a line encoding the wrapped data key.
+Armored and envelope files are detected automatically by open.
+
+With --plugin, the data key is wrapped by an external program, such as a
+smartcard or KMS client, which is passed CONFIG. The plugin name and CONFIG
+are stored in the file header, so open invokes the same plugin without
+needing -k or -p.
+
+See "xaes keyset -h" for managing keysets.
+
+Example:
+    $ xaes keygen -o key.txt
+    $ xaes seal -k key.txt -o data.txt.xaes data.txt
+    $ xaes open -k key.txt data.txt.xaes`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "%s\n", usage)
+		os.Exit(1)
+	}
+	if os.Args[1] == "rewrap" {
+		rewrapMain(os.Args[2:])
+		return
+	}
+	if os.Args[1] == "keyset" {
+		keysetMain(os.Args[2:])
+		return
+	}
+
+	fs := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprintf(os.Stderr, "%s\n", usage) }
+	var outFlag, keyFlag, pluginFlag string
+	var passFlag, armorFlag, recursiveFlag, unpackFlag, envelopeFlag, valuesFlag bool
+	fs.StringVar(&outFlag, "o", "", "output to `FILE` (default stdout)")
+	fs.StringVar(&outFlag, "output", "", "output to `FILE` (default stdout)")
+	fs.StringVar(&keyFlag, "k", "", "key file")
+	fs.StringVar(&keyFlag, "key", "", "key file")
+	fs.BoolVar(&passFlag, "p", false, "use a passphrase")
+	fs.BoolVar(&passFlag, "passphrase", false, "use a passphrase")
+	fs.BoolVar(&envelopeFlag, "e", false, "use a wrapped data key")
+	fs.BoolVar(&envelopeFlag, "envelope", false, "use a wrapped data key")
+	fs.StringVar(&pluginFlag, "plugin", "", "wrap the data key with a plugin")
+	fs.BoolVar(&armorFlag, "a", false, "generate an armored file")
+	fs.BoolVar(&armorFlag, "armor", false, "generate an armored file")
+	fs.BoolVar(&recursiveFlag, "r", false, "encrypt a directory")
+	fs.BoolVar(&recursiveFlag, "recursive", false, "encrypt a directory")
+	fs.BoolVar(&unpackFlag, "u", false, "extract to a directory")
+	fs.BoolVar(&unpackFlag, "unpack", false, "extract to a directory")
+	fs.BoolVar(&valuesFlag, "j", false, "encrypt the values of a JSON document")
+	fs.BoolVar(&valuesFlag, "json-values", false, "encrypt the values of a JSON document")
+	fs.Parse(os.Args[2:])
+
+	switch os.Args[1] {
+	case "keygen":
+		if fs.NArg() > 0 {
+			errorf("keygen doesn't take positional arguments")
+		}
+		if keyFlag != "" || passFlag || armorFlag || recursiveFlag || unpackFlag || envelopeFlag || valuesFlag || pluginFlag != "" {
+			errorf("keygen only takes -o")
+		}
+		out := os.Stdout
+		if outFlag != "" {
+			f, err := os.OpenFile(outFlag, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+			if err != nil {
+				errorf("failed to open output file %q: %v", outFlag, err)
+			}
+			defer f.Close()
+			out = f
+		}
+		if err := keygen(out); err != nil {
+			errorf("%v", err)
+		}
+	case "inspect":
+		if fs.NArg() > 1 {
+			errorf("too many INPUT arguments: %q", fs.Args())
+		}
+		if keyFlag != "" || passFlag || armorFlag || recursiveFlag || unpackFlag || envelopeFlag || valuesFlag || pluginFlag != "" || outFlag != "" {
+			errorf("inspect doesn't take options")
+		}
+		in := io.Reader(os.Stdin)
+		if name := fs.Arg(0); name != "" && name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				errorf("failed to open input file %q: %v", name, err)
+			}
+			defer f.Close()
+			in = f
+		}
+		if err := inspect(in, os.Stdout); err != nil {
+			errorf("%v", err)
+		}
+	case "seal", "open":
+		if fs.NArg() > 1 {
+			errorf("too many INPUT arguments: %q", fs.Args())
+		}
+		if pluginFlag != "" && os.Args[1] == "open" {
+			errorf("--plugin is only used by seal, plugin files are detected automatically")
+		}
+		if pluginFlag != "" && (keyFlag != "" || passFlag || envelopeFlag || valuesFlag) {
+			errorf("--plugin can't be used with -k, -p, -e, or -j")
+		}
+		if keyFlag == "" && !passFlag && pluginFlag == "" && (os.Args[1] == "seal" || valuesFlag) {
+			errorf("missing key, use -k, -p, or --plugin")
+		}
+		if keyFlag != "" && passFlag {
+			errorf("-k and -p can't be used together")
+		}
+		if envelopeFlag && os.Args[1] == "open" {
+			errorf("-e is only used by seal, envelope files are detected automatically")
+		}
+		if envelopeFlag && passFlag {
+			errorf("-e requires -k")
+		}
+		if armorFlag && os.Args[1] == "open" {
+			errorf("-a is only used by seal, armored files are detected automatically")
+		}
+		if recursiveFlag && os.Args[1] == "open" {
+			errorf("-r is only used by seal, use -u to extract archives")
+		}
+		if unpackFlag && os.Args[1] == "seal" {
+			errorf("-u is only used by open, use -r to encrypt directories")
+		}
+		if valuesFlag && (passFlag || envelopeFlag || armorFlag || recursiveFlag || unpackFlag) {
+			errorf("-j requires -k, and can't be used with -p, -e, -a, -r, or -u")
+		}
+		if recursiveFlag && fs.NArg() != 1 {
+			errorf("-r requires a DIRECTORY argument")
+		}
+		if unpackFlag && (outFlag == "" || outFlag == "-") {
+			errorf("-u requires an output DIRECTORY, use -o")
+		}
+		var key []byte
+		if keyFlag != "" {
+			var err error
+			if key, err = loadKey(keyFlag); err != nil {
+				errorf("%v", err)
+			}
+		}
+		in := io.Reader(os.Stdin)
+		if recursiveFlag {
+			pr, pw := io.Pipe()
+			go func() { pw.CloseWithError(writeTar(pw, fs.Arg(0))) }()
+			in = pr
+		} else if name := fs.Arg(0); name != "" && name != "-" {
+			f, err := os.Open(name)
+			if err != nil {
+				errorf("failed to open input file %q: %v", name, err)
+			}
+			defer f.Close()
+			in = f
+		}
+		out := io.Writer(os.Stdout)
+		var unpack *io.PipeWriter
+		var extracted chan error
+		if unpackFlag {
+			var pr *io.PipeReader
+			pr, unpack = io.Pipe()
+			extracted = make(chan error, 1)
+			go func() {
+				err := extractTar(pr, outFlag)
+				pr.CloseWithError(err)
+				extracted <- err
+			}()
+			out = unpack
+		} else if outFlag != "" && outFlag != "-" {
+			f, err := os.Create(outFlag)
+			if err != nil {
+				errorf("failed to open output file %q: %v", outFlag, err)
+			}
+			defer func() {
+				if err := f.Close(); err != nil {
+					errorf("failed to close output file %q: %v", outFlag, err)
+				}
+			}()
+			out = f
+		}
+		var err error
+		switch {
+		case os.Args[1] == "seal" && valuesFlag:
+			err = sealValues(key, in, out)
+		case valuesFlag:
+			err = openValues(key, in, out)
+		case os.Args[1] == "seal" && passFlag:
+			err = sealWithPassphrase(in, out, armorFlag)
+		case os.Args[1] == "seal" && pluginFlag != "":
+			err = sealWithPlugin(pluginFlag, in, out, armorFlag)
+		case os.Args[1] == "seal" && envelopeFlag:
+			err = sealWithEnvelope(key, in, out, armorFlag)
+		case os.Args[1] == "seal":
+			err = seal(key, nil, in, out, armorFlag)
+		case passFlag:
+			err = openWithPassphrase(in, out)
+		default:
+			err = open(key, in, out)
+		}
+		if unpack != nil {
+			// If extraction failed, decryption failed writing to the pipe.
+			unpack.CloseWithError(err)
+			if err := <-extracted; err != nil {
+				errorf("failed to extract archive: %v", err)
+			}
+		}
+		if err != nil {
+			errorf("%v", err)
+		}
+	default:
+		errorf("unknown command %q, see -h", os.Args[1])
+	}
+}
+
+func keygen(out io.Writer) error {
+	key := make([]byte, xaes256gcm.KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return fmt.Errorf("failed to generate key: %v", err)
+	}
+	_, err := fmt.Fprintf(out, "%x\n", key)
+	return err
+}
+
+// loadKey reads a key file with [xaes256gcm.LoadKeyFile], so the CLI accepts
+// the same encodings as the library, and rejects world-readable files.
+func loadKey(name string) ([]byte, error) {
+	key, err := xaes256gcm.LoadKeyFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return key.Bytes(), nil
+}
+
+var readPassphraseFunc = readPassphrase
+
+func sealWithPassphrase(in io.Reader, out io.Writer, armor bool) error {
+	passphrase, err := readPassphraseFunc("Enter passphrase: ")
+	if err != nil {
+		return err
+	}
+	confirm, err := readPassphraseFunc("Confirm passphrase: ")
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(passphrase, confirm) {
+		return errors.New("passphrases didn't match")
+	}
+	p, err := newArgon2Params()
+	if err != nil {
+		return err
+	}
+	return seal(p.key(passphrase), p.header(), in, out, armor)
+}
+
+// seal encrypts in to out as a stream, prefixed by header.
+func seal(key, header []byte, in io.Reader, out io.Writer, armor bool) error {
+	var a *armorWriter
+	if armor {
+		var err error
+		if a, err = newArmorWriter(out); err != nil {
+			return fmt.Errorf("failed to write output: %v", err)
+		}
+		out = a
+	}
+	if _, err := out.Write(header); err != nil {
+		return fmt.Errorf("failed to write output: %v", err)
+	}
+	w, err := stream.NewWriter(key, out)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(w, in); err != nil {
+		return fmt.Errorf("failed to encrypt: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to write output: %v", err)
+	}
+	if a != nil {
+		if err := a.Close(); err != nil {
+			return fmt.Errorf("failed to write output: %v", err)
+		}
+	}
+	return nil
+}
+
+// dearmor returns a reader for the contents of in, decoding it if it's
+// armored.
+func dearmor(in io.Reader) (*bufio.Reader, error) {
+	r := bufio.NewReader(in)
+	if start, _ := r.Peek(len(armorHeader)); string(start) != armorHeader {
+		return r, nil
+	}
+	a, err := newArmorReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewReader(a), nil
+}
+
+func open(key []byte, in io.Reader, out io.Writer) error {
+	r, err := dearmor(in)
+	if err != nil {
+		return err
+	}
+	if start, _ := r.Peek(len(passphrasePrefix)); string(start) == passphrasePrefix {
+		return errors.New("input is passphrase-encrypted, use -p")
+	}
+	if start, _ := r.Peek(len(pluginPrefix)); string(start) == pluginPrefix {
+		return openWithPlugin(r, out)
+	}
+	if key == nil {
+		return errors.New("missing key, use -k or -p")
+	}
+	if start, _ := r.Peek(len(envelopePrefix)); string(start) == envelopePrefix {
+		return openEnvelope(key, r, out)
+	}
+	return decrypt(key, r, out, "wrong key or corrupted file")
+}
+
+func openWithPassphrase(in io.Reader, out io.Writer) error {
+	r, err := dearmor(in)
+	if err != nil {
+		return err
+	}
+	p, err := readHeader(r)
+	if err != nil {
+		return err
+	}
+	passphrase, err := readPassphraseFunc("Enter passphrase: ")
+	if err != nil {
+		return err
+	}
+	return decrypt(p.key(passphrase), r, out, "wrong passphrase or corrupted file")
+}
+
+func decrypt(key []byte, in io.Reader, out io.Writer, hint string) error {
+	r, err := stream.NewReader(key, in)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %v", err)
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		return fmt.Errorf("failed to decrypt: %v (%s)", err, hint)
+	}
+	return nil
+}
+
+func errorf(format string, v ...any) {
+	fmt.Fprintf(os.Stderr, "xaes: error: "+format+"\n", v...)
+	os.Exit(1)
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/keyset"
+	"filippo.io/xaes256gcm/plugin"
+	"filippo.io/xaes256gcm/stream"
+)
+
+// testPlugin wraps keys with a KEK derived from the config.
+type testPlugin struct{}
+
+func (testPlugin) WrapKey(config string, dek xaes256gcm.Key, c *plugin.Conn) ([]byte, error) {
+	h := sha256.Sum256([]byte(config))
+	return xaes256gcm.WrapKey(xaes256gcm.MustKey(h[:]), dek), nil
+}
+
+func (testPlugin) UnwrapKey(config string, wrapped []byte, c *plugin.Conn) (xaes256gcm.Key, error) {
+	h := sha256.Sum256([]byte(config))
+	return xaes256gcm.UnwrapKey(xaes256gcm.MustKey(h[:]), wrapped)
+}
+
+func TestMain(m *testing.M) {
+	if strings.HasPrefix(filepath.Base(os.Args[0]), plugin.Prefix) {
+		plugin.Main(testPlugin{})
+	}
+	os.Exit(m.Run())
+}
+
+func TestRoundTrip(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key.txt")
+	keyBuf := &bytes.Buffer{}
+	if err := keygen(keyBuf); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, keyBuf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	key, err := loadKey(keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, armor := range []bool{false, true} {
+		plaintext := bytes.Repeat([]byte("hello, xaes\n"), 10000)
+		sealed := &bytes.Buffer{}
+		if err := seal(key, nil, bytes.NewReader(plaintext), sealed, armor); err != nil {
+			t.Fatal(err)
+		}
+		opened := &bytes.Buffer{}
+		if err := open(key, bytes.NewReader(sealed.Bytes()), opened); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(opened.Bytes(), plaintext) {
+			t.Errorf("got %q", opened.Bytes())
+		}
+
+		sealed.Bytes()[sealed.Len()-10] ^= 1
+		if err := open(key, bytes.NewReader(sealed.Bytes()), &bytes.Buffer{}); err == nil {
+			t.Errorf("corrupted file opened")
+		}
+	}
+}
+
+func TestLoadKey(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	keyFile := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(keyFile, []byte(key.Bech32()+"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := loadKey(keyFile); err != nil || !bytes.Equal(got, key.Bytes()) {
+		t.Errorf("loadKey of a Bech32 key: %x, %v", got, err)
+	}
+	if runtime.GOOS == "windows" {
+		return
+	}
+	if err := os.Chmod(keyFile, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadKey(keyFile); err == nil {
+		t.Errorf("loadKey accepted a world-readable key file")
+	}
+}
+
+func TestPassphrase(t *testing.T) {
+	readPassphraseFunc = func(string) ([]byte, error) { return []byte("correct horse"), nil }
+	defer func() { readPassphraseFunc = readPassphrase }()
+
+	plaintext := []byte("hello, xaes")
+	sealed := &bytes.Buffer{}
+	if err := sealWithPassphrase(bytes.NewReader(plaintext), sealed, true); err != nil {
+		t.Fatal(err)
+	}
+	opened := &bytes.Buffer{}
+	if err := openWithPassphrase(bytes.NewReader(sealed.Bytes()), opened); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened.Bytes(), plaintext) {
+		t.Errorf("got %q", opened.Bytes())
+	}
+
+	if err := open(make([]byte, 32), bytes.NewReader(sealed.Bytes()), &bytes.Buffer{}); err == nil {
+		t.Errorf("passphrase-encrypted file opened with key")
+	}
+	readPassphraseFunc = func(string) ([]byte, error) { return []byte("wrong"), nil }
+	if err := openWithPassphrase(bytes.NewReader(sealed.Bytes()), &bytes.Buffer{}); err == nil {
+		t.Errorf("opened with wrong passphrase")
+	}
+}
+
+func TestArchive(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "data")
+	if err := os.MkdirAll(filepath.Join(dir, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "a", "b", "c.txt"), []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "d.txt"), []byte("world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	archive := &bytes.Buffer{}
+	if err := writeTar(archive, dir); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(t.TempDir(), "out")
+	if err := extractTar(bytes.NewReader(archive.Bytes()), out); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(filepath.Join(out, "a", "b", "c.txt")); err != nil || string(got) != "hello" {
+		t.Errorf("a/b/c.txt: %q, %v", got, err)
+	}
+	if fi, err := os.Stat(filepath.Join(out, "d.txt")); err != nil || fi.Mode().Perm() != 0644 {
+		t.Errorf("d.txt: %v, %v", fi, err)
+	}
+	if err := extractTar(bytes.NewReader(archive.Bytes()), out); err == nil {
+		t.Errorf("extracted into existing directory")
+	}
+}
+
+func TestArchiveTraversal(t *testing.T) {
+	archive := &bytes.Buffer{}
+	tw := tar.NewWriter(archive)
+	tw.WriteHeader(&tar.Header{Name: "../evil.txt", Mode: 0644, Size: 4, Typeflag: tar.TypeReg})
+	tw.Write([]byte("evil"))
+	tw.Close()
+	dir := t.TempDir()
+	if err := extractTar(bytes.NewReader(archive.Bytes()), filepath.Join(dir, "out")); err == nil {
+		t.Errorf("extracted path outside of directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "evil.txt")); err == nil {
+		t.Errorf("file written outside of directory")
+	}
+}
+
+func TestKeysetRotate(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "keyset.json")
+	w := keyset.KeyWrapper(xaes256gcm.GenerateKey())
+	if err := rotateKeyset(name, w, 0, "", io.Discard); err != nil {
+		t.Fatal(err)
+	}
+	ks, err := readKeyset(name, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := ks.Primary()
+
+	data := filepath.Join(dir, "data")
+	if err := os.MkdirAll(filepath.Join(data, "sub"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []string{"a", filepath.Join("sub", "b")} {
+		ciphertext, err := ks.Seal(nil, []byte(f), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(data, f), ciphertext, 0640); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	log := &bytes.Buffer{}
+	if err := rotateKeyset(name, w, time.Hour, data, log); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := readKeyset(name, nil); err == nil {
+		t.Errorf("encrypted keyset parsed without KEK")
+	}
+	ks, err = readKeyset(name, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := ks.Primary()
+	if second.ID == first.ID || len(ks.Keys()) != 2 || second.Expires.IsZero() {
+		t.Fatalf("unexpected keys %+v", ks.Keys())
+	}
+	for _, f := range []string{"a", filepath.Join("sub", "b")} {
+		ciphertext, err := os.ReadFile(filepath.Join(data, f))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id, _ := keyset.KeyID(ciphertext); id != second.ID {
+			t.Errorf("%s not re-encrypted", f)
+		}
+		if got, err := ks.Open(nil, ciphertext, nil); err != nil || string(got) != f {
+			t.Errorf("%s: got %q, %v", f, got, err)
+		}
+		if fi, err := os.Stat(filepath.Join(data, f)); err != nil || fi.Mode().Perm() != 0640 {
+			t.Errorf("%s: mode not preserved: %v, %v", f, fi, err)
+		}
+	}
+	if n := strings.Count(log.String(), "re-encrypted"); n != 2 {
+		t.Errorf("%d files logged:\n%s", n, log)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	sealed := &bytes.Buffer{}
+	if err := seal(key, nil, bytes.NewReader(make([]byte, 1000)), sealed, true); err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := inspect(bytes.NewReader(sealed.Bytes()), out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"armored:         true", "metadata:        none",
+		"encrypted size:  1028 bytes", "plaintext size:  1000 bytes"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("missing %q in output:\n%s", line, out)
+		}
+	}
+
+	sealed.Reset()
+	w, err := stream.NewWriterWithMetadata(key, sealed, []byte("name=data.txt"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("hello"))
+	w.Close()
+	out.Reset()
+	if err := inspect(bytes.NewReader(sealed.Bytes()), out); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"armored:         false", `metadata:        public, "name=data.txt"`,
+		fmt.Sprintf("nonce prefix:    %x", sealed.Bytes()[:stream.HeaderSize])} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("missing %q in output:\n%s", line, out)
+		}
+	}
+}
+
+func TestEnvelopeRewrap(t *testing.T) {
+	oldKEK, newKEK := xaes256gcm.GenerateKey(), xaes256gcm.GenerateKey()
+	plaintext := bytes.Repeat([]byte("hello, xaes\n"), 10000)
+	sealed := &bytes.Buffer{}
+	if err := sealWithEnvelope(oldKEK.Bytes(), bytes.NewReader(plaintext), sealed, false); err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(t.TempDir(), "data.xaes")
+	if err := os.WriteFile(name, sealed.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rewrap(name, newKEK, newKEK); err == nil {
+		t.Errorf("rewrapped with the wrong KEK")
+	}
+	if err := rewrap(name, oldKEK, newKEK); err != nil {
+		t.Fatal(err)
+	}
+	rewrapped, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(rewrapped[envelopeHeaderSize:], sealed.Bytes()[envelopeHeaderSize:]) {
+		t.Errorf("encrypted data changed")
+	}
+	if err := open(oldKEK.Bytes(), bytes.NewReader(rewrapped), &bytes.Buffer{}); err == nil {
+		t.Errorf("opened with the old KEK")
+	}
+	opened := &bytes.Buffer{}
+	if err := open(newKEK.Bytes(), bytes.NewReader(rewrapped), opened); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened.Bytes(), plaintext) {
+		t.Errorf("got %q", opened.Bytes())
+	}
+
+	notEnvelope := filepath.Join(t.TempDir(), "stream.xaes")
+	sealed.Reset()
+	if err := seal(oldKEK.Bytes(), nil, bytes.NewReader(plaintext), sealed, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(notEnvelope, sealed.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := rewrap(notEnvelope, oldKEK, newKEK); err == nil {
+		t.Errorf("rewrapped a file that is not an envelope")
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Symlink(exe, filepath.Join(dir, plugin.Prefix+"test")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	t.Setenv("PATH", dir)
+
+	plaintext := bytes.Repeat([]byte("hello, xaes\n"), 10000)
+	sealed := &bytes.Buffer{}
+	if err := sealWithPlugin("test:kms key 1", bytes.NewReader(plaintext), sealed, true); err != nil {
+		t.Fatal(err)
+	}
+	opened := &bytes.Buffer{}
+	if err := open(nil, bytes.NewReader(sealed.Bytes()), opened); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(opened.Bytes(), plaintext) {
+		t.Errorf("got %q", opened.Bytes())
+	}
+
+	out := &bytes.Buffer{}
+	if err := inspect(bytes.NewReader(sealed.Bytes()), out); err != nil {
+		t.Fatal(err)
+	}
+	if line := `key:             data key wrapped by plugin "test", config "kms key 1"`; !strings.Contains(out.String(), line) {
+		t.Errorf("missing %q in output:\n%s", line, out)
+	}
+
+	if err := sealWithPlugin("missing", bytes.NewReader(plaintext), &bytes.Buffer{}, false); err == nil {
+		t.Errorf("sealed with a missing plugin")
+	}
+}
+
+func TestJSONValues(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	doc := `{"user": "admin", "password": "hunter2"}`
+	sealed := &bytes.Buffer{}
+	if err := sealValues(key, strings.NewReader(doc), sealed); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(sealed.String(), "hunter2") || !strings.Contains(sealed.String(), `"password": "xaes:`) {
+		t.Errorf("unexpected sealed document:\n%s", sealed)
+	}
+	opened := &bytes.Buffer{}
+	if err := openValues(key, bytes.NewReader(sealed.Bytes()), opened); err != nil {
+		t.Fatal(err)
+	}
+	expected := "{\n  \"user\": \"admin\",\n  \"password\": \"hunter2\"\n}\n"
+	if opened.String() != expected {
+		t.Errorf("got %q", opened)
+	}
+}
@@ -0,0 +1,63 @@
+package a
+
+import (
+	"bytes"
+	"crypto/rand"
+
+	"filippo.io/xaes256gcm"
+)
+
+func hardcoded(key []byte) {
+	xaes256gcm.New([]byte("0123456789abcdef0123456789abcdef"))       // want `hardcoded key passed to xaes256gcm.New`
+	xaes256gcm.NewKey(bytes.Repeat([]byte{1}, 32))                   // want `hardcoded key passed to xaes256gcm.NewKey`
+	xaes256gcm.NewWithManualNonces([]byte{1, 2, 3})                  // want `hardcoded key passed to xaes256gcm.NewWithManualNonces`
+	xaes256gcm.NewSealer([]byte("0123456789abcdef0123456789abcdef")) // want `hardcoded key passed to xaes256gcm.NewSealer`
+	xaes256gcm.NewOpener([]byte("0123456789abcdef0123456789abcdef")) // want `hardcoded key passed to xaes256gcm.NewOpener`
+	xaes256gcm.NewCipher([]byte("0123456789abcdef0123456789abcdef")) // want `hardcoded key passed to xaes256gcm.NewCipher`
+	xaes256gcm.New(key)
+	xaes256gcm.New(make([]byte, 32))
+
+	xaes256gcm.NewWithPurpose([]byte("0123456789abcdef0123456789abcdef"), "tokens") // want `hardcoded key passed to xaes256gcm.NewWithPurpose`
+	xaes256gcm.NewWithNodeNonces([]byte("0123456789abcdef0123456789abcdef"), 1, 2)  // want `hardcoded key passed to xaes256gcm.NewWithNodeNonces`
+}
+
+func fixedNonce(key []byte) {
+	a, _ := xaes256gcm.NewWithManualNonces(key)
+	a.Seal(nil, []byte("ABCDEFGHIJKLMNOPQRSTUVWX"), nil, nil) // want `constant nonce passed to Seal`
+	nonce := make([]byte, 24)
+	rand.Read(nonce)
+	a.Seal(nil, nonce, nil, nil)
+}
+
+func ignoredOpen(key []byte, ciphertext []byte) []byte {
+	a, _ := xaes256gcm.New(key)
+	plaintext, _ := a.Open(nil, nil, ciphertext, nil) // want `error returned by Open is ignored`
+	a.Open(nil, nil, ciphertext, nil)                 // want `error returned by Open is ignored`
+	if p, err := a.Open(nil, nil, ciphertext, nil); err == nil {
+		return p
+	}
+	return plaintext
+}
+
+func autoNonce(key []byte, nonce, ciphertext []byte) {
+	a, _ := xaes256gcm.New(key)
+	a.Seal(nil, nonce, nil, nil)        // want `non-empty nonce passed to Seal of an AEAD with automatic nonces`
+	a.Open(nil, nonce, ciphertext, nil) // want `non-empty nonce passed to Open` `error returned by Open is ignored`
+	a.Seal(nil, nil, nil, nil)
+	a.Seal(nil, []byte{}, nil, nil)
+	k, _ := xaes256gcm.NewKey(key)
+	b := k.AEAD()
+	b.Seal(nil, nonce, nil, nil) // want `non-empty nonce passed to Seal`
+	m, _ := xaes256gcm.NewWithManualNonces(key)
+	m.Seal(nil, nonce, nil, nil)
+	s, _ := xaes256gcm.NewSealer(key)
+	s.Seal(nil, nonce, nil, nil) // want `non-empty nonce passed to Seal`
+	o, _ := xaes256gcm.NewOpener(key)
+	if _, err := o.Open(nil, nonce, ciphertext, nil); err != nil { // want `non-empty nonce passed to Open`
+		return
+	}
+	p, _ := xaes256gcm.NewWithPurpose(key, "tokens")
+	p.Seal(nil, nonce, nil, nil) // want `non-empty nonce passed to Seal`
+	n, _ := xaes256gcm.NewWithNodeNonces(key, 1, 2)
+	n.Seal(nil, nonce, nil, nil) // want `non-empty nonce passed to Seal`
+}
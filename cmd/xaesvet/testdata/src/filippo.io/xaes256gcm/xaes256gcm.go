@@ -0,0 +1,33 @@
+package xaes256gcm
+
+import "crypto/cipher"
+
+type Key struct{}
+
+type Cipher struct{}
+
+type Sealer interface {
+	NonceSize() int
+	Overhead() int
+	Seal(dst, nonce, plaintext, additionalData []byte) []byte
+}
+
+type Opener interface {
+	NonceSize() int
+	Overhead() int
+	Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error)
+}
+
+func New(key []byte) (cipher.AEAD, error)                 { return nil, nil }
+func MustNew(key []byte) cipher.AEAD                      { return nil }
+func NewWithManualNonces(key []byte) (cipher.AEAD, error) { return nil, nil }
+func NewKey(key []byte) (Key, error)                      { return Key{}, nil }
+func NewSealer(key []byte) (Sealer, error)                { return nil, nil }
+func NewOpener(key []byte) (Opener, error)                { return nil, nil }
+func NewCipher(key []byte) (Cipher, error)                { return Cipher{}, nil }
+func (Key) AEAD() cipher.AEAD                             { return nil }
+
+func NewWithPurpose(key []byte, purpose string) (cipher.AEAD, error) { return nil, nil }
+func NewWithNodeNonces(key []byte, nodeID uint64, nodeIDSize int) (cipher.AEAD, error) {
+	return nil, nil
+}
@@ -0,0 +1,260 @@
+// Command xaesvet reports common misuses of filippo.io/xaes256gcm.
+//
+// Usage:
+//
+//	xaesvet [-flag] [package]
+//
+// It flags
+//
+//   - keys hardcoded as literals, passed to the constructors of this package;
+//   - constant nonces passed to Seal, which are reused for every message;
+//   - calls to Open whose error is ignored;
+//   - non-empty nonces passed to Seal or Open of AEADs with automatic nonces,
+//     such as those returned by xaes256gcm.New, which panic or always fail.
+//
+// The analysis is local to each function and uses simple heuristics, so it
+// might miss some misuses, but it should rarely report correct code.
+package main
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/analysis/singlechecker"
+	"golang.org/x/tools/go/ast/inspector"
+)
+
+const pkgPath = "filippo.io/xaes256gcm"
+
+// keyConstructors are the functions of pkgPath that take a key as their first
+// argument.
+var keyConstructors = map[string]bool{
+	"New": true, "NewWithManualNonces": true, "NewWithTagSize": true,
+	"NewWithRand": true, "NewWithTimestampNonces": true, "NewWithCounterNonces": true,
+	"NewWithShardedCounterNonces": true, "NewWithOptionalNonces": true,
+	"NewWithNonceFunc": true, "NewCodec": true, "MustNew": true,
+	"NewKey": true, "MustKey": true, "NewSealer": true, "NewOpener": true,
+	"NewCipher": true, "NewWithPurpose": true, "NewWithNodeNonces": true,
+}
+
+// autoNonceConstructors return AEADs with automatic nonces.
+var autoNonceConstructors = map[string]bool{
+	"New": true, "NewWithRand": true, "NewWithTimestampNonces": true,
+	"NewWithCounterNonces": true, "NewWithShardedCounterNonces": true,
+	"NewWithNonceFunc": true, "MustNew": true, "NewSealer": true, "NewOpener": true,
+	"NewWithPurpose": true, "NewWithNodeNonces": true,
+}
+
+var Analyzer = &analysis.Analyzer{
+	Name:     "xaesvet",
+	Doc:      "report common misuses of filippo.io/xaes256gcm",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func main() {
+	singlechecker.Main(Analyzer)
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+
+	// Find variables assigned the result of an automatic nonce constructor,
+	// or of Key.AEAD.
+	auto := make(map[types.Object]bool)
+	ins.Preorder([]ast.Node{(*ast.AssignStmt)(nil), (*ast.ValueSpec)(nil)}, func(n ast.Node) {
+		var lhs []ast.Expr
+		var rhs []ast.Expr
+		switch n := n.(type) {
+		case *ast.AssignStmt:
+			lhs, rhs = n.Lhs, n.Rhs
+		case *ast.ValueSpec:
+			for _, name := range n.Names {
+				lhs = append(lhs, name)
+			}
+			rhs = n.Values
+		}
+		if len(rhs) != 1 || len(lhs) == 0 {
+			return
+		}
+		call, ok := unparen(rhs[0]).(*ast.CallExpr)
+		if !ok || !isAutoNonceCall(pass, call) {
+			return
+		}
+		if id, ok := lhs[0].(*ast.Ident); ok {
+			if obj := pass.TypesInfo.ObjectOf(id); obj != nil {
+				auto[obj] = true
+			}
+		}
+	})
+
+	ins.Preorder([]ast.Node{(*ast.CallExpr)(nil), (*ast.AssignStmt)(nil), (*ast.ExprStmt)(nil)}, func(n ast.Node) {
+		switch n := n.(type) {
+		case *ast.CallExpr:
+			checkCall(pass, n, auto)
+		case *ast.AssignStmt:
+			if len(n.Rhs) == 1 && len(n.Lhs) == 2 && isAEADCall(pass, n.Rhs[0], "Open") {
+				if id, ok := n.Lhs[1].(*ast.Ident); ok && id.Name == "_" {
+					pass.Reportf(n.Pos(), "error returned by Open is ignored: unauthenticated plaintext might be used")
+				}
+			}
+		case *ast.ExprStmt:
+			if isAEADCall(pass, n.X, "Open") {
+				pass.Reportf(n.Pos(), "error returned by Open is ignored")
+			}
+		}
+	})
+	return nil, nil
+}
+
+func checkCall(pass *analysis.Pass, call *ast.CallExpr, auto map[types.Object]bool) {
+	if fn := pkgFunc(pass, call); fn != "" && keyConstructors[fn] && len(call.Args) > 0 {
+		if isConstantBytes(pass, call.Args[0]) {
+			pass.Reportf(call.Args[0].Pos(), "hardcoded key passed to xaes256gcm.%s: load keys from a secret store or file", fn)
+		}
+	}
+
+	sel, ok := unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || (sel.Sel.Name != "Seal" && sel.Sel.Name != "Open") || len(call.Args) != 4 {
+		return
+	}
+	if !isAEADMethod(pass, sel) {
+		return
+	}
+	nonce := call.Args[1]
+	if id, ok := unparen(sel.X).(*ast.Ident); ok && auto[pass.TypesInfo.ObjectOf(id)] {
+		if !isNil(pass, nonce) {
+			pass.Reportf(nonce.Pos(), "non-empty nonce passed to %s of an AEAD with automatic nonces: pass nil", sel.Sel.Name)
+		}
+		return
+	}
+	if sel.Sel.Name == "Seal" && !isNil(pass, nonce) && isConstantBytes(pass, nonce) {
+		pass.Reportf(nonce.Pos(), "constant nonce passed to Seal: nonces must never be reused, use xaes256gcm.New for random nonces")
+	}
+}
+
+// pkgFunc returns the name of the pkgPath function called by call, if any.
+func pkgFunc(pass *analysis.Pass, call *ast.CallExpr) string {
+	sel, ok := unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok {
+		return ""
+	}
+	fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != pkgPath {
+		return ""
+	}
+	if fn.Type().(*types.Signature).Recv() != nil {
+		return ""
+	}
+	return fn.Name()
+}
+
+func isAutoNonceCall(pass *analysis.Pass, call *ast.CallExpr) bool {
+	if fn := pkgFunc(pass, call); fn != "" {
+		return autoNonceConstructors[fn]
+	}
+	// Key.AEAD also uses automatic nonces.
+	sel, ok := unparen(call.Fun).(*ast.SelectorExpr)
+	if !ok || sel.Sel.Name != "AEAD" {
+		return false
+	}
+	fn, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == pkgPath
+}
+
+// isAEADMethod reports whether sel selects a method of a value implementing
+// crypto/cipher.AEAD.
+func isAEADMethod(pass *analysis.Pass, sel *ast.SelectorExpr) bool {
+	t := pass.TypesInfo.TypeOf(sel.X)
+	if t == nil {
+		return false
+	}
+	obj, _, _ := types.LookupFieldOrMethod(t, true, nil, "NonceSize")
+	if _, ok := obj.(*types.Func); !ok {
+		return false
+	}
+	obj, _, _ = types.LookupFieldOrMethod(t, true, nil, "Overhead")
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+func isAEADCall(pass *analysis.Pass, e ast.Expr, method string) bool {
+	call, ok := unparen(e).(*ast.CallExpr)
+	if !ok || len(call.Args) != 4 {
+		return false
+	}
+	sel, ok := unparen(call.Fun).(*ast.SelectorExpr)
+	return ok && sel.Sel.Name == method && isAEADMethod(pass, sel)
+}
+
+func isNil(pass *analysis.Pass, e ast.Expr) bool {
+	if tv, ok := pass.TypesInfo.Types[e]; ok && tv.IsNil() {
+		return true
+	}
+	if lit, ok := unparen(e).(*ast.CompositeLit); ok && len(lit.Elts) == 0 {
+		return true
+	}
+	return false
+}
+
+// isConstantBytes reports whether e is a byte slice or array with contents
+// fixed at compile time: a conversion of a constant string, or a composite
+// literal of constants.
+func isConstantBytes(pass *analysis.Pass, e ast.Expr) bool {
+	e = unparen(e)
+	if s, ok := e.(*ast.SliceExpr); ok {
+		return isConstantBytes(pass, s.X)
+	}
+	switch e := e.(type) {
+	case *ast.CallExpr:
+		// []byte("...") or bytes.Repeat([]byte{...}, n)
+		if tv, ok := pass.TypesInfo.Types[e.Fun]; ok && tv.IsType() && len(e.Args) == 1 {
+			arg := pass.TypesInfo.Types[e.Args[0]]
+			return arg.Value != nil && arg.Value.Kind() == constant.String
+		}
+		if fn, ok := typeutilCallee(pass, e).(*types.Func); ok && fn.Pkg() != nil &&
+			fn.Pkg().Path() == "bytes" && fn.Name() == "Repeat" && len(e.Args) == 2 {
+			return isConstantBytes(pass, e.Args[0])
+		}
+	case *ast.CompositeLit:
+		if len(e.Elts) == 0 {
+			return false
+		}
+		for _, elt := range e.Elts {
+			if kv, ok := elt.(*ast.KeyValueExpr); ok {
+				elt = kv.Value
+			}
+			if pass.TypesInfo.Types[elt].Value == nil {
+				return false
+			}
+		}
+		return true
+	case *ast.BasicLit:
+		return e.Kind == token.STRING
+	}
+	return false
+}
+
+func typeutilCallee(pass *analysis.Pass, call *ast.CallExpr) types.Object {
+	switch fun := unparen(call.Fun).(type) {
+	case *ast.Ident:
+		return pass.TypesInfo.Uses[fun]
+	case *ast.SelectorExpr:
+		return pass.TypesInfo.Uses[fun.Sel]
+	}
+	return nil
+}
+
+func unparen(e ast.Expr) ast.Expr {
+	for {
+		p, ok := e.(*ast.ParenExpr)
+		if !ok {
+			return e
+		}
+		e = p.X
+	}
+}
@@ -0,0 +1,104 @@
+package xaes256gcm
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+)
+
+// Codec is an XAES-256-GCM instance that expects 24-byte nonces, like the one
+// returned by [NewWithManualNonces], but that keeps scratch space and the last
+// derived AES-256-GCM key across calls.
+//
+// Consecutive messages that share the first 12 bytes of the nonce, like the
+// chunks of a stream, reuse the derived key instead of repeating the key
+// derivation and the AES and GCM key setup.
+//
+// Unlike the other AEADs returned by this package, a Codec is not safe for
+// concurrent use. It's meant for tight single-threaded loops; use one Codec
+// per goroutine.
+type Codec struct {
+	x      *xaes256gcm
+	k      [2 * aes.BlockSize]byte
+	prefix [12]byte
+	gcm    cipher.AEAD // for prefix, or nil
+}
+
+// NewCodec returns a new Codec. key must be exactly 32 bytes long.
+func NewCodec(key []byte) (*Codec, error) {
+	x, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{x: x.(*xaes256gcm)}, nil
+}
+
+func (*Codec) NonceSize() int {
+	return NonceSize
+}
+
+func (*Codec) Overhead() int {
+	return OverheadWithManualNonces
+}
+
+func (c *Codec) aead(nonce []byte) cipher.AEAD {
+	if c.gcm != nil && bytes.Equal(c.prefix[:], nonce[:12]) {
+		return c.gcm
+	}
+	b, _ := aes.NewCipher(c.x.deriveKeyInto(c.k[:], nonce[:12]))
+	c.gcm, _ = cipher.NewGCM(b)
+	copy(c.prefix[:], nonce[:12])
+	return c.gcm
+}
+
+func (c *Codec) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != NonceSize {
+		panic("xaes256gcm: bad nonce length")
+	}
+	return c.aead(nonce).Seal(dst, nonce[12:], plaintext, additionalData)
+}
+
+func (c *Codec) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		return nil, errors.New("xaes256gcm: bad nonce length")
+	}
+	return c.aead(nonce).Open(dst, nonce[12:], ciphertext, additionalData)
+}
+
+// OpenInPlace decrypts and authenticates buf, which holds a ciphertext
+// produced with nonce, overwriting it with the plaintext. It returns the
+// plaintext, which is a prefix of buf.
+//
+// OpenInPlace doesn't allocate if the first 12 bytes of nonce match those of
+// the previous call. If authentication fails, the contents of buf are
+// undefined and should be discarded.
+func (c *Codec) OpenInPlace(buf, nonce, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		return nil, errors.New("xaes256gcm: bad nonce length")
+	}
+	if len(buf) < OverheadWithManualNonces {
+		return nil, errOpen
+	}
+	return c.aead(nonce).Open(buf[:0], nonce[12:], buf, additionalData)
+}
+
+// SealInPlace encrypts and authenticates the plaintext in buf, overwriting it
+// with the ciphertext and appending the tag within the capacity of buf. It
+// returns the ciphertext, which is buf extended by [OverheadWithManualNonces]
+// bytes.
+//
+// It returns an error, without modifying buf, if cap(buf) is less than
+// len(buf) + OverheadWithManualNonces. Like [Codec.OpenInPlace], it doesn't
+// allocate if the first 12 bytes of nonce match those of the previous call.
+func (c *Codec) SealInPlace(buf, nonce, additionalData []byte) ([]byte, error) {
+	if len(nonce) != NonceSize {
+		return nil, errors.New("xaes256gcm: bad nonce length")
+	}
+	if cap(buf)-len(buf) < OverheadWithManualNonces {
+		return nil, fmt.Errorf("xaes256gcm: buffer capacity is %d bytes, need %d for the tag",
+			cap(buf), len(buf)+OverheadWithManualNonces)
+	}
+	return c.aead(nonce).Seal(buf[:0], nonce[12:], buf, additionalData), nil
+}
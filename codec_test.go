@@ -0,0 +1,104 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestCodec(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, err := xaes256gcm.NewCodec(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonces := [][]byte{
+		[]byte("ABCDEFGHIJKLMNOPQRSTUVWX"),
+		[]byte("ABCDEFGHIJKLmnopqrstuvwx"),
+		[]byte("abcdefghijklMNOPQRSTUVWX"),
+		[]byte("ABCDEFGHIJKLMNOPQRSTUVWX"),
+	}
+	for _, nonce := range nonces {
+		ciphertext := c.Seal(nil, nonce, []byte("XAES-256-GCM"), []byte("aad"))
+		if expected := m.Seal(nil, nonce, []byte("XAES-256-GCM"), []byte("aad")); !bytes.Equal(ciphertext, expected) {
+			t.Errorf("nonce %s: got %x, expected %x", nonce, ciphertext, expected)
+		}
+		if plaintext, err := c.Open(nil, nonce, ciphertext, []byte("aad")); err != nil {
+			t.Fatal(err)
+		} else if string(plaintext) != "XAES-256-GCM" {
+			t.Errorf("unexpected plaintext %q", plaintext)
+		}
+		if _, err := c.Open(nil, []byte("ABCDEFGHIJKLMNOPQRSTUVWZ"), ciphertext, []byte("aad")); err == nil {
+			t.Errorf("opened with the wrong nonce")
+		}
+	}
+}
+
+func TestCodecInPlace(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, err := xaes256gcm.NewCodec(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := []byte("ABCDEFGHIJKLMNOPQRSTUVWX")
+	buf := c.Seal(nil, nonce, []byte("XAES-256-GCM"), []byte("aad"))
+	plaintext, err := c.OpenInPlace(buf, nonce, []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(plaintext) != "XAES-256-GCM" || &plaintext[0] != &buf[0] {
+		t.Errorf("unexpected plaintext %q", plaintext)
+	}
+	if _, err := c.OpenInPlace(buf[:xaes256gcm.OverheadWithManualNonces-1], nonce, nil); err == nil {
+		t.Errorf("opened short ciphertext")
+	}
+
+	buf = append(make([]byte, 0, 12+xaes256gcm.OverheadWithManualNonces), "XAES-256-GCM"...)
+	sealed, err := c.SealInPlace(buf, nonce, []byte("aad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := c.Seal(nil, nonce, []byte("XAES-256-GCM"), []byte("aad")); !bytes.Equal(sealed, expected) || &sealed[0] != &buf[0] {
+		t.Errorf("got %x, expected %x", sealed, expected)
+	}
+	short := append(make([]byte, 0, 12+xaes256gcm.OverheadWithManualNonces-1), "XAES-256-GCM"...)
+	if _, err := c.SealInPlace(short, nonce, nil); err == nil {
+		t.Errorf("sealed with insufficient capacity")
+	} else if string(short) != "XAES-256-GCM" {
+		t.Errorf("buffer modified on error")
+	}
+
+	packet := make([]byte, 1024, 1024+xaes256gcm.OverheadWithManualNonces)
+	if allocs := testing.AllocsPerRun(100, func() {
+		sealed, err := c.SealInPlace(packet[:1024], nonce, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := c.OpenInPlace(sealed, nonce, nil); err != nil {
+			t.Fatal(err)
+		}
+	}); allocs != 0 {
+		t.Errorf("SealInPlace and OpenInPlace allocated %v times", allocs)
+	}
+}
+
+func BenchmarkCodec(b *testing.B) {
+	c, err := xaes256gcm.NewCodec(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		b.Fatal(err)
+	}
+	nonce := make([]byte, xaes256gcm.NonceSize)
+	buf := make([]byte, 0, 1024+xaes256gcm.OverheadWithManualNonces)
+	plaintext := make([]byte, 1024)
+	b.SetBytes(int64(len(plaintext)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		nonce[len(nonce)-1]++
+		c.Seal(buf[:0], nonce, plaintext, nil)
+	}
+}
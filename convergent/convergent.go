@@ -0,0 +1,86 @@
+// Package convergent implements convergent encryption with XAES-256-GCM, where
+// identical plaintexts encrypted with the same secret produce identical
+// ciphertexts, so that a backup system can deduplicate encrypted blobs across
+// its clients.
+//
+// Convergent encryption is deterministic and deliberately weaker than the
+// randomized encryption of [filippo.io/xaes256gcm.New]. Anyone holding the
+// secret can check whether a ciphertext encrypts a plaintext they can guess
+// (for example, a document with only a few unknown fields), and anyone who can
+// see the ciphertexts learns which blobs are equal. Use it only for blobs that
+// need to be deduplicated, and only share the secret between clients that are
+// allowed to learn that they store the same data.
+//
+// The 24-byte nonce is the truncated HMAC-SHA256 of the plaintext, keyed with
+// a key derived from the secret, so the message key that XAES-256-GCM derives
+// from the nonce is unique to the secret and the content. The ciphertext is
+// the nonce followed by the XAES-256-GCM ciphertext, like for New, and Open
+// checks that the nonce matches the decrypted plaintext.
+package convergent
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"filippo.io/xaes256gcm"
+	"golang.org/x/crypto/hkdf"
+)
+
+// Overhead is the difference between the lengths of a plaintext and its
+// ciphertext.
+const Overhead = xaes256gcm.Overhead
+
+// Encrypter seals blobs deterministically. It is safe for concurrent use.
+type Encrypter struct {
+	aead     cipher.AEAD
+	nonceKey []byte
+}
+
+// New returns an Encrypter for secret, which must be shared by all the clients
+// whose blobs are deduplicated against each other.
+func New(secret xaes256gcm.Key) *Encrypter {
+	nonceKey := make([]byte, 32)
+	r := hkdf.New(sha256.New, secret.Bytes(), nil, []byte("filippo.io/xaes256gcm/convergent nonce key"))
+	if _, err := io.ReadFull(r, nonceKey); err != nil {
+		panic("convergent: internal error: " + err.Error())
+	}
+	aead, err := xaes256gcm.NewWithManualNonces(secret.Bytes())
+	if err != nil {
+		panic("convergent: internal error: " + err.Error())
+	}
+	return &Encrypter{aead: aead, nonceKey: nonceKey}
+}
+
+func (e *Encrypter) nonce(plaintext []byte) []byte {
+	h := hmac.New(sha256.New, e.nonceKey)
+	h.Write(plaintext)
+	return h.Sum(nil)[:xaes256gcm.NonceSize]
+}
+
+// Seal encrypts plaintext, and appends the result to dst. The ciphertext only
+// depends on the secret and plaintext.
+func (e *Encrypter) Seal(dst, plaintext []byte) []byte {
+	nonce := e.nonce(plaintext)
+	dst = append(dst, nonce...)
+	return e.aead.Seal(dst, nonce, plaintext, nil)
+}
+
+// Open decrypts a ciphertext produced by Seal, and appends the plaintext to
+// dst.
+func (e *Encrypter) Open(dst, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < Overhead {
+		return nil, errors.New("convergent: ciphertext too short")
+	}
+	nonce := ciphertext[:xaes256gcm.NonceSize]
+	out, err := e.aead.Open(dst, nonce, ciphertext[xaes256gcm.NonceSize:], nil)
+	if err != nil {
+		return nil, errors.New("convergent: failed to decrypt blob")
+	}
+	if !hmac.Equal(e.nonce(out[len(dst):]), nonce) {
+		return nil, errors.New("convergent: blob was not encrypted convergently")
+	}
+	return out, nil
+}
@@ -0,0 +1,54 @@
+package convergent_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/convergent"
+)
+
+func TestConvergent(t *testing.T) {
+	secret := xaes256gcm.GenerateKey()
+	a, b := convergent.New(secret), convergent.New(secret)
+	blob := bytes.Repeat([]byte("backup"), 1000)
+
+	ca, cb := a.Seal(nil, blob), b.Seal(nil, blob)
+	if !bytes.Equal(ca, cb) {
+		t.Errorf("identical blobs produced different ciphertexts")
+	}
+	if len(ca) != len(blob)+convergent.Overhead {
+		t.Errorf("unexpected ciphertext length %d", len(ca))
+	}
+	if other := a.Seal(nil, blob[1:]); bytes.Equal(other[:xaes256gcm.NonceSize], ca[:xaes256gcm.NonceSize]) {
+		t.Errorf("different blobs have the same nonce")
+	}
+	if c := convergent.New(xaes256gcm.GenerateKey()).Seal(nil, blob); bytes.Equal(c, ca) {
+		t.Errorf("different secrets produced the same ciphertext")
+	}
+
+	out, err := b.Open([]byte("prefix"), ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, append([]byte("prefix"), blob...)) {
+		t.Errorf("Open didn't append the plaintext to dst")
+	}
+
+	// The ciphertext is compatible with xaes256gcm.New, but randomized
+	// ciphertexts are rejected since their nonce doesn't match the content.
+	if _, err := secret.AEAD().Open(nil, nil, ca, nil); err != nil {
+		t.Errorf("ciphertext not compatible with New: %v", err)
+	}
+	if _, err := a.Open(nil, secret.AEAD().Seal(nil, nil, blob, nil)); err == nil {
+		t.Errorf("randomized ciphertext opened")
+	}
+
+	ca[len(ca)-1] ^= 1
+	if _, err := a.Open(nil, ca); err == nil {
+		t.Errorf("corrupted ciphertext opened")
+	}
+	if _, err := a.Open(nil, ca[:convergent.Overhead-1]); err == nil {
+		t.Errorf("short ciphertext opened")
+	}
+}
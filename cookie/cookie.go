@@ -0,0 +1,95 @@
+// Package cookie implements encrypted and authenticated HTTP cookie values.
+//
+// A cookie value is the unpadded base64url encoding of the XAES-256-GCM
+// encryption (with a random nonce) of the creation time, as an 8-byte
+// big-endian Unix timestamp, followed by the value. The cookie name is
+// authenticated as additional data, so a value can't be moved to a cookie
+// with a different name.
+package cookie
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"filippo.io/xaes256gcm"
+)
+
+// DefaultMaxAge is the maximum age of cookie values accepted by
+// [Codec.DecodeCookie] if [Codec.MaxAge] is zero.
+const DefaultMaxAge = 30 * 24 * time.Hour
+
+// MaxLength is the maximum length of an encoded cookie value. Browsers are only
+// required to store cookies of up to 4096 bytes, including name and attributes.
+const MaxLength = 4000
+
+var b64 = base64.RawURLEncoding
+
+// Codec encodes and decodes cookie values. It is safe for concurrent use.
+type Codec struct {
+	// MaxAge is the maximum age of cookie values accepted by DecodeCookie.
+	// If zero, DefaultMaxAge is used.
+	MaxAge time.Duration
+
+	// Now, if not nil, is used instead of time.Now to compute creation times
+	// and check the age of cookie values.
+	Now func() time.Time
+
+	aead cipher.AEAD
+}
+
+// New returns a new Codec for the 32-byte key.
+func New(key []byte) (*Codec, error) {
+	aead, err := xaes256gcm.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Codec{aead: aead}, nil
+}
+
+func (c *Codec) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// EncodeCookie returns the encrypted value for a cookie named name, suitable
+// for use as [net/http.Cookie.Value].
+func (c *Codec) EncodeCookie(name string, value []byte) (string, error) {
+	plaintext := make([]byte, 8, 8+len(value))
+	binary.BigEndian.PutUint64(plaintext, uint64(c.now().Unix()))
+	plaintext = append(plaintext, value...)
+	encoded := b64.EncodeToString(c.aead.Seal(nil, nil, plaintext, []byte(name)))
+	if len(encoded) > MaxLength {
+		return "", errors.New("cookie: encoded value is too long")
+	}
+	return encoded, nil
+}
+
+// DecodeCookie decrypts the value of a cookie named name, and checks that it
+// is not older than the maximum age.
+func (c *Codec) DecodeCookie(name, encoded string) ([]byte, error) {
+	if len(encoded) > MaxLength {
+		return nil, errors.New("cookie: encoded value is too long")
+	}
+	ciphertext, err := b64.DecodeString(encoded)
+	if err != nil {
+		return nil, errors.New("cookie: invalid encoding")
+	}
+	plaintext, err := c.aead.Open(nil, nil, ciphertext, []byte(name))
+	if err != nil || len(plaintext) < 8 {
+		return nil, errors.New("cookie: invalid value")
+	}
+	maxAge := c.MaxAge
+	if maxAge == 0 {
+		maxAge = DefaultMaxAge
+	}
+	created := time.Unix(int64(binary.BigEndian.Uint64(plaintext)), 0)
+	if c.now().Sub(created) > maxAge {
+		return nil, errors.New("cookie: value expired")
+	}
+	return plaintext[8:], nil
+}
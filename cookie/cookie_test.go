@@ -0,0 +1,56 @@
+package cookie_test
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+	"time"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/cookie"
+)
+
+func TestCookie(t *testing.T) {
+	c, err := cookie.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := c.EncodeCookie("session", []byte("user=alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if (&http.Cookie{Name: "session", Value: v}).Valid() != nil {
+		t.Errorf("invalid cookie value %q", v)
+	}
+	if got, err := c.DecodeCookie("session", v); err != nil {
+		t.Fatal(err)
+	} else if string(got) != "user=alice" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := c.DecodeCookie("admin", v); err == nil {
+		t.Errorf("value decoded with a different name")
+	}
+
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.Now = func() time.Time { return now }
+	v, err = c.EncodeCookie("session", []byte("user=alice"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(cookie.DefaultMaxAge)
+	if _, err := c.DecodeCookie("session", v); err != nil {
+		t.Errorf("value rejected at the maximum age: %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, err := c.DecodeCookie("session", v); err == nil {
+		t.Errorf("expired value decoded")
+	}
+	c.MaxAge = 2 * cookie.DefaultMaxAge
+	if _, err := c.DecodeCookie("session", v); err != nil {
+		t.Errorf("value rejected with a longer MaxAge: %v", err)
+	}
+
+	if _, err := c.EncodeCookie("session", make([]byte, cookie.MaxLength)); err == nil {
+		t.Errorf("encoded a value longer than MaxLength")
+	}
+}
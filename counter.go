@@ -0,0 +1,125 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"sync"
+	"sync/atomic"
+)
+
+// counterPrefixSize is the size of the random prefix of nonces generated by
+// NewWithCounterNonces. The rest of the nonce is a big-endian counter.
+const counterPrefixSize = 16
+
+// NewWithCounterNonces is like [New], but each nonce is a random 16-byte
+// prefix, generated once when the AEAD is created, followed by an 8-byte
+// big-endian counter, incremented atomically for each message. key must be
+// exactly 32 bytes long.
+//
+// Nonces generated by the same AEAD never repeat, so the number of messages
+// is not limited by the probability of random collisions, while no state needs
+// to be persisted: a new AEAD, for example after a restart, uses a new random
+// prefix. It's meant for services that encrypt very large numbers of messages
+// with a single key. Note that ciphertexts reveal which were produced by the
+// same AEAD, and in which order.
+//
+// The ciphertexts are compatible with [New]. Returns an error if the random
+// prefix can't be generated.
+func NewWithCounterNonces(key []byte) (cipher.AEAD, error) {
+	x, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	c := &counterNonces{randomNonces: randomNonces{x.(*xaes256gcm), rand.Reader}}
+	if _, err := rand.Read(c.prefix[:]); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+type counterNonces struct {
+	randomNonces
+	prefix  [counterPrefixSize]byte
+	counter atomic.Uint64
+}
+
+func (c *counterNonces) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != 0 {
+		panic("xaes256gcm: non-empty nonce passed to Seal with automatic nonces")
+	}
+
+	n := c.counter.Add(1) - 1
+	if n == 1<<64-1 {
+		panic("xaes256gcm: nonce counter exhausted")
+	}
+	ret, out := sliceForAppend(dst, NonceSize)
+	copy(out, c.prefix[:])
+	binary.BigEndian.PutUint64(out[counterPrefixSize:], n)
+	return c.x.Seal(ret, out, plaintext, additionalData)
+}
+
+// NewWithShardedCounterNonces is like [NewWithCounterNonces], but avoids
+// contention between goroutines sealing concurrently, by partitioning the
+// nonce space into shards that are each used by one goroutine at a time. key
+// must be exactly 32 bytes long.
+//
+// Each nonce is a random 12-byte prefix, generated once when the AEAD is
+// created, followed by a 4-byte big-endian shard number and an 8-byte
+// big-endian counter. Shards are cached per processor with a [sync.Pool], so
+// most Seal calls don't synchronize at all. Shards dropped from the pool are
+// never reused, and Seal panics if more than 2³² shards are ever created,
+// which takes vastly more garbage collections than a process can run.
+//
+// The ciphertexts are compatible with [New].
+func NewWithShardedCounterNonces(key []byte) (cipher.AEAD, error) {
+	x, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	s := &shardedNonces{randomNonces: randomNonces{x.(*xaes256gcm), rand.Reader}}
+	if _, err := rand.Read(s.prefix[:]); err != nil {
+		return nil, err
+	}
+	s.pool.New = func() any {
+		n := s.shards.Add(1) - 1
+		if n > 1<<32-1 {
+			panic("xaes256gcm: nonce shards exhausted")
+		}
+		return &nonceShard{id: uint32(n)}
+	}
+	return s, nil
+}
+
+type shardedNonces struct {
+	randomNonces
+	prefix [12]byte
+	shards atomic.Uint64
+	pool   sync.Pool
+}
+
+type nonceShard struct {
+	id      uint32
+	counter uint64
+}
+
+func (s *shardedNonces) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != 0 {
+		panic("xaes256gcm: non-empty nonce passed to Seal with automatic nonces")
+	}
+
+	shard := s.pool.Get().(*nonceShard)
+	n := shard.counter
+	if n == 1<<64-1 {
+		// Drop the exhausted shard, and seal with a new one.
+		return s.Seal(dst, nil, plaintext, additionalData)
+	}
+	shard.counter++
+	s.pool.Put(shard)
+
+	ret, out := sliceForAppend(dst, NonceSize)
+	copy(out, s.prefix[:])
+	binary.BigEndian.PutUint32(out[12:], shard.id)
+	binary.BigEndian.PutUint64(out[16:], n)
+	return s.x.Seal(ret, out, plaintext, additionalData)
+}
@@ -0,0 +1,129 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/binary"
+	"sync"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestCounterNonces(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, err := xaes256gcm.NewWithCounterNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				ciphertext := c.Seal(nil, nil, []byte("hello"), nil)
+				if got, err := a.Open(nil, nil, ciphertext, nil); err != nil || string(got) != "hello" {
+					t.Errorf("Open: %q, %v", got, err)
+				}
+				mu.Lock()
+				seen[string(ciphertext[:xaes256gcm.NonceSize])] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != 800 {
+		t.Errorf("%d unique nonces, expected 800", len(seen))
+	}
+
+	first := c.Seal(nil, nil, nil, nil)
+	second := c.Seal(nil, nil, nil, nil)
+	if !bytes.Equal(first[:16], second[:16]) ||
+		binary.BigEndian.Uint64(second[16:24]) != binary.BigEndian.Uint64(first[16:24])+1 {
+		t.Errorf("unexpected nonces %x, %x", first[:24], second[:24])
+	}
+	other, err := xaes256gcm.NewWithCounterNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(other.Seal(nil, nil, nil, nil)[:16], first[:16]) {
+		t.Errorf("prefix reused across AEADs")
+	}
+}
+
+func TestShardedCounterNonces(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, err := xaes256gcm.NewWithShardedCounterNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				ciphertext := c.Seal(nil, nil, []byte("hello"), nil)
+				if i%100 == 0 {
+					if got, err := a.Open(nil, nil, ciphertext, nil); err != nil || string(got) != "hello" {
+						t.Errorf("Open: %q, %v", got, err)
+					}
+				}
+				mu.Lock()
+				if seen[string(ciphertext[:xaes256gcm.NonceSize])] {
+					t.Errorf("nonce reused: %x", ciphertext[:xaes256gcm.NonceSize])
+				}
+				seen[string(ciphertext[:xaes256gcm.NonceSize])] = true
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	first := c.Seal(nil, nil, nil, nil)
+	for nonce := range seen {
+		if nonce[:12] != string(first[:12]) {
+			t.Fatalf("prefix changed: %x, %x", nonce, first[:24])
+		}
+	}
+}
+
+func BenchmarkShardedCounterNonces(b *testing.B) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	for _, tc := range []struct {
+		name string
+		new  func([]byte) (cipher.AEAD, error)
+	}{
+		{"Counter", xaes256gcm.NewWithCounterNonces},
+		{"Sharded", xaes256gcm.NewWithShardedCounterNonces},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			c, err := tc.new(key)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.RunParallel(func(pb *testing.PB) {
+				buf := make([]byte, 0, 64+xaes256gcm.Overhead)
+				plaintext := make([]byte, 64)
+				for pb.Next() {
+					c.Seal(buf, nil, plaintext, nil)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// SealWithNonce encrypts and authenticates plaintext and additionalData with
+// aead, which must use automatic nonces prepended to the ciphertext, like the
+// AEADs returned by [New]. It appends the ciphertext, without the nonce, to dst
+// and returns it along with the generated nonce.
+//
+// It's meant for applications that store nonces and ciphertexts separately,
+// for example in different database columns, but still want nonces to be
+// generated safely. Use [OpenWithNonce] to decrypt the result.
+func SealWithNonce(aead cipher.AEAD, dst, plaintext, additionalData []byte) (ciphertext, nonce []byte) {
+	if aead.NonceSize() != 0 || aead.Overhead() < NonceSize {
+		panic("xaes256gcm: SealWithNonce requires an AEAD with automatic nonces")
+	}
+	out := aead.Seal(dst, nil, plaintext, additionalData)
+	nonce = make([]byte, NonceSize)
+	copy(nonce, out[len(dst):])
+	n := copy(out[len(dst):], out[len(dst)+NonceSize:])
+	return out[:len(dst)+n], nonce
+}
+
+// OpenWithNonce decrypts and authenticates a ciphertext and nonce returned by
+// [SealWithNonce] with aead, and appends the plaintext to dst.
+func OpenWithNonce(aead cipher.AEAD, dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if aead.NonceSize() != 0 || aead.Overhead() < NonceSize {
+		panic("xaes256gcm: OpenWithNonce requires an AEAD with automatic nonces")
+	}
+	if len(nonce) != NonceSize {
+		return nil, errOpen
+	}
+	joined := make([]byte, 0, len(nonce)+len(ciphertext))
+	joined = append(joined, nonce...)
+	joined = append(joined, ciphertext...)
+	return aead.Open(dst, nil, joined, additionalData)
+}
+
+// ExtractNonce returns the nonce of a ciphertext produced by the Seal method
+// of an AEAD with automatic nonces, like the AEADs returned by [New]. The
+// returned slice aliases ciphertext.
+//
+// The nonce is not authenticated until the ciphertext is opened, but it can be
+// used without the key, for example to detect nonce reuse or to index
+// ciphertexts.
+func ExtractNonce(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < Overhead {
+		return nil, errors.New("xaes256gcm: ciphertext too short")
+	}
+	return ciphertext[:NonceSize:NonceSize], nil
+}
@@ -0,0 +1,67 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestSealWithNonce(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := []byte("XAES-256-GCM")
+	prefix := []byte("prefix")
+	ciphertext, nonce := xaes256gcm.SealWithNonce(c, prefix, plaintext, []byte("ad"))
+	if !bytes.HasPrefix(ciphertext, prefix) {
+		t.Errorf("SealWithNonce didn't append to dst")
+	}
+	ciphertext = ciphertext[len(prefix):]
+	if len(nonce) != xaes256gcm.NonceSize || len(ciphertext) != len(plaintext)+xaes256gcm.OverheadWithManualNonces {
+		t.Fatalf("unexpected lengths %d, %d", len(nonce), len(ciphertext))
+	}
+
+	m, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := m.Open(nil, nonce, ciphertext, []byte("ad")); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Open with manual nonces: %q, %v", got, err)
+	}
+	if got, err := xaes256gcm.OpenWithNonce(c, nil, nonce, ciphertext, []byte("ad")); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("OpenWithNonce: %q, %v", got, err)
+	}
+	if _, err := xaes256gcm.OpenWithNonce(c, nil, nonce[1:], ciphertext, []byte("ad")); err == nil {
+		t.Errorf("OpenWithNonce succeeded with short nonce")
+	}
+	nonce[0] ^= 1
+	if _, err := xaes256gcm.OpenWithNonce(c, nil, nonce, ciphertext, []byte("ad")); err == nil {
+		t.Errorf("OpenWithNonce succeeded with modified nonce")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("SealWithNonce didn't panic with manual nonces")
+		}
+	}()
+	xaes256gcm.SealWithNonce(m, nil, plaintext, nil)
+}
+
+func TestExtractNonce(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := c.Seal(nil, nil, nil, nil)
+	nonce, err := xaes256gcm.ExtractNonce(ciphertext)
+	if err != nil || !bytes.Equal(nonce, ciphertext[:xaes256gcm.NonceSize]) {
+		t.Errorf("got %x, %v", nonce, err)
+	}
+	if _, err := xaes256gcm.ExtractNonce(ciphertext[:xaes256gcm.Overhead-1]); err == nil {
+		t.Errorf("accepted a short ciphertext")
+	}
+}
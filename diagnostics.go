@@ -0,0 +1,47 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+)
+
+// Errors returned by Open with an AEAD wrapped by [WithOpenDiagnostics]. They
+// can be matched with [errors.Is].
+var (
+	ErrCiphertextTooShort = errors.New("xaes256gcm: ciphertext shorter than the overhead")
+	ErrBadNonceLength     = errors.New("xaes256gcm: bad nonce length")
+	ErrAuthentication     = errors.New("xaes256gcm: message authentication failed")
+)
+
+// WithOpenDiagnostics returns an AEAD that wraps aead, and whose Open errors
+// distinguish malformed inputs from authentication failures, and include the
+// relevant lengths.
+//
+// By default, Open returns the same error for every failure, which is the
+// safest behavior. Diagnostics are meant for debugging integrations, and
+// should not be enabled in production, where error details might end up in
+// responses to attackers. They reveal only public information, such as
+// lengths, never plaintext or key material.
+func WithOpenDiagnostics(aead cipher.AEAD) cipher.AEAD {
+	return &diagnosticAEAD{aead}
+}
+
+type diagnosticAEAD struct {
+	cipher.AEAD
+}
+
+func (d *diagnosticAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != d.NonceSize() {
+		return nil, fmt.Errorf("%w: got %d bytes, expected %d", ErrBadNonceLength, len(nonce), d.NonceSize())
+	}
+	if len(ciphertext) < d.Overhead() {
+		return nil, fmt.Errorf("%w: got %d bytes, need at least %d", ErrCiphertextTooShort, len(ciphertext), d.Overhead())
+	}
+	out, err := d.AEAD.Open(dst, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, fmt.Errorf("%w (wrong key, nonce, or additional data, or corrupted ciphertext; %d bytes of ciphertext, %d bytes of additional data)",
+			ErrAuthentication, len(ciphertext), len(additionalData))
+	}
+	return out, nil
+}
@@ -0,0 +1,36 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestOpenDiagnostics(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c := xaes256gcm.WithOpenDiagnostics(xaes256gcm.MustNew(key))
+	ciphertext := c.Seal(nil, nil, []byte("hello"), []byte("aad"))
+	if got, err := c.Open(nil, nil, ciphertext, []byte("aad")); err != nil || string(got) != "hello" {
+		t.Fatalf("Open: %q, %v", got, err)
+	}
+	if _, err := c.Open(nil, nil, ciphertext[:10], []byte("aad")); !errors.Is(err, xaes256gcm.ErrCiphertextTooShort) {
+		t.Errorf("short ciphertext: %v", err)
+	}
+	if _, err := c.Open(nil, make([]byte, 24), ciphertext, []byte("aad")); !errors.Is(err, xaes256gcm.ErrBadNonceLength) {
+		t.Errorf("non-empty nonce: %v", err)
+	}
+	if _, err := c.Open(nil, nil, ciphertext, []byte("wrong")); !errors.Is(err, xaes256gcm.ErrAuthentication) {
+		t.Errorf("wrong additional data: %v", err)
+	}
+
+	m, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = xaes256gcm.WithOpenDiagnostics(m)
+	if _, err := m.Open(nil, make([]byte, 12), make([]byte, 16), nil); !errors.Is(err, xaes256gcm.ErrBadNonceLength) {
+		t.Errorf("short nonce: %v", err)
+	}
+}
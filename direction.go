@@ -0,0 +1,66 @@
+package xaes256gcm
+
+import "crypto/cipher"
+
+// Sealer is the encryption half of [cipher.AEAD].
+type Sealer interface {
+	NonceSize() int
+	Overhead() int
+	Seal(dst, nonce, plaintext, additionalData []byte) []byte
+}
+
+// Opener is the decryption half of [cipher.AEAD].
+type Opener interface {
+	NonceSize() int
+	Overhead() int
+	Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// NewSealer is like [New], but returns a value that can only encrypt. It can
+// be handed to components that must not be able to decrypt.
+func NewSealer(key []byte) (Sealer, error) {
+	a, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	return SealOnly(a), nil
+}
+
+// NewOpener is like [New], but returns a value that can only decrypt.
+func NewOpener(key []byte) (Opener, error) {
+	a, err := New(key)
+	if err != nil {
+		return nil, err
+	}
+	return OpenOnly(a), nil
+}
+
+// SealOnly returns a Sealer that wraps aead. Unlike aead itself, the returned
+// value can't be converted back to an AEAD with a type assertion.
+func SealOnly(aead cipher.AEAD) Sealer {
+	return sealer{aead}
+}
+
+// OpenOnly returns an Opener that wraps aead. Unlike aead itself, the returned
+// value can't be converted back to an AEAD with a type assertion.
+func OpenOnly(aead cipher.AEAD) Opener {
+	return opener{aead}
+}
+
+type sealer struct{ a cipher.AEAD }
+
+func (s sealer) NonceSize() int { return s.a.NonceSize() }
+func (s sealer) Overhead() int  { return s.a.Overhead() }
+
+func (s sealer) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return s.a.Seal(dst, nonce, plaintext, additionalData)
+}
+
+type opener struct{ a cipher.AEAD }
+
+func (o opener) NonceSize() int { return o.a.NonceSize() }
+func (o opener) Overhead() int  { return o.a.Overhead() }
+
+func (o opener) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return o.a.Open(dst, nonce, ciphertext, additionalData)
+}
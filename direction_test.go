@@ -0,0 +1,42 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestSealerOpener(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	s, err := xaes256gcm.NewSealer(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := xaes256gcm.NewOpener(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.(cipher.AEAD); ok {
+		t.Errorf("Sealer is an AEAD")
+	}
+	if _, ok := o.(cipher.AEAD); ok {
+		t.Errorf("Opener is an AEAD")
+	}
+	if s.NonceSize() != 0 || s.Overhead() != xaes256gcm.Overhead ||
+		o.NonceSize() != 0 || o.Overhead() != xaes256gcm.Overhead {
+		t.Errorf("unexpected NonceSize or Overhead")
+	}
+
+	ciphertext := s.Seal(nil, nil, []byte("hello"), []byte("aad"))
+	if plaintext, err := o.Open(nil, nil, ciphertext, []byte("aad")); err != nil {
+		t.Fatal(err)
+	} else if string(plaintext) != "hello" {
+		t.Errorf("unexpected plaintext %q", plaintext)
+	}
+
+	if _, err := xaes256gcm.NewSealer(key[:16]); err == nil {
+		t.Errorf("short key accepted")
+	}
+}
@@ -0,0 +1,52 @@
+package xaes256gcm
+
+import "errors"
+
+// Magic is the string that starts ciphertexts in the canonical format produced
+// by [Marshal], so that they can be identified by file type detection tools.
+const Magic = "XAES"
+
+// FormatVersion is the version of the canonical format produced by [Marshal].
+const FormatVersion = 0x01
+
+// FormatOverhead is the difference between the size of a message in the
+// canonical format and the size of its plaintext.
+const FormatOverhead = len(Magic) + 1 + Overhead
+
+// Marshal encodes a ciphertext returned by the Seal method of an AEAD produced
+// by [New] in the canonical format.
+//
+// The canonical format is the four bytes "XAES", the version byte 0x01, the
+// 24-byte nonce, and the ciphertext with its 16-byte tag:
+//
+//	"XAES" || 0x01 || nonce || ciphertext || tag
+//
+// The header is not authenticated: a message can't be changed to a different
+// version, because each version defines its own layout, and parsers reject
+// versions they don't know.
+func Marshal(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < Overhead {
+		return nil, errors.New("xaes256gcm: ciphertext too short")
+	}
+	out := make([]byte, 0, len(Magic)+1+len(ciphertext))
+	out = append(out, Magic...)
+	out = append(out, FormatVersion)
+	return append(out, ciphertext...), nil
+}
+
+// Parse decodes a message in the canonical format produced by [Marshal], and
+// returns the ciphertext, which can be passed to the Open method of an AEAD
+// produced by [New]. The returned slice aliases data.
+func Parse(data []byte) ([]byte, error) {
+	if len(data) < len(Magic)+1 || string(data[:len(Magic)]) != Magic {
+		return nil, errors.New("xaes256gcm: not an XAES-256-GCM message")
+	}
+	if v := data[len(Magic)]; v != FormatVersion {
+		return nil, errors.New("xaes256gcm: unsupported message format version")
+	}
+	ciphertext := data[len(Magic)+1:]
+	if len(ciphertext) < Overhead {
+		return nil, errors.New("xaes256gcm: message too short")
+	}
+	return ciphertext, nil
+}
@@ -0,0 +1,49 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestFormat(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	m, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce := []byte("ABCDEFGHIJKLMNOPQRSTUVWX")
+	ciphertext := append([]byte(nonce), m.Seal(nil, nonce, []byte("XAES-256-GCM"), nil)...)
+	msg, err := xaes256gcm.Marshal(ciphertext)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "XAES\x01" + string(nonce) + string(ciphertext[len(nonce):])
+	if string(msg) != expected {
+		t.Errorf("got %x", msg)
+	}
+	if len(msg) != len("XAES-256-GCM")+xaes256gcm.FormatOverhead {
+		t.Errorf("unexpected length %d", len(msg))
+	}
+
+	parsed, err := xaes256gcm.Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := xaes256gcm.MustNew(key).Open(nil, nil, parsed, nil); err != nil {
+		t.Fatal(err)
+	} else if string(plaintext) != "XAES-256-GCM" {
+		t.Errorf("got %q", plaintext)
+	}
+
+	for _, bad := range []string{"", "XAES", "XAE\x01" + expected[4:], "XAES\x02" + expected[5:],
+		expected[:xaes256gcm.FormatOverhead-1]} {
+		if _, err := xaes256gcm.Parse([]byte(bad)); err == nil {
+			t.Errorf("parsed invalid message %q", bad)
+		}
+	}
+	if _, err := xaes256gcm.Marshal(ciphertext[:xaes256gcm.Overhead-1]); err == nil {
+		t.Errorf("marshaled short ciphertext")
+	}
+}
@@ -2,8 +2,16 @@ module filippo.io/xaes256gcm
 
 go 1.21
 
-// Testing dependencies.
 require (
-	golang.org/x/crypto v0.23.0
-	golang.org/x/sys v0.20.0 // indirect
+	filippo.io/age v1.2.1
+	filippo.io/edwards25519 v1.1.0
+	golang.org/x/crypto v0.24.0
+	golang.org/x/sys v0.23.0
+	golang.org/x/term v0.21.0
+	golang.org/x/tools v0.24.1
+)
+
+require (
+	golang.org/x/mod v0.20.0 // indirect
+	golang.org/x/sync v0.8.0 // indirect
 )
@@ -0,0 +1,40 @@
+package xaes256gcm
+
+import (
+	"errors"
+	"runtime"
+
+	"golang.org/x/sys/cpu"
+)
+
+// RequireHardwareAES returns an error if the platform lacks the hardware
+// support that the standard library needs for its constant-time AES and GCM
+// implementations, and would fall back to table-based software ones, which
+// might leak key material through timing side channels.
+//
+// It's meant to be called at startup by applications that would rather fail
+// than run on a timing-leaky implementation.
+func RequireHardwareAES() error {
+	if !hasHardwareAES() {
+		return errors.New("xaes256gcm: hardware AES and GCM support is not available on " + runtime.GOARCH)
+	}
+	return nil
+}
+
+// hasHardwareAES mirrors the conditions under which crypto/aes and
+// crypto/cipher use their assembly implementations.
+func hasHardwareAES() bool {
+	switch runtime.GOARCH {
+	case "amd64":
+		return cpu.X86.HasAES && cpu.X86.HasPCLMULQDQ && cpu.X86.HasSSE41 && cpu.X86.HasSSSE3
+	case "arm64":
+		return cpu.ARM64.HasAES && cpu.ARM64.HasPMULL
+	case "s390x":
+		return cpu.S390X.HasAES && cpu.S390X.HasAESCTR && cpu.S390X.HasGHASH
+	case "ppc64", "ppc64le":
+		// POWER8 and later, which are required by Go, support AES and GCM.
+		return true
+	default:
+		return false
+	}
+}
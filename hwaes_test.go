@@ -0,0 +1,16 @@
+package xaes256gcm_test
+
+import (
+	"runtime"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestRequireHardwareAES(t *testing.T) {
+	err := xaes256gcm.RequireHardwareAES()
+	t.Logf("RequireHardwareAES() = %v", err)
+	if runtime.GOARCH == "386" && err == nil {
+		t.Errorf("hardware AES reported on 386")
+	}
+}
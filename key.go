@@ -0,0 +1,108 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+)
+
+// Key is an XAES-256-GCM key. The zero value is not a valid key.
+//
+// Key doesn't expose the key material when formatted, for example in logs,
+// printing its fingerprint instead.
+type Key struct {
+	k [KeySize]byte
+}
+
+// NewKey returns a Key with a copy of key, which must be exactly 32 bytes
+// long.
+func NewKey(key []byte) (Key, error) {
+	if len(key) != KeySize {
+		return Key{}, errors.New("xaes256gcm: bad key length")
+	}
+	var k Key
+	copy(k.k[:], key)
+	return k, nil
+}
+
+// MustKey is like [NewKey], but panics if key is not 32 bytes long.
+func MustKey(key []byte) Key {
+	k, err := NewKey(key)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+// GenerateKey returns a new random Key.
+func GenerateKey() Key {
+	var k Key
+	if _, err := rand.Read(k.k[:]); err != nil {
+		panic("xaes256gcm: failed to generate random key: " + err.Error())
+	}
+	return k
+}
+
+// Bytes returns a copy of the key material.
+func (k Key) Bytes() []byte {
+	return append([]byte(nil), k.k[:]...)
+}
+
+// AEAD returns an XAES-256-GCM instance for the key, like [New].
+func (k Key) AEAD() cipher.AEAD {
+	a, err := New(k.k[:])
+	if err != nil {
+		panic("xaes256gcm: internal error: " + err.Error())
+	}
+	return a
+}
+
+// Equal reports whether k and other are the same key, in constant time.
+//
+// Keys are comparable, but == and bytes.Equal on the output of Bytes may leak
+// through timing how much of the keys match.
+func (k Key) Equal(other Key) bool {
+	return subtle.ConstantTimeCompare(k.k[:], other.k[:]) == 1
+}
+
+// Fingerprint returns a short identifier for the key, as 16 hexadecimal
+// characters. It's the truncated SHA-256 hash of a fixed label and the key,
+// so it doesn't reveal anything about the key, and can be used in logs,
+// headers, and configuration files to check which key is in use.
+//
+// Fingerprints are not long enough to be collision resistant against an
+// attacker that can choose keys, so they must not be used as a replacement for
+// authentication.
+func (k Key) Fingerprint() string {
+	h := sha256.New()
+	h.Write([]byte("filippo.io/xaes256gcm key fingerprint\x00"))
+	h.Write(k.k[:])
+	return hex.EncodeToString(h.Sum(nil)[:8])
+}
+
+// HasFingerprint reports whether fingerprint, as returned by [Key.Fingerprint],
+// matches k. The comparison is constant time.
+func (k Key) HasFingerprint(fingerprint string) bool {
+	return subtle.ConstantTimeCompare([]byte(k.Fingerprint()), []byte(fingerprint)) == 1
+}
+
+// String returns a representation of the key that includes its fingerprint,
+// but not the key material.
+func (k Key) String() string {
+	return "xaes256gcm.Key(" + k.Fingerprint() + ")"
+}
+
+// GoString is like String, so that %#v doesn't print the key material.
+func (k Key) GoString() string {
+	return k.String()
+}
+
+// LogValue implements [slog.LogValuer], logging the key's fingerprint instead
+// of the key material.
+func (k Key) LogValue() slog.Value {
+	return slog.GroupValue(slog.String("fingerprint", k.Fingerprint()))
+}
@@ -0,0 +1,81 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestKey(t *testing.T) {
+	raw := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	k, err := xaes256gcm.NewKey(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw[0] = 0x02
+	if b := k.Bytes(); !bytes.Equal(b, bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)) {
+		t.Errorf("Bytes() = %x", b)
+	}
+	if _, err := xaes256gcm.NewKey(raw[:16]); err == nil {
+		t.Errorf("short key accepted")
+	}
+
+	fp := k.Fingerprint()
+	if len(fp) != 16 || fp != k.Fingerprint() {
+		t.Errorf("Fingerprint() = %q", fp)
+	}
+	other, _ := xaes256gcm.NewKey(raw)
+	if other.Fingerprint() == fp {
+		t.Errorf("different keys have the same fingerprint")
+	}
+	if !k.HasFingerprint(fp) || other.HasFingerprint(fp) || k.HasFingerprint(fp[:8]) {
+		t.Errorf("HasFingerprint returned the wrong result")
+	}
+	if !k.Equal(xaes256gcm.MustKey(k.Bytes())) || k.Equal(other) {
+		t.Errorf("Equal returned the wrong result")
+	}
+	for _, format := range []string{"%v", "%s", "%#v", "%+v"} {
+		s := fmt.Sprintf(format, k)
+		if !strings.Contains(s, fp) || strings.Contains(s, "0101") || strings.Contains(s, "1 1 1") {
+			t.Errorf("%s: %q", format, s)
+		}
+	}
+
+	a := k.AEAD()
+	ciphertext := a.Seal(nil, nil, []byte("hello"), nil)
+	c, _ := xaes256gcm.New(k.Bytes())
+	if _, err := c.Open(nil, nil, ciphertext, nil); err != nil {
+		t.Errorf("AEAD() is not compatible with New: %v", err)
+	}
+
+	if g := xaes256gcm.GenerateKey(); g == xaes256gcm.GenerateKey() || g == (xaes256gcm.Key{}) {
+		t.Errorf("GenerateKey() is not random")
+	}
+}
+
+func TestMust(t *testing.T) {
+	raw := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	if k := xaes256gcm.MustKey(raw); !bytes.Equal(k.Bytes(), raw) {
+		t.Errorf("MustKey returned the wrong key")
+	}
+	a := xaes256gcm.MustNew(raw)
+	if _, err := a.Open(nil, nil, a.Seal(nil, nil, []byte("hello"), nil), nil); err != nil {
+		t.Error(err)
+	}
+	for name, f := range map[string]func(){
+		"MustKey": func() { xaes256gcm.MustKey(raw[:16]) },
+		"MustNew": func() { xaes256gcm.MustNew(raw[:16]) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s didn't panic on short key", name)
+				}
+			}()
+			f()
+		}()
+	}
+}
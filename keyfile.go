@@ -0,0 +1,96 @@
+package xaes256gcm
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// PEMType is the type of PEM blocks that encode a key, as accepted by
+// [LoadKeyFile].
+const PEMType = "XAES-256-GCM KEY"
+
+// LoadKeyFile reads a key from the file at path.
+//
+// The file can contain the 32 bytes of the key (unless they are all printable
+// ASCII characters), or the key encoded as hexadecimal, as standard or URL-safe
+// base64 (with or without padding), as returned by [Key.Bech32], or as a PEM
+// block of type [PEMType]. Leading and trailing whitespace is ignored in the
+// text encodings.
+//
+// On systems other than Windows, LoadKeyFile returns an error if the file is
+// readable by all users.
+func LoadKeyFile(path string) (Key, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Key{}, fmt.Errorf("xaes256gcm: failed to open key file: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return Key{}, fmt.Errorf("xaes256gcm: failed to open key file: %w", err)
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o004 != 0 {
+		return Key{}, fmt.Errorf("xaes256gcm: key file %q is world-readable (mode %v), run chmod o-r on it",
+			path, info.Mode().Perm())
+	}
+	// Valid key files are small, don't read arbitrarily large files.
+	data, err := io.ReadAll(io.LimitReader(f, 4096))
+	if err != nil {
+		return Key{}, fmt.Errorf("xaes256gcm: failed to read key file: %w", err)
+	}
+	key, err := parseKey(data)
+	if err != nil {
+		return Key{}, fmt.Errorf("xaes256gcm: key file %q: %w", path, err)
+	}
+	return key, nil
+}
+
+func parseKey(data []byte) (Key, error) {
+	// Random keys are practically never printable, but 32 characters of text
+	// are likely a truncated or wrongly encoded key.
+	if len(data) == KeySize && !isPrintable(data) {
+		return NewKey(data)
+	}
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != PEMType {
+			return Key{}, fmt.Errorf("unexpected PEM block type %q", block.Type)
+		}
+		if len(block.Bytes) != KeySize {
+			return Key{}, errors.New("PEM block has the wrong key length")
+		}
+		return NewKey(block.Bytes)
+	}
+	text := string(bytes.TrimSpace(data))
+	if strings.HasPrefix(strings.ToLower(text), Bech32Prefix+"1") {
+		return parseBech32Key(text)
+	}
+	if len(text) == hex.EncodedLen(KeySize) {
+		if key, err := hex.DecodeString(text); err == nil {
+			return NewKey(key)
+		}
+	}
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding,
+		base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := enc.Strict().DecodeString(text); err == nil && len(key) == KeySize {
+			return NewKey(key)
+		}
+	}
+	return Key{}, errors.New("unrecognized key encoding")
+}
+
+func isPrintable(b []byte) bool {
+	for _, c := range b {
+		if c < ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,67 @@
+package xaes256gcm_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestLoadKeyFile(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	raw := key.Bytes()
+	dir := t.TempDir()
+	for name, contents := range map[string]string{
+		"raw":        string(raw),
+		"hex":        hex.EncodeToString(raw) + "\n",
+		"base64":     base64.StdEncoding.EncodeToString(raw) + "\n",
+		"rawurl":     base64.RawURLEncoding.EncodeToString(raw),
+		"bech32":     key.Bech32() + "\n",
+		"pem":        string(pem.EncodeToMemory(&pem.Block{Type: xaes256gcm.PEMType, Bytes: raw})),
+		"whitespace": "  " + hex.EncodeToString(raw) + "\r\n\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		got, err := xaes256gcm.LoadKeyFile(path)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if got != key {
+			t.Errorf("%s: wrong key", name)
+		}
+	}
+
+	for name, contents := range map[string]string{
+		"short":   hex.EncodeToString(raw[:16]),
+		"pemtype": string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: raw})),
+		"garbage": "not a key",
+		"empty":   "",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(contents), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := xaes256gcm.LoadKeyFile(path); err == nil {
+			t.Errorf("%s: loaded invalid key file", name)
+		}
+	}
+
+	if runtime.GOOS != "windows" {
+		path := filepath.Join(dir, "world")
+		if err := os.WriteFile(path, []byte(hex.EncodeToString(raw)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := xaes256gcm.LoadKeyFile(path); err == nil {
+			t.Errorf("loaded world-readable key file")
+		}
+	}
+}
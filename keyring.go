@@ -0,0 +1,92 @@
+package xaes256gcm
+
+import (
+	"container/list"
+	"crypto/cipher"
+	"crypto/sha256"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Keyring derives a separate key for each tenant of a multi-tenant service
+// from a single master key, with HKDF-SHA256 and the tenant identifier as
+// info. It caches the AEADs of the most recently used tenants.
+//
+// Tenants are cryptographically isolated: a ciphertext encrypted for one
+// tenant doesn't decrypt for any other, and the derived keys of some tenants
+// don't reveal the keys of others or the master key.
+//
+// A Keyring is safe for concurrent use.
+type Keyring struct {
+	master Key
+	size   int
+
+	mu    sync.Mutex
+	lru   *list.List // of *keyringEntry, most recently used first
+	cache map[string]*list.Element
+}
+
+type keyringEntry struct {
+	tenant string
+	aead   cipher.AEAD
+}
+
+// NewKeyring returns a Keyring that derives tenant keys from master, and
+// caches the AEADs of up to size tenants. If size is less than one, AEADs are
+// not cached.
+func NewKeyring(master Key, size int) *Keyring {
+	return &Keyring{master: master, size: size,
+		lru: list.New(), cache: make(map[string]*list.Element)}
+}
+
+// Key returns the key of tenant.
+func (k *Keyring) Key(tenant string) Key {
+	var key Key
+	info := "filippo.io/xaes256gcm keyring\x00" + tenant
+	r := hkdf.New(sha256.New, k.master.k[:], nil, []byte(info))
+	if _, err := io.ReadFull(r, key.k[:]); err != nil {
+		panic("xaes256gcm: internal error: " + err.Error())
+	}
+	return key
+}
+
+// AEAD returns an XAES-256-GCM instance for the key of tenant, like
+// [Key.AEAD].
+func (k *Keyring) AEAD(tenant string) cipher.AEAD {
+	k.mu.Lock()
+	if e, ok := k.cache[tenant]; ok {
+		k.lru.MoveToFront(e)
+		k.mu.Unlock()
+		return e.Value.(*keyringEntry).aead
+	}
+	k.mu.Unlock()
+
+	// Derive the key without holding the lock. Concurrent misses for the same
+	// tenant might derive it twice, which is harmless.
+	aead := k.Key(tenant).AEAD()
+	if k.size < 1 {
+		return aead
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if e, ok := k.cache[tenant]; ok {
+		k.lru.MoveToFront(e)
+		return e.Value.(*keyringEntry).aead
+	}
+	k.cache[tenant] = k.lru.PushFront(&keyringEntry{tenant, aead})
+	if k.lru.Len() > k.size {
+		oldest := k.lru.Remove(k.lru.Back()).(*keyringEntry)
+		delete(k.cache, oldest.tenant)
+	}
+	return aead
+}
+
+// Len returns the number of tenants with a cached AEAD.
+func (k *Keyring) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.lru.Len()
+}
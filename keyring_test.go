@@ -0,0 +1,60 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestKeyring(t *testing.T) {
+	master := xaes256gcm.MustKey(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	k := xaes256gcm.NewKeyring(master, 2)
+
+	if k.Key("alice") != xaes256gcm.NewKeyring(master, 0).Key("alice") {
+		t.Errorf("derivation is not deterministic")
+	}
+	if k.Key("alice") == k.Key("bob") || k.Key("alice") == master {
+		t.Errorf("tenant keys are not separated")
+	}
+	if k.Key("a") == k.Key("a\x00") {
+		t.Errorf("tenant keys are not separated")
+	}
+
+	ciphertext := k.AEAD("alice").Seal(nil, nil, []byte("hello"), nil)
+	if _, err := k.AEAD("bob").Open(nil, nil, ciphertext, nil); err == nil {
+		t.Errorf("ciphertext opened with another tenant's key")
+	}
+	if got, err := k.Key("alice").AEAD().Open(nil, nil, ciphertext, nil); err != nil || string(got) != "hello" {
+		t.Errorf("Open: %q, %v", got, err)
+	}
+
+	k.AEAD("carol")
+	if k.Len() != 2 {
+		t.Errorf("Len = %d, expected 2", k.Len())
+	}
+	if k.AEAD("carol") != k.AEAD("carol") {
+		t.Errorf("AEAD not cached")
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				tenant := fmt.Sprint(i % (g + 1))
+				a := k.AEAD(tenant)
+				if _, err := a.Open(nil, nil, a.Seal(nil, nil, nil, nil), nil); err != nil {
+					t.Error(err)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	if k.Len() != 2 {
+		t.Errorf("Len = %d, expected 2", k.Len())
+	}
+}
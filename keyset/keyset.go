@@ -0,0 +1,265 @@
+// Package keyset implements sets of XAES-256-GCM keys with a primary key for
+// encryption, to support key rotation.
+//
+// Ciphertexts are prefixed by the 4-byte big-endian ID of the key that
+// produced them, so that Open can select the right key without trial
+// decryption. The ID is also prepended to the additional data.
+//
+// Each key has a creation time and an optional expiration time. Seal refuses to
+// use an expired primary key, while Open keeps accepting ciphertexts produced
+// by expired keys until they are removed from the set. This enforces rotation
+// schedules in code, while allowing data to be re-encrypted lazily.
+package keyset
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Overhead is the difference between the lengths of a plaintext and its
+// ciphertext.
+const Overhead = 4 + xaes256gcm.Overhead
+
+// ErrPrimaryExpired is returned by Seal if the primary key is expired.
+var ErrPrimaryExpired = errors.New("keyset: primary key is expired, rotate the keyset")
+
+// Keyset is a set of keys, one of which is the primary. It's safe for
+// concurrent use.
+type Keyset struct {
+	// Now, if not nil, is used instead of time.Now to check expiration times
+	// and to set creation times.
+	Now func() time.Time
+
+	// Logger, if not nil, receives records of changes to the keyset, refused
+	// Seal calls, and failed Open calls, with key IDs and fingerprints, but
+	// never key material.
+	Logger *slog.Logger
+
+	mu      sync.RWMutex
+	keys    map[uint32]*entry
+	primary uint32
+}
+
+type entry struct {
+	id      uint32
+	key     xaes256gcm.Key
+	aead    cipher.AEAD
+	created time.Time
+	expires time.Time
+}
+
+// KeyInfo describes a key in a Keyset, without the key material.
+type KeyInfo struct {
+	ID uint32
+	// Fingerprint is the fingerprint of the key, see
+	// [xaes256gcm.Key.Fingerprint].
+	Fingerprint string
+	Created     time.Time
+	// Expires is the expiration time of the key, or the zero Time if the key
+	// doesn't expire.
+	Expires time.Time
+	Primary bool
+}
+
+// Expired reports whether the key is expired at time t.
+func (k KeyInfo) Expired(t time.Time) bool {
+	return !k.Expires.IsZero() && !t.Before(k.Expires)
+}
+
+// New returns an empty Keyset. A primary key must be added with [Keyset.Add]
+// and [Keyset.SetPrimary] before it can be used with Seal.
+func New() *Keyset {
+	return &Keyset{keys: make(map[uint32]*entry)}
+}
+
+func (ks *Keyset) log(level slog.Level, msg string, attrs ...slog.Attr) {
+	if ks.Logger != nil {
+		ks.Logger.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+}
+
+func (ks *Keyset) now() time.Time {
+	if ks.Now != nil {
+		return ks.Now()
+	}
+	return time.Now()
+}
+
+// Add adds key to the set with a new random ID, and returns the ID. expires is
+// the expiration time of the key, or the zero Time if it doesn't expire.
+func (ks *Keyset) Add(key xaes256gcm.Key, expires time.Time) (uint32, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	for _, e := range ks.keys {
+		if e.key.Equal(key) {
+			return 0, fmt.Errorf("keyset: key is already in the set with ID %d", e.id)
+		}
+	}
+	var id uint32
+	for id == 0 || ks.keys[id] != nil {
+		var b [4]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return 0, err
+		}
+		id = binary.BigEndian.Uint32(b[:])
+	}
+	ks.keys[id] = newEntry(id, key, ks.now(), expires)
+	ks.log(slog.LevelInfo, "keyset: added key", slog.Any("id", id),
+		slog.String("fingerprint", key.Fingerprint()), slog.Time("expires", expires))
+	return id, nil
+}
+
+func newEntry(id uint32, key xaes256gcm.Key, created, expires time.Time) *entry {
+	return &entry{id: id, key: key, aead: key.AEAD(), created: created, expires: expires}
+}
+
+// Rotate generates a new key that expires after lifetime (or never, if lifetime
+// is zero), adds it to the set, and makes it the primary. The previous primary
+// stays in the set for decryption. It returns the ID of the new key.
+func (ks *Keyset) Rotate(lifetime time.Duration) (uint32, error) {
+	var expires time.Time
+	if lifetime != 0 {
+		expires = ks.now().Add(lifetime)
+	}
+	id, err := ks.Add(xaes256gcm.GenerateKey(), expires)
+	if err != nil {
+		return 0, err
+	}
+	return id, ks.SetPrimary(id)
+}
+
+// SetPrimary makes the key with the given ID the primary.
+func (ks *Keyset) SetPrimary(id uint32) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.keys[id] == nil {
+		return fmt.Errorf("keyset: no key with ID %d", id)
+	}
+	if ks.primary != id {
+		ks.log(slog.LevelInfo, "keyset: changed primary key",
+			slog.Any("previous", ks.primary), slog.Any("id", id))
+	}
+	ks.primary = id
+	return nil
+}
+
+// Remove removes the key with the given ID from the set. Ciphertexts produced
+// by it can't be decrypted anymore. The primary key can't be removed.
+func (ks *Keyset) Remove(id uint32) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if ks.keys[id] == nil {
+		return fmt.Errorf("keyset: no key with ID %d", id)
+	}
+	if id == ks.primary {
+		return errors.New("keyset: can't remove the primary key")
+	}
+	delete(ks.keys, id)
+	ks.log(slog.LevelInfo, "keyset: removed key", slog.Any("id", id))
+	return nil
+}
+
+// Keys returns information about the keys in the set, sorted by creation time.
+func (ks *Keyset) Keys() []KeyInfo {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return ks.keysLocked()
+}
+
+func (ks *Keyset) keysLocked() []KeyInfo {
+	infos := make([]KeyInfo, 0, len(ks.keys))
+	for _, e := range ks.keys {
+		infos = append(infos, ks.info(e))
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		if !infos[i].Created.Equal(infos[j].Created) {
+			return infos[i].Created.Before(infos[j].Created)
+		}
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// Primary returns information about the primary key, and false if there is no
+// primary key.
+func (ks *Keyset) Primary() (KeyInfo, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	e := ks.keys[ks.primary]
+	if e == nil {
+		return KeyInfo{}, false
+	}
+	return ks.info(e), true
+}
+
+func (ks *Keyset) info(e *entry) KeyInfo {
+	return KeyInfo{ID: e.id, Fingerprint: e.key.Fingerprint(), Created: e.created,
+		Expires: e.expires, Primary: e.id == ks.primary}
+}
+
+// Seal encrypts and authenticates plaintext and additionalData with the
+// primary key, and appends the result to dst. It returns [ErrPrimaryExpired]
+// if the primary key is expired.
+func (ks *Keyset) Seal(dst, plaintext, additionalData []byte) ([]byte, error) {
+	ks.mu.RLock()
+	e := ks.keys[ks.primary]
+	ks.mu.RUnlock()
+	if e == nil {
+		return nil, errors.New("keyset: no primary key")
+	}
+	if !e.expires.IsZero() && !ks.now().Before(e.expires) {
+		ks.log(slog.LevelError, "keyset: refused to seal with expired primary key",
+			slog.Any("id", e.id), slog.Time("expires", e.expires))
+		return nil, ErrPrimaryExpired
+	}
+	dst = binary.BigEndian.AppendUint32(dst, e.id)
+	return e.aead.Seal(dst, nil, plaintext, ad(e.id, additionalData)), nil
+}
+
+// Open decrypts and authenticates a ciphertext produced by Seal, with the key
+// identified by its prefix, and appends the plaintext to dst. Expired keys are
+// still used.
+func (ks *Keyset) Open(dst, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < Overhead {
+		return nil, errors.New("keyset: ciphertext too short")
+	}
+	id := binary.BigEndian.Uint32(ciphertext)
+	ks.mu.RLock()
+	e := ks.keys[id]
+	ks.mu.RUnlock()
+	if e == nil {
+		ks.log(slog.LevelWarn, "keyset: open failed, unknown key ID", slog.Any("id", id))
+		return nil, fmt.Errorf("keyset: unknown key ID %d", id)
+	}
+	out, err := e.aead.Open(dst, nil, ciphertext[4:], ad(id, additionalData))
+	if err != nil {
+		ks.log(slog.LevelWarn, "keyset: open failed", slog.Any("id", id),
+			slog.Int("ciphertext_length", len(ciphertext)))
+	}
+	return out, err
+}
+
+// KeyID returns the ID of the key that produced ciphertext, without
+// decrypting it.
+func KeyID(ciphertext []byte) (uint32, error) {
+	if len(ciphertext) < Overhead {
+		return 0, errors.New("keyset: ciphertext too short")
+	}
+	return binary.BigEndian.Uint32(ciphertext), nil
+}
+
+func ad(id uint32, additionalData []byte) []byte {
+	ad := make([]byte, 0, 4+len(additionalData))
+	ad = binary.BigEndian.AppendUint32(ad, id)
+	return append(ad, additionalData...)
+}
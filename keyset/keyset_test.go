@@ -0,0 +1,138 @@
+package keyset_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/keyset"
+)
+
+func TestRotation(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ks := keyset.New()
+	ks.Now = func() time.Time { return now }
+
+	if _, err := ks.Seal(nil, []byte("hello"), nil); err == nil {
+		t.Fatal("Seal succeeded without a primary key")
+	}
+	first, err := ks.Rotate(24 * time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old, err := ks.Seal(nil, []byte("hello"), []byte("ad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old) != len("hello")+keyset.Overhead {
+		t.Errorf("unexpected ciphertext length %d", len(old))
+	}
+	if id, err := keyset.KeyID(old); err != nil || id != first {
+		t.Errorf("KeyID = %d, %v, expected %d", id, err, first)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if _, err := ks.Seal(nil, []byte("hello"), nil); !errors.Is(err, keyset.ErrPrimaryExpired) {
+		t.Fatalf("Seal with expired primary: %v", err)
+	}
+	if got, err := ks.Open(nil, old, []byte("ad")); err != nil || string(got) != "hello" {
+		t.Fatalf("Open with expired key: %q, %v", got, err)
+	}
+
+	second, err := ks.Rotate(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := ks.Seal(nil, []byte("world"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := ks.Open(nil, ciphertext, nil); err != nil || string(got) != "world" {
+		t.Fatalf("Open: %q, %v", got, err)
+	}
+	if _, err := ks.Open(nil, old, []byte("wrong")); err == nil {
+		t.Error("Open succeeded with wrong additional data")
+	}
+
+	keys := ks.Keys()
+	if len(keys) != 2 || keys[0].ID != first || keys[1].ID != second ||
+		keys[0].Primary || !keys[1].Primary || !keys[0].Expired(now) || keys[1].Expired(now) {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+
+	if err := ks.Remove(second); err == nil {
+		t.Error("removed the primary key")
+	}
+	if err := ks.Remove(first); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Open(nil, old, []byte("ad")); err == nil {
+		t.Error("Open succeeded with removed key")
+	}
+}
+
+func TestAddDuplicate(t *testing.T) {
+	ks := keyset.New()
+	key := xaes256gcm.GenerateKey()
+	id, err := ks.Add(key, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Add(key, time.Time{}); err == nil {
+		t.Error("duplicate key added")
+	}
+	if err := ks.SetPrimary(id + 1); err == nil {
+		t.Error("unknown key set as primary")
+	}
+
+	// Ciphertexts swapped between key IDs don't open.
+	other, err := ks.Add(xaes256gcm.GenerateKey(), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.SetPrimary(id); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := ks.Seal(nil, []byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext[0], ciphertext[1], ciphertext[2], ciphertext[3] = byte(other>>24), byte(other>>16), byte(other>>8), byte(other)
+	if _, err := ks.Open(nil, ciphertext, nil); err == nil {
+		t.Error("ciphertext opened with a different key ID")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	buf := &bytes.Buffer{}
+	ks := keyset.New()
+	ks.Logger = slog.New(slog.NewTextHandler(buf, nil))
+	key := xaes256gcm.GenerateKey()
+	id, err := ks.Add(key, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.SetPrimary(id); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := ks.Seal(nil, []byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Open(nil, ciphertext, []byte("wrong")); err == nil {
+		t.Fatal("Open succeeded with wrong additional data")
+	}
+	for _, msg := range []string{"added key", "changed primary key", "open failed", key.Fingerprint()} {
+		if !strings.Contains(buf.String(), msg) {
+			t.Errorf("missing %q in log:\n%s", msg, buf)
+		}
+	}
+	if strings.Contains(buf.String(), hex.EncodeToString(key.Bytes())) {
+		t.Errorf("key material logged:\n%s", buf)
+	}
+}
@@ -0,0 +1,135 @@
+package keyset
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Wrapper encrypts and decrypts serialized keysets. It can be implemented by
+// a KMS client, or by [KeyWrapper] with a local key encryption key.
+type Wrapper interface {
+	// Wrap encrypts and authenticates plaintext and additionalData.
+	Wrap(plaintext, additionalData []byte) ([]byte, error)
+	// Unwrap decrypts and authenticates a ciphertext returned by Wrap.
+	Unwrap(ciphertext, additionalData []byte) ([]byte, error)
+}
+
+// KeyWrapper returns a [Wrapper] that encrypts keysets with XAES-256-GCM
+// under kek, with random nonces.
+func KeyWrapper(kek xaes256gcm.Key) Wrapper {
+	return keyWrapper{kek}
+}
+
+type keyWrapper struct{ kek xaes256gcm.Key }
+
+func (w keyWrapper) Wrap(plaintext, additionalData []byte) ([]byte, error) {
+	return w.kek.AEAD().Seal(nil, nil, plaintext, additionalData), nil
+}
+
+func (w keyWrapper) Unwrap(ciphertext, additionalData []byte) ([]byte, error) {
+	return w.kek.AEAD().Open(nil, nil, ciphertext, additionalData)
+}
+
+const wrapAD = "filippo.io/xaes256gcm/keyset encrypted keyset"
+
+type jsonKeyset struct {
+	Primary uint32    `json:"primary"`
+	Keys    []jsonKey `json:"keys"`
+}
+
+type jsonKey struct {
+	ID      uint32     `json:"id"`
+	Key     []byte     `json:"key"`
+	Created time.Time  `json:"created"`
+	Expires *time.Time `json:"expires,omitempty"`
+}
+
+type jsonEncryptedKeyset struct {
+	EncryptedKeyset []byte `json:"encryptedKeyset"`
+}
+
+// Marshal serializes the keyset, including the key material, as JSON.
+//
+// The result must be kept secret. Use [Keyset.Encrypt] to store keysets in
+// configuration stores or version control.
+func (ks *Keyset) Marshal() ([]byte, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	j := jsonKeyset{Primary: ks.primary, Keys: []jsonKey{}}
+	for _, info := range ks.keysLocked() {
+		e := ks.keys[info.ID]
+		k := jsonKey{ID: e.id, Key: e.key.Bytes(), Created: e.created}
+		if !e.expires.IsZero() {
+			expires := e.expires
+			k.Expires = &expires
+		}
+		j.Keys = append(j.Keys, k)
+	}
+	return json.MarshalIndent(j, "", "\t")
+}
+
+// Parse parses a keyset serialized by [Keyset.Marshal].
+func Parse(data []byte) (*Keyset, error) {
+	var j jsonKeyset
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.DisallowUnknownFields()
+	if err := d.Decode(&j); err != nil {
+		return nil, fmt.Errorf("keyset: invalid keyset: %w", err)
+	}
+	ks := New()
+	for _, k := range j.Keys {
+		if k.ID == 0 || ks.keys[k.ID] != nil {
+			return nil, fmt.Errorf("keyset: invalid or duplicate key ID %d", k.ID)
+		}
+		key, err := xaes256gcm.NewKey(k.Key)
+		if err != nil {
+			return nil, fmt.Errorf("keyset: key %d: %w", k.ID, err)
+		}
+		var expires time.Time
+		if k.Expires != nil {
+			expires = *k.Expires
+		}
+		ks.keys[k.ID] = newEntry(k.ID, key, k.Created, expires)
+	}
+	if j.Primary != 0 && ks.keys[j.Primary] == nil {
+		return nil, fmt.Errorf("keyset: primary key %d not in the keyset", j.Primary)
+	}
+	ks.primary = j.Primary
+	return ks, nil
+}
+
+// Encrypt serializes the keyset like [Keyset.Marshal], and encrypts it with w.
+// The result is a JSON document that can be stored in the clear.
+func (ks *Keyset) Encrypt(w Wrapper) ([]byte, error) {
+	plaintext, err := ks.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := w.Wrap(plaintext, []byte(wrapAD))
+	if err != nil {
+		return nil, fmt.Errorf("keyset: failed to encrypt keyset: %w", err)
+	}
+	return json.MarshalIndent(jsonEncryptedKeyset{ciphertext}, "", "\t")
+}
+
+// Decrypt decrypts a keyset encrypted by [Keyset.Encrypt] with w, and parses
+// it.
+func Decrypt(data []byte, w Wrapper) (*Keyset, error) {
+	var j jsonEncryptedKeyset
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("keyset: invalid encrypted keyset: %w", err)
+	}
+	if j.EncryptedKeyset == nil {
+		return nil, errors.New("keyset: not an encrypted keyset")
+	}
+	plaintext, err := w.Unwrap(j.EncryptedKeyset, []byte(wrapAD))
+	if err != nil {
+		return nil, fmt.Errorf("keyset: failed to decrypt keyset: %w", err)
+	}
+	return Parse(plaintext)
+}
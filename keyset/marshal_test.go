@@ -0,0 +1,74 @@
+package keyset_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/keyset"
+)
+
+func TestEncrypted(t *testing.T) {
+	ks := keyset.New()
+	ks.Now = func() time.Time { return time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC) }
+	if _, err := ks.Rotate(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := ks.Seal(nil, []byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ks.Rotate(0); err != nil {
+		t.Fatal(err)
+	}
+
+	kek := xaes256gcm.GenerateKey()
+	data, err := ks.Encrypt(keyset.KeyWrapper(kek))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, err := ks.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range ks.Keys() {
+		if bytes.Contains(data, []byte(info.Fingerprint)) {
+			t.Errorf("encrypted keyset leaks key information")
+		}
+	}
+	if _, err := keyset.Decrypt(data, keyset.KeyWrapper(xaes256gcm.GenerateKey())); err == nil {
+		t.Fatal("keyset decrypted with the wrong KEK")
+	}
+	if _, err := keyset.Decrypt(plaintext, keyset.KeyWrapper(kek)); err == nil {
+		t.Fatal("plaintext keyset accepted as encrypted")
+	}
+	if _, err := keyset.Parse(data); err == nil {
+		t.Fatal("encrypted keyset accepted as plaintext")
+	}
+
+	parsed, err := keyset.Decrypt(data, keyset.KeyWrapper(kek))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := parsed.Open(nil, ciphertext, nil); err != nil || string(got) != "hello" {
+		t.Fatalf("Open: %q, %v", got, err)
+	}
+	remarshaled, err := parsed.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(remarshaled, plaintext) {
+		t.Errorf("round-trip changed the keyset:\n%s\n%s", remarshaled, plaintext)
+	}
+
+	for _, invalid := range []string{
+		`{"primary": 1, "keys": []}`,
+		`{"primary": 0, "keys": [{"id": 0, "key": "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA="}]}`,
+		`{"primary": 0, "keys": [{"id": 1, "key": "AAAA"}]}`,
+	} {
+		if _, err := keyset.Parse([]byte(invalid)); err == nil {
+			t.Errorf("invalid keyset %s parsed", invalid)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package keyset
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Rotator rotates the primary key of a Keyset on a schedule, so that rotation
+// happens without operator action.
+type Rotator struct {
+	ks       *Keyset
+	interval time.Duration
+	retain   int
+	persist  func(*Keyset) error
+}
+
+// NewRotator returns a Rotator that replaces the primary key of ks once it's
+// older than interval, and keeps the retain most recent previous keys for
+// Open, removing older ones. ks must have a primary key, or get one on the
+// first rotation.
+//
+// If persist is not nil, it's called to save ks every time it changes. A new
+// key is persisted before it becomes the primary, so that no ciphertext is
+// ever produced with a key that was not saved. If persist fails, the new key
+// is discarded, and the rotation is retried later.
+func NewRotator(ks *Keyset, interval time.Duration, retain int, persist func(*Keyset) error) (*Rotator, error) {
+	if interval <= 0 {
+		return nil, errors.New("keyset: rotation interval must be positive")
+	}
+	if retain < 0 {
+		return nil, errors.New("keyset: number of retained keys must not be negative")
+	}
+	return &Rotator{ks: ks, interval: interval, retain: retain, persist: persist}, nil
+}
+
+// due returns the time of the next rotation.
+func (r *Rotator) due() time.Time {
+	primary, ok := r.ks.Primary()
+	if !ok {
+		return time.Time{}
+	}
+	return primary.Created.Add(r.interval)
+}
+
+// RotateIfDue rotates the keyset if the primary key is older than the
+// interval, or if there is no primary key, and reports whether it did.
+func (r *Rotator) RotateIfDue() (bool, error) {
+	if r.ks.now().Before(r.due()) {
+		return false, nil
+	}
+	return true, r.rotate()
+}
+
+func (r *Rotator) rotate() error {
+	id, err := r.ks.Add(xaes256gcm.GenerateKey(), time.Time{})
+	if err != nil {
+		return err
+	}
+	if err := r.save(); err != nil {
+		r.ks.Remove(id)
+		return err
+	}
+	if err := r.ks.SetPrimary(id); err != nil {
+		return err
+	}
+
+	var previous []KeyInfo
+	for _, k := range r.ks.Keys() {
+		if !k.Primary {
+			previous = append(previous, k)
+		}
+	}
+	sort.SliceStable(previous, func(i, j int) bool {
+		return previous[i].Created.After(previous[j].Created)
+	})
+	for _, k := range previous[min(r.retain, len(previous)):] {
+		if err := r.ks.Remove(k.ID); err != nil {
+			return err
+		}
+	}
+	return r.save()
+}
+
+func (r *Rotator) save() error {
+	if r.persist == nil {
+		return nil
+	}
+	if err := r.persist(r.ks); err != nil {
+		r.ks.log(slog.LevelError, "keyset: failed to persist keyset", slog.String("error", err.Error()))
+		return err
+	}
+	return nil
+}
+
+// Run rotates the keyset whenever it's due, until ctx is canceled, and then
+// returns ctx.Err(). Failed rotations are retried after a minute, or after the
+// interval if shorter, and are logged to the Logger of the keyset.
+func (r *Rotator) Run(ctx context.Context) error {
+	retry := min(r.interval, time.Minute)
+	for {
+		wait := r.due().Sub(r.ks.now())
+		if wait <= 0 {
+			if _, err := r.RotateIfDue(); err != nil {
+				wait = retry
+			} else {
+				continue
+			}
+		}
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
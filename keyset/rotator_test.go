@@ -0,0 +1,107 @@
+package keyset_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"filippo.io/xaes256gcm/keyset"
+)
+
+func TestRotator(t *testing.T) {
+	ks := keyset.New()
+	now := time.Unix(1700000000, 0)
+	ks.Now = func() time.Time { return now }
+	var persisted int
+	var persistErr error
+	r, err := keyset.NewRotator(ks, 24*time.Hour, 2, func(*keyset.Keyset) error {
+		persisted++
+		return persistErr
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first rotation creates a primary key.
+	if rotated, err := r.RotateIfDue(); err != nil || !rotated {
+		t.Fatalf("rotated %v, %v", rotated, err)
+	}
+	first, ok := ks.Primary()
+	if !ok || persisted != 2 {
+		t.Fatalf("no primary key, or persisted %d times", persisted)
+	}
+	ciphertext, err := ks.Seal(nil, []byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rotated, err := r.RotateIfDue(); err != nil || rotated {
+		t.Errorf("rotated early: %v, %v", rotated, err)
+	}
+
+	for i := 0; i < 4; i++ {
+		now = now.Add(24 * time.Hour)
+		if rotated, err := r.RotateIfDue(); err != nil || !rotated {
+			t.Fatalf("rotation %d: rotated %v, %v", i, rotated, err)
+		}
+		if i == 1 {
+			if _, err := ks.Open(nil, ciphertext, nil); err != nil {
+				t.Errorf("ciphertext of a retained key failed to open: %v", err)
+			}
+		}
+	}
+	if keys := ks.Keys(); len(keys) != 3 {
+		t.Errorf("got %d keys, expected the primary and 2 previous keys", len(keys))
+	}
+	if _, err := ks.Open(nil, ciphertext, nil); err == nil {
+		t.Errorf("ciphertext of a removed key opened")
+	}
+
+	// A failed persist leaves the keyset unchanged.
+	primary, _ := ks.Primary()
+	now = now.Add(24 * time.Hour)
+	persistErr = errors.New("disk full")
+	if _, err := r.RotateIfDue(); err == nil {
+		t.Errorf("rotation with failing persist succeeded")
+	}
+	if p, _ := ks.Primary(); p.ID != primary.ID || len(ks.Keys()) != 3 || p.ID == first.ID {
+		t.Errorf("keyset changed after a failed persist")
+	}
+	persistErr = nil
+	if rotated, err := r.RotateIfDue(); err != nil || !rotated {
+		t.Errorf("retried rotation: %v, %v", rotated, err)
+	}
+
+	if _, err := keyset.NewRotator(ks, 0, 1, nil); err == nil {
+		t.Errorf("zero interval accepted")
+	}
+}
+
+func TestRotatorRun(t *testing.T) {
+	ks := keyset.New()
+	rotations := make(chan struct{}, 10)
+	r, err := keyset.NewRotator(ks, 10*time.Millisecond, 1, func(*keyset.Keyset) error {
+		select {
+		case rotations <- struct{}{}:
+		default:
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- r.Run(ctx) }()
+	for i := 0; i < 6; i++ {
+		<-rotations
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run returned %v", err)
+	}
+	if len(ks.Keys()) != 2 {
+		t.Errorf("got %d keys", len(ks.Keys()))
+	}
+}
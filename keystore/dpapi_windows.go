@@ -0,0 +1,111 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"unsafe"
+
+	"filippo.io/xaes256gcm"
+	"golang.org/x/sys/windows"
+)
+
+// DPAPI returns a Store that keeps keys in files in dir, encrypted with the
+// Windows Data Protection API, so that they can only be decrypted by the
+// current user on the current machine. dir is created if it doesn't exist,
+// and each key is stored in a file named after the key with the extension
+// ".dpapi".
+//
+// The name of the key is bound to the encrypted file as DPAPI entropy, so
+// files can't be renamed to swap keys.
+func DPAPI(dir string) Store {
+	return dpapi(dir)
+}
+
+type dpapi string
+
+func (d dpapi) path(name string) string {
+	return filepath.Join(string(d), name+".dpapi")
+}
+
+func dpapiEntropy(name string) *windows.DataBlob {
+	return newBlob([]byte("filippo.io/xaes256gcm/keystore DPAPI key " + name))
+}
+
+func newBlob(b []byte) *windows.DataBlob {
+	return &windows.DataBlob{Size: uint32(len(b)), Data: &b[0]}
+}
+
+// blobBytes copies the contents of b, which was allocated by DPAPI, and frees
+// it.
+func blobBytes(b *windows.DataBlob) []byte {
+	defer windows.LocalFree(windows.Handle(unsafe.Pointer(b.Data)))
+	return append([]byte(nil), unsafe.Slice(b.Data, b.Size)...)
+}
+
+func (d dpapi) Get(name string) (xaes256gcm.Key, error) {
+	if err := checkName(name); err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	protected, err := os.ReadFile(d.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return xaes256gcm.Key{}, fmt.Errorf("keystore: failed to read key: %w", ErrNotFound)
+	} else if err != nil {
+		return xaes256gcm.Key{}, fmt.Errorf("keystore: failed to read key: %w", err)
+	}
+	if len(protected) == 0 {
+		return xaes256gcm.Key{}, errors.New("keystore: key file is empty")
+	}
+	var out windows.DataBlob
+	if err := windows.CryptUnprotectData(newBlob(protected), nil, dpapiEntropy(name), 0, nil,
+		windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return xaes256gcm.Key{}, fmt.Errorf("keystore: failed to decrypt key: %w", err)
+	}
+	return xaes256gcm.NewKey(blobBytes(&out))
+}
+
+func (d dpapi) Set(name string, key xaes256gcm.Key) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	var out windows.DataBlob
+	if err := windows.CryptProtectData(newBlob(key.Bytes()), nil, dpapiEntropy(name), 0, nil,
+		windows.CRYPTPROTECT_UI_FORBIDDEN, &out); err != nil {
+		return fmt.Errorf("keystore: failed to encrypt key: %w", err)
+	}
+	protected := blobBytes(&out)
+	if err := os.MkdirAll(string(d), 0700); err != nil {
+		return fmt.Errorf("keystore: failed to store key: %w", err)
+	}
+	tmp, err := os.CreateTemp(string(d), "."+name+".tmp*")
+	if err != nil {
+		return fmt.Errorf("keystore: failed to store key: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(protected); err != nil {
+		tmp.Close()
+		return fmt.Errorf("keystore: failed to store key: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("keystore: failed to store key: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), d.path(name)); err != nil {
+		return fmt.Errorf("keystore: failed to store key: %w", err)
+	}
+	return nil
+}
+
+func (d dpapi) Delete(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	err := os.Remove(d.path(name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("keystore: failed to delete key: %w", ErrNotFound)
+	} else if err != nil {
+		return fmt.Errorf("keystore: failed to delete key: %w", err)
+	}
+	return nil
+}
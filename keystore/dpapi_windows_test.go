@@ -0,0 +1,27 @@
+package keystore_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/keystore"
+)
+
+func TestDPAPI(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "keys")
+	s := keystore.DPAPI(dir)
+	testStore(t, s, "test")
+
+	// Renamed files don't decrypt.
+	if err := s.Set("a", xaes256gcm.GenerateKey()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "a.dpapi"), filepath.Join(dir, "b.dpapi")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("b"); err == nil {
+		t.Errorf("renamed key decrypted")
+	}
+}
@@ -0,0 +1,136 @@
+//go:build darwin && cgo
+
+package keystore
+
+/*
+#cgo LDFLAGS: -framework CoreFoundation -framework Security
+#include <stdlib.h>
+#include <CoreFoundation/CoreFoundation.h>
+#include <Security/Security.h>
+
+static CFMutableDictionaryRef xaes_query(const char *service, const char *account) {
+	CFMutableDictionaryRef q = CFDictionaryCreateMutable(NULL, 0,
+		&kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFStringRef s = CFStringCreateWithCString(NULL, service, kCFStringEncodingUTF8);
+	CFStringRef a = CFStringCreateWithCString(NULL, account, kCFStringEncodingUTF8);
+	CFDictionarySetValue(q, kSecClass, kSecClassGenericPassword);
+	CFDictionarySetValue(q, kSecAttrService, s);
+	CFDictionarySetValue(q, kSecAttrAccount, a);
+	CFRelease(s);
+	CFRelease(a);
+	return q;
+}
+
+static OSStatus xaes_keychain_set(const char *service, const char *account, const void *key, int len) {
+	CFMutableDictionaryRef q = xaes_query(service, account);
+	CFDataRef d = CFDataCreate(NULL, key, len);
+	CFMutableDictionaryRef attrs = CFDictionaryCreateMutable(NULL, 0,
+		&kCFTypeDictionaryKeyCallBacks, &kCFTypeDictionaryValueCallBacks);
+	CFDictionarySetValue(attrs, kSecValueData, d);
+	OSStatus st = SecItemUpdate(q, attrs);
+	if (st == errSecItemNotFound) {
+		CFDictionarySetValue(q, kSecValueData, d);
+		CFDictionarySetValue(q, kSecAttrAccessible, kSecAttrAccessibleAfterFirstUnlockThisDeviceOnly);
+		st = SecItemAdd(q, NULL);
+	}
+	CFRelease(attrs);
+	CFRelease(d);
+	CFRelease(q);
+	return st;
+}
+
+static OSStatus xaes_keychain_get(const char *service, const char *account, void *key, int len) {
+	CFMutableDictionaryRef q = xaes_query(service, account);
+	CFDictionarySetValue(q, kSecReturnData, kCFBooleanTrue);
+	CFDictionarySetValue(q, kSecMatchLimit, kSecMatchLimitOne);
+	CFTypeRef result = NULL;
+	OSStatus st = SecItemCopyMatching(q, &result);
+	CFRelease(q);
+	if (st != errSecSuccess) {
+		return st;
+	}
+	if (CFGetTypeID(result) != CFDataGetTypeID() || CFDataGetLength((CFDataRef)result) != len) {
+		CFRelease(result);
+		return errSecDecode;
+	}
+	CFDataGetBytes((CFDataRef)result, CFRangeMake(0, len), key);
+	CFRelease(result);
+	return errSecSuccess;
+}
+
+static OSStatus xaes_keychain_delete(const char *service, const char *account) {
+	CFMutableDictionaryRef q = xaes_query(service, account);
+	OSStatus st = SecItemDelete(q);
+	CFRelease(q);
+	return st;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Keychain returns a Store that keeps keys in the login keychain of the
+// current user, as generic passwords of the given service, with the key name
+// as the account. Keys are only accessible while the device is unlocked after
+// boot, and are not synchronized to other devices.
+//
+// The first access to a key by a different binary than the one that stored it
+// might prompt the user for permission.
+func Keychain(service string) Store {
+	return keychain(service)
+}
+
+type keychain string
+
+func keychainError(op string, st C.OSStatus) error {
+	if st == C.errSecItemNotFound {
+		return fmt.Errorf("keystore: failed to %s key: %w", op, ErrNotFound)
+	}
+	return fmt.Errorf("keystore: failed to %s key: keychain error %d", op, int(st))
+}
+
+func (k keychain) Get(name string) (xaes256gcm.Key, error) {
+	if err := checkName(name); err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	service, account := C.CString(string(k)), C.CString(name)
+	defer C.free(unsafe.Pointer(service))
+	defer C.free(unsafe.Pointer(account))
+	key := make([]byte, xaes256gcm.KeySize)
+	if st := C.xaes_keychain_get(service, account, unsafe.Pointer(&key[0]), C.int(len(key))); st != C.errSecSuccess {
+		return xaes256gcm.Key{}, keychainError("read", st)
+	}
+	return xaes256gcm.NewKey(key)
+}
+
+func (k keychain) Set(name string, key xaes256gcm.Key) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	service, account := C.CString(string(k)), C.CString(name)
+	defer C.free(unsafe.Pointer(service))
+	defer C.free(unsafe.Pointer(account))
+	b := key.Bytes()
+	if st := C.xaes_keychain_set(service, account, unsafe.Pointer(&b[0]), C.int(len(b))); st != C.errSecSuccess {
+		return keychainError("store", st)
+	}
+	return nil
+}
+
+func (k keychain) Delete(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	service, account := C.CString(string(k)), C.CString(name)
+	defer C.free(unsafe.Pointer(service))
+	defer C.free(unsafe.Pointer(account))
+	if st := C.xaes_keychain_delete(service, account); st != C.errSecSuccess {
+		return keychainError("delete", st)
+	}
+	return nil
+}
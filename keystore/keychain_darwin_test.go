@@ -0,0 +1,16 @@
+//go:build darwin && cgo
+
+package keystore_test
+
+import (
+	"testing"
+
+	"filippo.io/xaes256gcm/keystore"
+)
+
+func TestKeychain(t *testing.T) {
+	if testing.Short() {
+		t.Skip("accessing the keychain might prompt the user")
+	}
+	testStore(t, keystore.Keychain("filippo.io/xaes256gcm/keystore test"), "test")
+}
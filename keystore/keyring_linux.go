@@ -0,0 +1,98 @@
+package keystore
+
+import (
+	"errors"
+	"fmt"
+
+	"filippo.io/xaes256gcm"
+	"golang.org/x/sys/unix"
+)
+
+// Keyring identifies a Linux kernel keyring.
+type Keyring int
+
+const (
+	// ThreadKeyring is the keyring of the calling thread.
+	ThreadKeyring Keyring = unix.KEY_SPEC_THREAD_KEYRING
+	// ProcessKeyring is the keyring of the calling process, which is not
+	// inherited across fork.
+	ProcessKeyring Keyring = unix.KEY_SPEC_PROCESS_KEYRING
+	// SessionKeyring is the session keyring, which is inherited by child
+	// processes, so that an init process or a wrapper can store keys for the
+	// daemon it executes.
+	SessionKeyring Keyring = unix.KEY_SPEC_SESSION_KEYRING
+	// UserKeyring is the keyring shared by all processes of the current user.
+	UserKeyring Keyring = unix.KEY_SPEC_USER_KEYRING
+)
+
+// KernelKeyring returns a Store that keeps keys in the Linux kernel keyring
+// ring, as keys of type "user" with description prefix followed by the key
+// name. Keys live in kernel memory, so they are not exposed by files in /proc,
+// and are not in the heap of the process until they are read.
+//
+// Get searches ring and the keyrings linked to it, so for example a key added
+// with "keyctl padd user myapp:key @s" by a wrapper script can be read from
+// SessionKeyring with prefix "myapp:" and name "key". Delete invalidates the
+// key, removing it from every keyring.
+func KernelKeyring(ring Keyring, prefix string) Store {
+	return &kernelKeyring{ring: ring, prefix: prefix}
+}
+
+type kernelKeyring struct {
+	ring   Keyring
+	prefix string
+}
+
+func (k *kernelKeyring) search(name string) (int, error) {
+	id, err := unix.KeyctlSearch(int(k.ring), "user", k.prefix+name, 0)
+	if errors.Is(err, unix.ENOKEY) || errors.Is(err, unix.EKEYEXPIRED) || errors.Is(err, unix.EKEYREVOKED) {
+		return 0, ErrNotFound
+	}
+	return id, err
+}
+
+func (k *kernelKeyring) Get(name string) (xaes256gcm.Key, error) {
+	if err := checkName(name); err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	id, err := k.search(name)
+	if err != nil {
+		return xaes256gcm.Key{}, fmt.Errorf("keystore: failed to read key: %w", err)
+	}
+	// Read one more byte than the key size, to detect longer payloads.
+	buf := make([]byte, xaes256gcm.KeySize+1)
+	n, err := unix.KeyctlBuffer(unix.KEYCTL_READ, id, buf, 0)
+	if err != nil {
+		return xaes256gcm.Key{}, fmt.Errorf("keystore: failed to read key: %w", err)
+	}
+	if n != xaes256gcm.KeySize {
+		return xaes256gcm.Key{}, fmt.Errorf("keystore: kernel key %q has the wrong size", k.prefix+name)
+	}
+	return xaes256gcm.NewKey(buf[:n])
+}
+
+func (k *kernelKeyring) Set(name string, key xaes256gcm.Key) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	// add_key updates the payload of a key with the same type and
+	// description in ring, if any.
+	if _, err := unix.AddKey("user", k.prefix+name, key.Bytes(), int(k.ring)); err != nil {
+		return fmt.Errorf("keystore: failed to store key: %w", err)
+	}
+	return nil
+}
+
+func (k *kernelKeyring) Delete(name string) error {
+	if err := checkName(name); err != nil {
+		return err
+	}
+	id, err := k.search(name)
+	if err != nil {
+		return fmt.Errorf("keystore: failed to delete key: %w", err)
+	}
+	if _, err := unix.KeyctlInt(unix.KEYCTL_INVALIDATE, id, 0, 0, 0); err != nil {
+		return fmt.Errorf("keystore: failed to delete key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package keystore_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"filippo.io/xaes256gcm/keystore"
+	"golang.org/x/sys/unix"
+)
+
+func TestKernelKeyring(t *testing.T) {
+	// Check that the keyring is accessible, it's often disabled in containers.
+	if _, err := unix.KeyctlGetKeyringID(unix.KEY_SPEC_PROCESS_KEYRING, true); errors.Is(err, unix.ENOSYS) || errors.Is(err, unix.EPERM) || errors.Is(err, unix.EACCES) {
+		t.Skipf("kernel keyring not available: %v", err)
+	}
+	prefix := fmt.Sprintf("filippo.io/xaes256gcm/keystore test %d:", os.Getpid())
+	testStore(t, keystore.KernelKeyring(keystore.ProcessKeyring, prefix), "test")
+}
@@ -0,0 +1,51 @@
+// Package keystore stores XAES-256-GCM keys in the key storage facilities of
+// the operating system, so that applications don't need to write raw keys to
+// configuration files.
+//
+// The available backends depend on the platform: [Keychain] on macOS,
+// [DPAPI] on Windows, and [KernelKeyring] on Linux.
+package keystore
+
+import (
+	"errors"
+	"fmt"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Store is a named collection of keys.
+type Store interface {
+	// Get returns the key stored as name, or an error wrapping [ErrNotFound]
+	// if there is none.
+	Get(name string) (xaes256gcm.Key, error)
+
+	// Set stores key as name, replacing any existing key with that name.
+	Set(name string, key xaes256gcm.Key) error
+
+	// Delete removes the key stored as name. It returns an error wrapping
+	// [ErrNotFound] if there is none.
+	Delete(name string) error
+}
+
+// ErrNotFound is returned by [Store] methods if no key has the requested
+// name.
+var ErrNotFound = errors.New("keystore: key not found")
+
+// checkName returns an error if name can't be used as a key name. Names must
+// be non-empty, and can contain only ASCII letters, digits, and the characters
+// '.', '_', and '-', so that they are valid file names on every platform.
+func checkName(name string) error {
+	if name == "" || len(name) > 128 {
+		return fmt.Errorf("keystore: invalid key name %q", name)
+	}
+	for _, c := range name {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+			c == '.' || c == '_' || c == '-') {
+			return fmt.Errorf("keystore: invalid key name %q", name)
+		}
+	}
+	if name[0] == '.' {
+		return fmt.Errorf("keystore: invalid key name %q", name)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package keystore_test
+
+import (
+	"errors"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/keystore"
+)
+
+// testStore exercises a Store backend. name must not exist in s.
+func testStore(t *testing.T, s keystore.Store, name string) {
+	if _, err := s.Get(name); !errors.Is(err, keystore.ErrNotFound) {
+		t.Fatalf("Get of missing key: got %v, expected ErrNotFound", err)
+	}
+	if err := s.Delete(name); !errors.Is(err, keystore.ErrNotFound) {
+		t.Errorf("Delete of missing key: got %v, expected ErrNotFound", err)
+	}
+
+	key := xaes256gcm.GenerateKey()
+	if err := s.Set(name, key); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Delete(name)
+	if got, err := s.Get(name); err != nil || got != key {
+		t.Errorf("Get: got %v, %v", got, err)
+	}
+
+	replacement := xaes256gcm.GenerateKey()
+	if err := s.Set(name, replacement); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := s.Get(name); err != nil || got != replacement {
+		t.Errorf("Get after replacement: got %v, %v", got, err)
+	}
+
+	if err := s.Delete(name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(name); !errors.Is(err, keystore.ErrNotFound) {
+		t.Errorf("Get of deleted key: got %v, expected ErrNotFound", err)
+	}
+
+	for _, bad := range []string{"", ".hidden", "a/b", `a\b`, "a b"} {
+		if err := s.Set(bad, key); err == nil {
+			t.Errorf("Set accepted invalid name %q", bad)
+		}
+	}
+}
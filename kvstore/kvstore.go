@@ -0,0 +1,222 @@
+// Package kvstore implements a small encrypted and authenticated key-value
+// store, persisted to a single file, for example for CLI credential caches and
+// application secrets.
+//
+// Each value is encrypted with XAES-256-GCM, with a random nonce and additional
+// data made of a fixed prefix and the entry name, so values can't be moved
+// between names or passed off as the index. The file
+// also holds an encrypted index of the entry names and of the SHA-256 hashes of
+// their ciphertexts, so entries can't be removed, added, or replaced with older
+// versions individually, and names are not revealed. The file as a whole can
+// still be replaced with an older version.
+//
+// The file is the 4-byte big-endian length of the encrypted index, followed by
+// the encrypted index, and by each encrypted value, prefixed by its 4-byte
+// big-endian length, in index order. Each index entry is the 2-byte big-endian
+// length of the name, the name, and the hash.
+package kvstore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"filippo.io/xaes256gcm"
+)
+
+const indexAD = "filippo.io/xaes256gcm/kvstore index"
+
+// entryAD returns the additional data of the value of the entry name, which
+// can't be equal to indexAD, whatever the name.
+func entryAD(name string) []byte {
+	return []byte("filippo.io/xaes256gcm/kvstore entry " + name)
+}
+
+// MaxNameLength is the maximum length of an entry name.
+const MaxNameLength = 0xffff
+
+// ErrNotFound is returned by [Store.Get] if the entry doesn't exist.
+var ErrNotFound = errors.New("kvstore: entry not found")
+
+// Store is an encrypted key-value store. Values are kept encrypted in memory,
+// and decrypted by Get. Changes are only persisted by Save. It's safe for
+// concurrent use.
+type Store struct {
+	path string
+	aead cipher.AEAD
+
+	mu      sync.Mutex
+	entries map[string][]byte // encrypted values
+}
+
+// Open loads the store at path, encrypted with the 32-byte key, or returns an
+// empty store if the file doesn't exist. The file is created by Save.
+func Open(path string, key []byte) (*Store, error) {
+	aead, err := xaes256gcm.New(key)
+	if err != nil {
+		return nil, err
+	}
+	s := &Store{path: path, aead: aead, entries: make(map[string][]byte)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.parse(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func readRecord(data []byte) (record, rest []byte, ok bool) {
+	if len(data) < 4 {
+		return nil, nil, false
+	}
+	n := binary.BigEndian.Uint32(data)
+	if uint64(len(data)-4) < uint64(n) {
+		return nil, nil, false
+	}
+	return data[4 : 4+n], data[4+n:], true
+}
+
+func (s *Store) parse(data []byte) error {
+	errMalformed := errors.New("kvstore: malformed or corrupted store")
+	sealedIndex, data, ok := readRecord(data)
+	if !ok {
+		return errMalformed
+	}
+	index, err := s.aead.Open(nil, nil, sealedIndex, []byte(indexAD))
+	if err != nil {
+		return errors.New("kvstore: failed to decrypt store, wrong key or corrupted file")
+	}
+	for len(index) > 0 {
+		if len(index) < 2 {
+			return errMalformed
+		}
+		n := int(binary.BigEndian.Uint16(index))
+		if len(index) < 2+n+sha256.Size {
+			return errMalformed
+		}
+		name := string(index[2 : 2+n])
+		hash := index[2+n : 2+n+sha256.Size]
+		index = index[2+n+sha256.Size:]
+
+		var value []byte
+		if value, data, ok = readRecord(data); !ok {
+			return errMalformed
+		}
+		if h := sha256.Sum256(value); !bytes.Equal(h[:], hash) {
+			return errMalformed
+		}
+		if _, dup := s.entries[name]; dup {
+			return errMalformed
+		}
+		s.entries[name] = bytes.Clone(value)
+	}
+	if len(data) != 0 {
+		return errMalformed
+	}
+	return nil
+}
+
+// Get returns the value of the entry name, or [ErrNotFound].
+func (s *Store) Get(name string) ([]byte, error) {
+	s.mu.Lock()
+	sealed, ok := s.entries[name]
+	s.mu.Unlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	value, err := s.aead.Open(nil, nil, sealed, entryAD(name))
+	if err != nil {
+		return nil, errors.New("kvstore: failed to decrypt entry")
+	}
+	return value, nil
+}
+
+// Set sets the value of the entry name. name must be at most
+// [MaxNameLength] bytes long.
+func (s *Store) Set(name string, value []byte) error {
+	if len(name) > MaxNameLength {
+		return errors.New("kvstore: name too long")
+	}
+	sealed := s.aead.Seal(nil, nil, value, entryAD(name))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[name] = sealed
+	return nil
+}
+
+// Delete removes the entry name, if it exists.
+func (s *Store) Delete(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, name)
+}
+
+// Names returns the sorted names of the entries.
+func (s *Store) Names() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.entries))
+	for name := range s.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Save atomically replaces the file with the current contents of the store,
+// creating it with permissions 0600 if it doesn't exist.
+func (s *Store) Save() (err error) {
+	names := s.Names()
+	s.mu.Lock()
+	var index, values []byte
+	for _, name := range names {
+		sealed := s.entries[name]
+		index = binary.BigEndian.AppendUint16(index, uint16(len(name)))
+		index = append(index, name...)
+		h := sha256.Sum256(sealed)
+		index = append(index, h[:]...)
+		values = binary.BigEndian.AppendUint32(values, uint32(len(sealed)))
+		values = append(values, sealed...)
+	}
+	s.mu.Unlock()
+	sealedIndex := s.aead.Seal(nil, nil, index, []byte(indexAD))
+	data := binary.BigEndian.AppendUint32(nil, uint32(len(sealedIndex)))
+	data = append(data, sealedIndex...)
+	data = append(data, values...)
+
+	f, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := f.Chmod(0600); err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
@@ -0,0 +1,103 @@
+package kvstore_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/kvstore"
+)
+
+var testKey = bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets")
+	s, err := kvstore.Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("token"); !errors.Is(err, kvstore.ErrNotFound) {
+		t.Errorf("Get on empty store: %v", err)
+	}
+	if err := s.Set("token", []byte("hunter2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("api-key", []byte("swordfish")); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("empty", nil); err != nil {
+		t.Fatal(err)
+	}
+	s.Delete("empty")
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"token", "hunter2", "api-key", "swordfish"} {
+		if bytes.Contains(data, []byte(secret)) {
+			t.Errorf("%q stored in plaintext", secret)
+		}
+	}
+
+	s, err = kvstore.Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := s.Names(); len(names) != 2 || names[0] != "api-key" || names[1] != "token" {
+		t.Errorf("Names = %q", names)
+	}
+	if got, err := s.Get("token"); err != nil || string(got) != "hunter2" {
+		t.Errorf("Get: %q, %v", got, err)
+	}
+
+	if _, err := kvstore.Open(path, bytes.Repeat([]byte{0x02}, xaes256gcm.KeySize)); err == nil {
+		t.Errorf("opened with the wrong key")
+	}
+	for _, corrupted := range [][]byte{
+		data[:len(data)-1],
+		append(bytes.Clone(data), 0),
+		append(bytes.Clone(data[:len(data)-1]), data[len(data)-1]^1),
+	} {
+		if err := os.WriteFile(path, corrupted, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := kvstore.Open(path, testKey); err == nil {
+			t.Errorf("opened corrupted store")
+		}
+	}
+}
+
+func TestIndexForgery(t *testing.T) {
+	// An entry named like the index, with an empty index as its value, must
+	// not be accepted as the index of a forged store.
+	path := filepath.Join(t.TempDir(), "secrets")
+	s, err := kvstore.Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("filippo.io/xaes256gcm/kvstore index", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := data[4+binary.BigEndian.Uint32(data):]
+	if err := os.WriteFile(path, forged, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kvstore.Open(path, testKey); err == nil {
+		t.Errorf("opened store with an entry as its index")
+	}
+}
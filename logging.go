@@ -0,0 +1,24 @@
+package xaes256gcm
+
+import (
+	"context"
+	"crypto/cipher"
+	"encoding/hex"
+	"log/slog"
+)
+
+// WithLogger returns an AEAD that wraps aead, and logs failed calls to Open to
+// logger at warning level, like [WithOpenFailureHook]. The log records include
+// the nonce and the lengths of the ciphertext and additional data, but never
+// plaintext, additional data, or key material.
+//
+// Keys can be logged directly, since [Key] implements [slog.LogValuer] and
+// only logs its fingerprint.
+func WithLogger(aead cipher.AEAD, logger *slog.Logger) cipher.AEAD {
+	return WithOpenFailureHook(aead, func(f *OpenFailure) {
+		logger.LogAttrs(context.Background(), slog.LevelWarn, "xaes256gcm: open failed",
+			slog.String("nonce", hex.EncodeToString(f.Nonce)),
+			slog.Int("ciphertext_length", f.CiphertextLength),
+			slog.Int("additional_data_length", len(f.AdditionalData)))
+	})
+}
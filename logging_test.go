@@ -0,0 +1,40 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestWithLogger(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	buf := &bytes.Buffer{}
+	logger := slog.New(slog.NewTextHandler(buf, nil))
+	c := xaes256gcm.WithLogger(key.AEAD(), logger)
+
+	ciphertext := c.Seal(nil, nil, []byte("hello"), []byte("secret context"))
+	if _, err := c.Open(nil, nil, ciphertext, []byte("secret context")); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("logged on success: %s", buf)
+	}
+	if _, err := c.Open(nil, nil, ciphertext, []byte("wrong context")); err == nil {
+		t.Fatal("Open succeeded with wrong additional data")
+	}
+	if !strings.Contains(buf.String(), "level=WARN") || !strings.Contains(buf.String(), "ciphertext_length=45") ||
+		strings.Contains(buf.String(), "context") {
+		t.Errorf("unexpected log: %s", buf)
+	}
+
+	buf.Reset()
+	logger.Info("loaded key", "key", key)
+	if !strings.Contains(buf.String(), "key.fingerprint="+key.Fingerprint()) ||
+		strings.Contains(buf.String(), hex.EncodeToString(key.Bytes())) {
+		t.Errorf("unexpected log: %s", buf)
+	}
+}
@@ -0,0 +1,221 @@
+// Package logseal implements a tamper-evident, append-only encrypted log.
+//
+// A log file starts with a 12-byte random log ID. It is followed by records,
+// each made of the 4-byte big-endian length of the ciphertext, followed by the
+// XAES-256-GCM encryption of the record. The nonce of each record is the log
+// ID followed by the 12-byte big-endian index of the record, and the
+// additional data is the authentication tag of the previous record (or 16 zero
+// bytes for the first record).
+//
+// Chaining the tags makes removing, reordering, or splicing records detectable
+// by [Reader]. Removing records from the end of the log is not detectable
+// without recording the number of records, or the latest tag, elsewhere.
+package logseal
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"filippo.io/xaes256gcm"
+)
+
+// MaxRecordSize is the maximum size of a record's plaintext.
+const MaxRecordSize = 1 << 24
+
+const (
+	idSize  = 12
+	tagSize = 16
+)
+
+type state struct {
+	aead  cipher.AEAD
+	id    [idSize]byte
+	index uint64
+	tag   [tagSize]byte
+}
+
+func (s *state) nonce() []byte {
+	nonce := make([]byte, xaes256gcm.NonceSize)
+	copy(nonce, s.id[:])
+	binary.BigEndian.PutUint64(nonce[xaes256gcm.NonceSize-8:], s.index)
+	return nonce
+}
+
+func (s *state) advance(ciphertext []byte) {
+	copy(s.tag[:], ciphertext[len(ciphertext)-tagSize:])
+	s.index++
+}
+
+// Writer appends records to a log file. It is safe for concurrent use.
+type Writer struct {
+	mu sync.Mutex
+	f  *os.File
+	s  state
+}
+
+// OpenWriter opens the log file at name for appending, creating it if it
+// doesn't exist. If it exists, the whole log is read and verified first, to
+// recover the index and tag of the last record.
+func OpenWriter(key []byte, name string) (*Writer, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{f: f, s: state{aead: aead}}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		if _, err := rand.Read(w.s.id[:]); err != nil {
+			f.Close()
+			return nil, err
+		}
+		if _, err := f.Write(w.s.id[:]); err != nil {
+			f.Close()
+			return nil, err
+		}
+		return w, nil
+	}
+
+	r, err := NewReader(key, f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	for {
+		if _, err := r.Next(); err == io.EOF {
+			break
+		} else if err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	w.s = r.s
+	return w, nil
+}
+
+// Append seals record and appends it to the log. It doesn't flush the file to
+// stable storage, see [Writer.Sync].
+func (w *Writer) Append(record []byte) error {
+	if len(record) > MaxRecordSize {
+		return errors.New("logseal: record too large")
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return errors.New("logseal: Writer is closed")
+	}
+
+	buf := make([]byte, 4, 4+len(record)+tagSize)
+	binary.BigEndian.PutUint32(buf, uint32(len(record)+tagSize))
+	buf = w.s.aead.Seal(buf, w.s.nonce(), record, w.s.tag[:])
+	// Write the record with a single call, to minimize the chance of a partial
+	// record if the process crashes.
+	if _, err := w.f.Write(buf); err != nil {
+		return err
+	}
+	w.s.advance(buf)
+	return nil
+}
+
+// Sync flushes the log to stable storage.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return errors.New("logseal: Writer is closed")
+	}
+	return w.f.Sync()
+}
+
+// Close flushes the log to stable storage and closes the file.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return errors.New("logseal: Writer is already closed")
+	}
+	err := w.f.Sync()
+	if cerr := w.f.Close(); err == nil {
+		err = cerr
+	}
+	w.f = nil
+	return err
+}
+
+// Reader reads and verifies the records of a log.
+type Reader struct {
+	r   *bufio.Reader
+	s   state
+	err error
+}
+
+// NewReader returns a Reader for the log read from r. It reads the log ID.
+func NewReader(key []byte, r io.Reader) (*Reader, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	lr := &Reader{r: bufio.NewReader(r), s: state{aead: aead}}
+	if _, err := io.ReadFull(lr.r, lr.s.id[:]); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	return lr, nil
+}
+
+// Next returns the next record, or [io.EOF] if there are no more records.
+// Any other error means the log is corrupted, tampered with, or was
+// truncated in the middle of a record.
+func (r *Reader) Next() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	record, err := r.next()
+	if err != nil {
+		r.err = err
+	}
+	return record, err
+}
+
+func (r *Reader) next() ([]byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r.r, length[:]); err == io.EOF {
+		return nil, io.EOF
+	} else if err == io.ErrUnexpectedEOF {
+		return nil, errors.New("logseal: truncated record")
+	} else if err != nil {
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(length[:])
+	if n < tagSize || n > MaxRecordSize+tagSize {
+		return nil, fmt.Errorf("logseal: invalid length for record %d", r.s.index)
+	}
+	ciphertext := make([]byte, n)
+	if _, err := io.ReadFull(r.r, ciphertext); err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, errors.New("logseal: truncated record")
+	} else if err != nil {
+		return nil, err
+	}
+	record, err := r.s.aead.Open(nil, r.s.nonce(), ciphertext, r.s.tag[:])
+	if err != nil {
+		return nil, fmt.Errorf("logseal: record %d failed to authenticate", r.s.index)
+	}
+	r.s.advance(ciphertext)
+	return record, nil
+}
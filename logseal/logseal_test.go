@@ -0,0 +1,92 @@
+package logseal_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/logseal"
+)
+
+var testKey = bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+
+func readAll(key, log []byte) ([]string, error) {
+	r, err := logseal.NewReader(key, bytes.NewReader(log))
+	if err != nil {
+		return nil, err
+	}
+	var records []string
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return records, err
+		}
+		records = append(records, string(record))
+	}
+}
+
+func TestLog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "audit.log")
+	w, err := logseal.OpenWriter(testKey, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := w.Append([]byte(fmt.Sprint("record ", i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen, and check the chain continues.
+	w, err = logseal.OpenWriter(testKey, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Append([]byte("record 3")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	log, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := readAll(testKey, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(records) != "[record 0 record 1 record 2 record 3]" {
+		t.Errorf("got %q", records)
+	}
+
+	// Remove the second record.
+	recordSize := 4 + len("record 0") + 16
+	spliced := append(bytes.Clone(log[:12+recordSize]), log[12+2*recordSize:]...)
+	if records, err := readAll(testKey, spliced); err == nil || len(records) != 1 {
+		t.Errorf("spliced log: %q, %v", records, err)
+	}
+
+	if _, err := readAll(testKey, log[:len(log)-1]); err == nil {
+		t.Errorf("truncated record accepted")
+	}
+	if _, err := readAll(bytes.Repeat([]byte{0x02}, xaes256gcm.KeySize), log); err == nil {
+		t.Errorf("log opened with wrong key")
+	}
+	if err := os.WriteFile(name, log[:len(log)-1], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := logseal.OpenWriter(testKey, name); err == nil {
+		t.Errorf("opened corrupted log for writing")
+	}
+}
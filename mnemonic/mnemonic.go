@@ -0,0 +1,92 @@
+// Package mnemonic encodes XAES-256-GCM keys as sequences of words, so that
+// backup keys can be written on paper and entered again by hand.
+//
+// A key is encoded as 24 words from the English word list of BIP-39, using the
+// BIP-39 encoding of 256 bits of entropy: the key is followed by the first
+// byte of its SHA-256 hash as a checksum, and each group of 11 bits selects a
+// word. The first four letters of each word are unique in the list, so they
+// are enough to decode it.
+//
+// Only the word encoding of BIP-39 is implemented: the words are the key
+// itself, and are not processed into a seed with PBKDF2.
+package mnemonic
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Words is the number of words in a mnemonic.
+const Words = 24
+
+//go:embed english.txt
+var english string
+
+var wordList = strings.Fields(english)
+
+// Encode returns the mnemonic of key, as 24 lowercase words separated by
+// spaces.
+func Encode(key xaes256gcm.Key) string {
+	b := key.Bytes()
+	sum := sha256.Sum256(b)
+	b = append(b, sum[0])
+	words := make([]string, Words)
+	for i := range words {
+		words[i] = wordList[bits11(b, i*11)]
+	}
+	return strings.Join(words, " ")
+}
+
+// Decode parses a mnemonic produced by [Encode]. Words can be separated by any
+// whitespace, are case-insensitive, and can be abbreviated to their first four
+// letters. The checksum is verified, so that mistyped words are detected.
+func Decode(mnemonic string) (xaes256gcm.Key, error) {
+	fields := strings.Fields(strings.ToLower(mnemonic))
+	if len(fields) != Words {
+		return xaes256gcm.Key{}, fmt.Errorf("mnemonic: got %d words, expected %d", len(fields), Words)
+	}
+	b := make([]byte, xaes256gcm.KeySize+1)
+	for i, w := range fields {
+		idx, ok := lookup(w)
+		if !ok {
+			return xaes256gcm.Key{}, fmt.Errorf("mnemonic: word %d (%q) is not in the word list", i+1, w)
+		}
+		for j := 0; j < 11; j++ {
+			if idx&(1<<(10-j)) != 0 {
+				bit := i*11 + j
+				b[bit/8] |= 0x80 >> (bit % 8)
+			}
+		}
+	}
+	key, checksum := b[:xaes256gcm.KeySize], b[xaes256gcm.KeySize]
+	if sum := sha256.Sum256(key); sum[0] != checksum {
+		return xaes256gcm.Key{}, errors.New("mnemonic: invalid checksum, a word might be mistyped")
+	}
+	return xaes256gcm.NewKey(key)
+}
+
+// bits11 returns the 11 bits of b starting at bit offset off.
+func bits11(b []byte, off int) int {
+	var v int
+	for j := 0; j < 11; j++ {
+		bit := off + j
+		v = v<<1 | int(b[bit/8]>>(7-bit%8)&1)
+	}
+	return v
+}
+
+// lookup returns the index of w in the word list, matching either the whole
+// word or its first four letters.
+func lookup(w string) (int, bool) {
+	for i, word := range wordList {
+		if w == word || len(w) == 4 && strings.HasPrefix(word, w) {
+			return i, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,80 @@
+package mnemonic_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/mnemonic"
+)
+
+// Test vectors from BIP-39, for 256 bits of entropy.
+var vectors = []struct {
+	key      byte
+	mnemonic string
+}{
+	{0x00, strings.Repeat("abandon ", 23) + "art"},
+	{0x7f, "legal winner thank year wave sausage worth useful legal winner thank year " +
+		"wave sausage worth useful legal winner thank year wave sausage worth title"},
+	{0x80, "letter advice cage absurd amount doctor acoustic avoid letter advice cage absurd " +
+		"amount doctor acoustic avoid letter advice cage absurd amount doctor acoustic bless"},
+	{0xff, strings.Repeat("zoo ", 23) + "vote"},
+}
+
+func TestVectors(t *testing.T) {
+	for _, v := range vectors {
+		key := xaes256gcm.MustKey(bytes.Repeat([]byte{v.key}, xaes256gcm.KeySize))
+		if got := mnemonic.Encode(key); got != v.mnemonic {
+			t.Errorf("%02x: got %q", v.key, got)
+		}
+		decoded, err := mnemonic.Decode(v.mnemonic)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded.Bytes(), key.Bytes()) {
+			t.Errorf("%02x: decoded %x", v.key, decoded.Bytes())
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	words := strings.Fields(mnemonic.Encode(key))
+
+	// Abbreviated, uppercase, and irregularly spaced mnemonics are accepted.
+	var abbreviated []string
+	for _, w := range words {
+		if len(w) > 4 {
+			w = w[:4]
+		}
+		abbreviated = append(abbreviated, strings.ToUpper(w))
+	}
+	for _, m := range []string{strings.Join(abbreviated, " "), strings.Join(words, "\n\t ")} {
+		decoded, err := mnemonic.Decode(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decoded.Bytes(), key.Bytes()) {
+			t.Errorf("decoded %q to the wrong key", m)
+		}
+	}
+
+	swapped := append([]string{}, words...)
+	swapped[0], swapped[1] = swapped[1], swapped[0]
+	for _, m := range []string{
+		strings.Join(words[1:], " "),
+		strings.Join(append(words, "zoo"), " "),
+		strings.Join(append([]string{"xaes"}, words[1:]...), " "),
+		strings.Join(append([]string{"ab"}, words[1:]...), " "),
+	} {
+		if _, err := mnemonic.Decode(m); err == nil {
+			t.Errorf("decoded invalid mnemonic %q", m)
+		}
+	}
+	if swapped[0] != swapped[1] {
+		if k, err := mnemonic.Decode(strings.Join(swapped, " ")); err == nil && bytes.Equal(k.Bytes(), key.Bytes()) {
+			t.Errorf("swapped words decoded to the same key")
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Package mobile exposes XAES-256-GCM to iOS and Android apps through
+// gomobile bind, with a flattened API made only of functions that take and
+// return byte slices, strings, and errors.
+//
+// For example,
+//
+//	gomobile bind -target=android filippo.io/xaes256gcm/mobile
+//
+// produces an AAR with a Mobile class with static methods, and
+// -target=ios an XCFramework with Mobile-prefixed functions.
+//
+// Seal and Open use random nonces like [filippo.io/xaes256gcm.New], and the
+// file functions use the chunked format of [filippo.io/xaes256gcm/stream], so
+// ciphertexts interoperate with Go backends using the same formats.
+package mobile
+
+import (
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/stream"
+)
+
+// Keygen returns a new random 32-byte key.
+func Keygen() []byte {
+	return xaes256gcm.GenerateKey().Bytes()
+}
+
+// Fingerprint returns a short identifier for key, as returned by
+// [xaes256gcm.Key.Fingerprint].
+func Fingerprint(key []byte) (string, error) {
+	k, err := xaes256gcm.NewKey(key)
+	if err != nil {
+		return "", err
+	}
+	return k.Fingerprint(), nil
+}
+
+// Seal encrypts and authenticates plaintext, authenticates additionalData,
+// and returns the ciphertext, prefixed by a random nonce. additionalData may
+// be nil.
+func Seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return a.Seal(nil, nil, plaintext, additionalData), nil
+}
+
+// Open decrypts and authenticates a ciphertext returned by Seal, and returns
+// the plaintext.
+func Open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		return nil, err
+	}
+	return a.Open(nil, nil, ciphertext, additionalData)
+}
+
+// EncryptFile encrypts the file at src to dst, like [stream.EncryptFile].
+func EncryptFile(key []byte, src, dst string) error {
+	return stream.EncryptFile(key, src, dst)
+}
+
+// DecryptFile decrypts the file at src to dst, like [stream.DecryptFile].
+func DecryptFile(key []byte, src, dst string) error {
+	return stream.DecryptFile(key, src, dst)
+}
+
+// VerifyFile checks the integrity of the file at path, without writing the
+// plaintext anywhere, like [stream.VerifyFile].
+func VerifyFile(key []byte, path string) error {
+	return stream.VerifyFile(key, path)
+}
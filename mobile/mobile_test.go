@@ -0,0 +1,64 @@
+package mobile_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/mobile"
+)
+
+func TestRoundTrip(t *testing.T) {
+	key := mobile.Keygen()
+	if len(key) != xaes256gcm.KeySize {
+		t.Fatalf("key is %d bytes", len(key))
+	}
+	if fp, err := mobile.Fingerprint(key); err != nil || fp != xaes256gcm.MustKey(key).Fingerprint() {
+		t.Errorf("Fingerprint: %q, %v", fp, err)
+	}
+
+	ciphertext, err := mobile.Seal(key, []byte("hello"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Ciphertexts interoperate with xaes256gcm.New.
+	if p, err := xaes256gcm.MustNew(key).Open(nil, nil, ciphertext, nil); err != nil || string(p) != "hello" {
+		t.Errorf("Open with xaes256gcm.New: %q, %v", p, err)
+	}
+	if p, err := mobile.Open(key, ciphertext, nil); err != nil || string(p) != "hello" {
+		t.Errorf("Open: %q, %v", p, err)
+	}
+	if _, err := mobile.Open(key, ciphertext, []byte("ad")); err == nil {
+		t.Errorf("opened with the wrong additional data")
+	}
+	if _, err := mobile.Seal(key[:16], []byte("hello"), nil); err == nil {
+		t.Errorf("short key accepted")
+	}
+}
+
+func TestFile(t *testing.T) {
+	key := mobile.Keygen()
+	dir := t.TempDir()
+	src, enc, dec := filepath.Join(dir, "src"), filepath.Join(dir, "enc"), filepath.Join(dir, "dec")
+	plaintext := bytes.Repeat([]byte("hello, mobile\n"), 10000)
+	if err := os.WriteFile(src, plaintext, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := mobile.EncryptFile(key, src, enc); err != nil {
+		t.Fatal(err)
+	}
+	if err := mobile.VerifyFile(key, enc); err != nil {
+		t.Fatal(err)
+	}
+	if err := mobile.DecryptFile(key, enc, dec); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(dec); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("decrypted file doesn't match: %v", err)
+	}
+	if err := mobile.VerifyFile(mobile.Keygen(), enc); err == nil {
+		t.Errorf("verified with the wrong key")
+	}
+}
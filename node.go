@@ -0,0 +1,63 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+)
+
+// NewWithNodeNonces is like [NewWithCounterNonces], but the nonce starts with
+// nodeID, a caller-assigned identifier of the node or shard that is sealing,
+// encoded as a nodeIDSize-byte big-endian integer. key must be exactly 32
+// bytes long.
+//
+// Each nonce is the node ID, followed by a random prefix generated once when
+// the AEAD is created, filling the first 16 bytes, and an 8-byte big-endian
+// counter. Nodes with distinct IDs never produce the same nonce, so a fleet of
+// sealers sharing one key doesn't need to coordinate at seal time, nor rely
+// on random nonces not colliding across the fleet. The IDs must be assigned
+// so that no two running nodes share one.
+//
+// nodeIDSize must be between 1 and 8, leaving at least 8 random bytes, and
+// nodeID must fit in nodeIDSize bytes. All nodes sharing a key must use the
+// same nodeIDSize, which must be chosen to accommodate the largest ID the
+// fleet will ever assign.
+//
+// The ciphertexts are compatible with [New]. The node ID can be recovered
+// from a ciphertext with [NodeID].
+func NewWithNodeNonces(key []byte, nodeID uint64, nodeIDSize int) (cipher.AEAD, error) {
+	if nodeIDSize < 1 || nodeIDSize > 8 {
+		return nil, errors.New("xaes256gcm: node ID size must be between 1 and 8 bytes")
+	}
+	if nodeIDSize < 8 && nodeID >= 1<<(8*nodeIDSize) {
+		return nil, errors.New("xaes256gcm: node ID doesn't fit in node ID size")
+	}
+	x, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	c := &counterNonces{randomNonces: randomNonces{x.(*xaes256gcm), rand.Reader}}
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], nodeID)
+	copy(c.prefix[:], id[8-nodeIDSize:])
+	if _, err := rand.Read(c.prefix[nodeIDSize:]); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// NodeID returns the node ID of a ciphertext produced by an AEAD returned by
+// [NewWithNodeNonces] with the same nodeIDSize. The ID is not authenticated
+// until the ciphertext is opened.
+func NodeID(ciphertext []byte, nodeIDSize int) (uint64, error) {
+	if nodeIDSize < 1 || nodeIDSize > 8 {
+		return 0, errors.New("xaes256gcm: node ID size must be between 1 and 8 bytes")
+	}
+	if len(ciphertext) < Overhead {
+		return 0, errors.New("xaes256gcm: ciphertext too short")
+	}
+	var id [8]byte
+	copy(id[8-nodeIDSize:], ciphertext[:nodeIDSize])
+	return binary.BigEndian.Uint64(id[:]), nil
+}
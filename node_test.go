@@ -0,0 +1,50 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestNodeNonces(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		id   uint64
+		size int
+	}{{0, 1}, {255, 1}, {0x0102, 2}, {1 << 40, 6}, {1<<64 - 1, 8}} {
+		c, err := xaes256gcm.NewWithNodeNonces(key, tc.id, tc.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		first := c.Seal(nil, nil, []byte("hello"), nil)
+		second := c.Seal(nil, nil, []byte("hello"), nil)
+		if got, err := a.Open(nil, nil, first, nil); err != nil || string(got) != "hello" {
+			t.Errorf("Open: %q, %v", got, err)
+		}
+		if id, err := xaes256gcm.NodeID(first, tc.size); err != nil || id != tc.id {
+			t.Errorf("NodeID: got %d, %v, expected %d", id, err, tc.id)
+		}
+		if !bytes.Equal(first[:16], second[:16]) ||
+			binary.BigEndian.Uint64(second[16:24]) != binary.BigEndian.Uint64(first[16:24])+1 {
+			t.Errorf("unexpected nonces %x, %x", first[:24], second[:24])
+		}
+	}
+
+	for _, tc := range []struct {
+		id   uint64
+		size int
+	}{{256, 1}, {1 << 16, 2}, {0, 0}, {0, 9}} {
+		if _, err := xaes256gcm.NewWithNodeNonces(key, tc.id, tc.size); err == nil {
+			t.Errorf("accepted node ID %d with size %d", tc.id, tc.size)
+		}
+	}
+	if _, err := xaes256gcm.NodeID(make([]byte, xaes256gcm.Overhead-1), 4); err == nil {
+		t.Errorf("NodeID accepted a short ciphertext")
+	}
+}
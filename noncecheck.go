@@ -0,0 +1,60 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"sync"
+)
+
+// WithNonceReuseCheck returns an AEAD that wraps aead, and panics if Seal is
+// called with one of the last size nonces passed to Seal. For AEADs returned by
+// [New], the check applies to the generated nonces. It's safe for concurrent
+// use if aead is.
+//
+// Random 24-byte nonces never collide in practice, so a repeat indicates a
+// broken random number generator, a cloned virtual machine state, or a bug in
+// the nonce management of the application. The check is meant as a
+// defense-in-depth tool, for example in staging environments: it only detects
+// repeats within a single process, and it costs memory proportional to size.
+func WithNonceReuseCheck(aead cipher.AEAD, size int) cipher.AEAD {
+	if size <= 0 {
+		panic("xaes256gcm: non-positive nonce reuse check size")
+	}
+	return &nonceCheckAEAD{AEAD: aead, seen: make(map[string]struct{}, size),
+		ring: make([]string, 0, size)}
+}
+
+type nonceCheckAEAD struct {
+	cipher.AEAD
+
+	mu   sync.Mutex
+	seen map[string]struct{}
+	ring []string // the nonces in seen, in a ring buffer starting at next
+	next int
+}
+
+func (a *nonceCheckAEAD) record(nonce []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	n := string(nonce)
+	if _, ok := a.seen[n]; ok {
+		panic("xaes256gcm: nonce reuse detected")
+	}
+	a.seen[n] = struct{}{}
+	if len(a.ring) < cap(a.ring) {
+		a.ring = append(a.ring, n)
+		return
+	}
+	delete(a.seen, a.ring[a.next])
+	a.ring[a.next] = n
+	a.next = (a.next + 1) % len(a.ring)
+}
+
+func (a *nonceCheckAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if a.AEAD.NonceSize() != 0 {
+		a.record(nonce)
+		return a.AEAD.Seal(dst, nonce, plaintext, additionalData)
+	}
+	out := a.AEAD.Seal(dst, nonce, plaintext, additionalData)
+	a.record(out[len(dst) : len(dst)+NonceSize])
+	return out
+}
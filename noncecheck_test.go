@@ -0,0 +1,46 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestNonceReuseCheck(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c = xaes256gcm.WithNonceReuseCheck(c, 2)
+
+	n1 := bytes.Repeat([]byte{1}, xaes256gcm.NonceSize)
+	n2 := bytes.Repeat([]byte{2}, xaes256gcm.NonceSize)
+	n3 := bytes.Repeat([]byte{3}, xaes256gcm.NonceSize)
+	c.Seal(nil, n1, []byte("hello"), nil)
+	c.Seal(nil, n2, []byte("hello"), nil)
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("reused nonce didn't panic")
+			}
+		}()
+		c.Seal(nil, n2, []byte("hello"), nil)
+	}()
+	c.Seal(nil, n3, []byte("hello"), nil)
+	// n1 fell out of the window.
+	c.Seal(nil, n1, []byte("hello"), nil)
+
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a = xaes256gcm.WithNonceReuseCheck(a, 100)
+	for i := 0; i < 1000; i++ {
+		ciphertext := a.Seal([]byte("prefix"), nil, []byte("hello"), nil)
+		if _, err := a.Open(nil, nil, ciphertext[len("prefix"):], nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,5 @@
+//go:build !boringcrypto
+
+package xaes256gcm
+
+func boringEnabled() bool { return false }
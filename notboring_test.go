@@ -0,0 +1,15 @@
+//go:build !boringcrypto
+
+package xaes256gcm_test
+
+import (
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestBoringEnabled(t *testing.T) {
+	if xaes256gcm.BoringEnabled() {
+		t.Errorf("BoringEnabled() = true without boringcrypto")
+	}
+}
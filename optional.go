@@ -0,0 +1,57 @@
+package xaes256gcm
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+)
+
+// NewWithOptionalNonces is like [New], but Seal also accepts a caller-supplied
+// 24-byte nonce, which is used instead of a random one. Either way, the nonce
+// is prepended to the ciphertext, so Open doesn't need to know which was used.
+// key must be exactly 32 bytes long.
+//
+// It's meant for applications migrating from [NewWithManualNonces], or that
+// occasionally need deterministic nonces, without a second AEAD instance over
+// the same key. Caller-supplied nonces must never repeat for the same key.
+//
+// Open also accepts a 24-byte nonce, in which case it must match the nonce
+// prepended to the ciphertext. NonceSize returns zero.
+func NewWithOptionalNonces(key []byte) (cipher.AEAD, error) {
+	x, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	return &optionalNonces{randomNonces{x.(*xaes256gcm), rand.Reader}}, nil
+}
+
+type optionalNonces struct {
+	randomNonces
+}
+
+func (o *optionalNonces) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	switch len(nonce) {
+	case 0:
+		return o.randomNonces.Seal(dst, nil, plaintext, additionalData)
+	case NonceSize:
+		ret, n := sliceForAppend(dst, NonceSize)
+		copy(n, nonce)
+		return o.x.Seal(ret, n, plaintext, additionalData)
+	default:
+		panic("xaes256gcm: bad nonce length")
+	}
+}
+
+func (o *optionalNonces) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	switch len(nonce) {
+	case 0:
+	case NonceSize:
+		if len(ciphertext) < Overhead || !bytes.Equal(nonce, ciphertext[:NonceSize]) {
+			return nil, errOpen
+		}
+	default:
+		return nil, errors.New("xaes256gcm: bad nonce length")
+	}
+	return o.randomNonces.Open(dst, nil, ciphertext, additionalData)
+}
@@ -0,0 +1,55 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestOptionalNonces(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	nonce := []byte("ABCDEFGHIJKLMNOPQRSTUVWX")
+	plaintext := []byte("XAES-256-GCM")
+	c, err := xaes256gcm.NewWithOptionalNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ciphertext := c.Seal(nil, nonce, plaintext, nil)
+	if expected := append(append([]byte{}, nonce...), m.Seal(nil, nonce, plaintext, nil)...); !bytes.Equal(ciphertext, expected) {
+		t.Errorf("got %x, expected %x", ciphertext, expected)
+	}
+	if got, err := a.Open(nil, nil, ciphertext, nil); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Open with New: %q, %v", got, err)
+	}
+	if got, err := c.Open(nil, nonce, ciphertext, nil); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Open with nonce: %q, %v", got, err)
+	}
+	if _, err := c.Open(nil, []byte("XXXXXXXXXXXXXXXXXXXXXXXX"), ciphertext, nil); err == nil {
+		t.Errorf("Open succeeded with mismatched nonce")
+	}
+
+	random := c.Seal(nil, nil, plaintext, nil)
+	if bytes.Equal(random[:xaes256gcm.NonceSize], nonce) {
+		t.Errorf("random nonce not generated")
+	}
+	if got, err := c.Open(nil, nil, random, nil); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("Open: %q, %v", got, err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Seal didn't panic on bad nonce length")
+		}
+	}()
+	c.Seal(nil, nonce[:12], plaintext, nil)
+}
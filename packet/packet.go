@@ -0,0 +1,138 @@
+// Package packet implements XAES-256-GCM encryption of datagrams, such as UDP
+// packets or QUIC datagrams, which might be lost, duplicated, or reordered.
+//
+// Each packet is the 8-byte big-endian sequence number followed by the
+// ciphertext and the 16-byte tag, for a fixed [Overhead] of 24 bytes. The
+// 24-byte nonce is the 12-byte session ID, which is not transmitted, followed
+// by four zero bytes and the sequence number.
+//
+// Since the first half of the nonce is fixed for a session, the key
+// derivation is performed only once, and opening packets in place with
+// [Opener.OpenInPlace] doesn't allocate. The Opener tolerates loss and
+// reordering, and rejects replayed packets with a [replay.Window].
+package packet
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/replay"
+)
+
+// SessionIDSize is the size of session IDs.
+const SessionIDSize = 12
+
+// Overhead is the difference between the lengths of a packet and its
+// plaintext.
+const Overhead = seqSize + xaes256gcm.OverheadWithManualNonces
+
+const seqSize = 8
+
+var errOpen = errors.New("packet: message authentication failed")
+
+// Sealer encrypts the packets sent in one direction of a session.
+//
+// Each pair of key and session ID must be used by a single Sealer, for example
+// by deriving a key per session, and using different session IDs for the two
+// directions. Otherwise, sequence numbers and nonces would repeat.
+//
+// A Sealer is not safe for concurrent use.
+type Sealer struct {
+	c     *xaes256gcm.Codec
+	nonce [xaes256gcm.NonceSize]byte
+	seq   uint64
+}
+
+// NewSealer returns a Sealer for key and sessionID, whose first packet has
+// sequence number zero.
+func NewSealer(key []byte, sessionID [SessionIDSize]byte) (*Sealer, error) {
+	c, err := xaes256gcm.NewCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	s := &Sealer{c: c}
+	copy(s.nonce[:], sessionID[:])
+	return s, nil
+}
+
+// Seal encrypts and authenticates plaintext, authenticates additionalData,
+// and appends the packet to dst. Each call uses the next sequence number.
+func (s *Sealer) Seal(dst, plaintext, additionalData []byte) ([]byte, error) {
+	if s.seq == 1<<64-1 {
+		return nil, errors.New("packet: sequence numbers exhausted")
+	}
+	binary.BigEndian.PutUint64(s.nonce[xaes256gcm.NonceSize-seqSize:], s.seq)
+	s.seq++
+	dst = append(dst, s.nonce[xaes256gcm.NonceSize-seqSize:]...)
+	return s.c.Seal(dst, s.nonce[:], plaintext, additionalData), nil
+}
+
+// Opener decrypts the packets received in one direction of a session.
+//
+// An Opener is not safe for concurrent use.
+type Opener struct {
+	c     *xaes256gcm.Codec
+	nonce [xaes256gcm.NonceSize]byte
+	w     replay.Window
+}
+
+// NewOpener returns an Opener for packets sealed by a [Sealer] with the same
+// key and sessionID.
+func NewOpener(key []byte, sessionID [SessionIDSize]byte) (*Opener, error) {
+	c, err := xaes256gcm.NewCodec(key)
+	if err != nil {
+		return nil, err
+	}
+	o := &Opener{c: c}
+	copy(o.nonce[:], sessionID[:])
+	return o, nil
+}
+
+// open checks the sequence number of packet and sets the nonce, returning the
+// sequence number.
+func (o *Opener) open(packet []byte) (uint64, error) {
+	if len(packet) < Overhead {
+		return 0, errOpen
+	}
+	seq := binary.BigEndian.Uint64(packet)
+	if !o.w.Check(seq) {
+		return 0, replay.ErrReplay
+	}
+	copy(o.nonce[xaes256gcm.NonceSize-seqSize:], packet[:seqSize])
+	return seq, nil
+}
+
+// Open decrypts and authenticates packet and additionalData, and appends the
+// plaintext to dst. It returns [replay.ErrReplay] without attempting
+// decryption if the packet was already opened or is too old to tell.
+func (o *Opener) Open(dst, packet, additionalData []byte) ([]byte, error) {
+	seq, err := o.open(packet)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := o.c.Open(dst, o.nonce[:], packet[seqSize:], additionalData)
+	if err != nil {
+		return nil, errOpen
+	}
+	o.w.Accept(seq)
+	return plaintext, nil
+}
+
+// OpenInPlace is like [Opener.Open], but decrypts packet in place, and returns
+// the plaintext, which is a subslice of packet. It doesn't allocate.
+//
+// If authentication fails, the contents of packet are undefined and should be
+// discarded.
+func (o *Opener) OpenInPlace(packet, additionalData []byte) ([]byte, error) {
+	seq, err := o.open(packet)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := o.c.OpenInPlace(packet[seqSize:], o.nonce[:], additionalData)
+	if err != nil {
+		return nil, errOpen
+	}
+	o.w.Accept(seq)
+	return plaintext, nil
+}
@@ -0,0 +1,95 @@
+package packet_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/packet"
+	"filippo.io/xaes256gcm/replay"
+)
+
+func TestRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	id := [packet.SessionIDSize]byte{1, 2, 3}
+	s, err := packet.NewSealer(key, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	o, err := packet.NewOpener(key, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var packets [][]byte
+	for i := 0; i < 5; i++ {
+		p, err := s.Seal(nil, []byte{byte(i)}, []byte("ad"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(p) != 1+packet.Overhead {
+			t.Errorf("packet is %d bytes", len(p))
+		}
+		packets = append(packets, p)
+	}
+
+	// Packets can be lost and reordered, but not replayed.
+	for _, i := range []int{3, 0, 4} {
+		got, err := o.Open(nil, packets[i], []byte("ad"))
+		if err != nil {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+		if !bytes.Equal(got, []byte{byte(i)}) {
+			t.Errorf("packet %d: got %x", i, got)
+		}
+	}
+	if _, err := o.Open(nil, packets[3], []byte("ad")); !errors.Is(err, replay.ErrReplay) {
+		t.Errorf("expected ErrReplay, got %v", err)
+	}
+
+	// Failed packets don't consume their sequence number.
+	if _, err := o.Open(nil, packets[1], []byte("wrong")); err == nil {
+		t.Errorf("opened with the wrong additional data")
+	}
+	tampered := append([]byte(nil), packets[2]...)
+	tampered[0] ^= 1
+	if _, err := o.Open(nil, tampered, []byte("ad")); err == nil {
+		t.Errorf("opened with a tampered sequence number")
+	}
+	if got, err := o.OpenInPlace(packets[1], []byte("ad")); err != nil || !bytes.Equal(got, []byte{1}) {
+		t.Errorf("OpenInPlace: %x, %v", got, err)
+	}
+
+	other, err := packet.NewOpener(key, [packet.SessionIDSize]byte{4, 5, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.Open(nil, packets[2], []byte("ad")); err == nil {
+		t.Errorf("opened with a different session ID")
+	}
+}
+
+func TestOpenInPlaceAllocations(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	var id [packet.SessionIDSize]byte
+	s, _ := packet.NewSealer(key, id)
+	o, _ := packet.NewOpener(key, id)
+	plaintext := make([]byte, 1200)
+	buf := make([]byte, 0, len(plaintext)+packet.Overhead)
+
+	// Warm up the Opener, which derives the key on first use.
+	p, _ := s.Seal(buf, plaintext, nil)
+	if _, err := o.OpenInPlace(p, nil); err != nil {
+		t.Fatal(err)
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		p, _ := s.Seal(buf[:0], plaintext, nil)
+		if _, err := o.OpenInPlace(p, nil); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Errorf("Seal and OpenInPlace allocated %v times", allocs)
+	}
+}
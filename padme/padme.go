@@ -0,0 +1,60 @@
+// Package padme implements the Padmé padding scheme, to reduce how much the
+// length of a ciphertext reveals about the length of its plaintext.
+//
+// Padmé, from "Reducing Metadata Leakage from Encrypted Files and
+// Communication with PURBs" (Nikitin et al., PETS 2019), rounds lengths up
+// so that a padded length only reveals O(log log L) bits of information about
+// a length L, with an overhead of at most 12%, and decreasing for larger
+// lengths.
+//
+// Messages are padded before Seal, and unpadded after Open:
+//
+//	ciphertext := aead.Seal(nil, nil, padme.Pad(nil, plaintext), ad)
+//	padded, err := aead.Open(nil, nil, ciphertext, ad)
+//	plaintext, err := padme.Unpad(padded)
+//
+// Padding is unambiguous: Pad appends a 0x80 byte and then zeroes, which Unpad
+// removes. Padding is not authenticated by itself, so it must always be
+// applied under an AEAD.
+package padme
+
+import (
+	"errors"
+	"math/bits"
+)
+
+// Length returns the Padmé padded length for a length n.
+func Length(n int) int {
+	if n < 2 {
+		return n
+	}
+	e := bits.Len(uint(n)) - 1
+	s := bits.Len(uint(e))
+	mask := 1<<(e-s) - 1
+	return (n + mask) &^ mask
+}
+
+// Pad appends plaintext followed by its padding to dst. The padded length is
+// Length(len(plaintext) + 1).
+func Pad(dst, plaintext []byte) []byte {
+	n := Length(len(plaintext) + 1)
+	dst = append(dst, plaintext...)
+	dst = append(dst, 0x80)
+	return append(dst, make([]byte, n-len(plaintext)-1)...)
+}
+
+// Unpad returns the plaintext of a padded message, as a subslice of padded.
+// It doesn't check that the padded length matches the Padmé length, so the
+// padding scheme can be changed without breaking existing messages.
+func Unpad(padded []byte) ([]byte, error) {
+	for i := len(padded) - 1; i >= 0; i-- {
+		switch padded[i] {
+		case 0x80:
+			return padded[:i], nil
+		case 0x00:
+		default:
+			return nil, errors.New("padme: invalid padding")
+		}
+	}
+	return nil, errors.New("padme: invalid padding")
+}
@@ -0,0 +1,51 @@
+package padme_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm/padme"
+)
+
+func TestLength(t *testing.T) {
+	for n, want := range map[int]int{
+		0: 0, 1: 1, 2: 2, 3: 3, 9: 10, 100: 104, 1000: 1024,
+		1025: 1088, 65536: 65536, 100000: 100352, 1_000_000: 1_015_808,
+	} {
+		if got := padme.Length(n); got != want {
+			t.Errorf("Length(%d) = %d, want %d", n, got, want)
+		}
+	}
+	for n := 0; n < 100_000; n++ {
+		l := padme.Length(n)
+		if l < n || float64(l-n) > 0.12*float64(n)+1 {
+			t.Fatalf("Length(%d) = %d", n, l)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	for n := 0; n < 2000; n++ {
+		plaintext := bytes.Repeat([]byte{0x80}, n)
+		padded := padme.Pad([]byte("prefix"), plaintext)
+		if !bytes.HasPrefix(padded, []byte("prefix")) {
+			t.Fatalf("Pad didn't append to dst")
+		}
+		padded = padded[len("prefix"):]
+		if len(padded) != padme.Length(n+1) {
+			t.Fatalf("Pad(%d) returned %d bytes", n, len(padded))
+		}
+		got, err := padme.Unpad(padded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("Unpad(Pad(%d)) returned %d bytes", n, len(got))
+		}
+	}
+	for _, invalid := range [][]byte{nil, {0, 0}, {0x80, 1}, {1, 0}} {
+		if _, err := padme.Unpad(invalid); err == nil {
+			t.Errorf("Unpad(%x) succeeded", invalid)
+		}
+	}
+}
@@ -0,0 +1,351 @@
+// Package plugin implements a protocol for wrapping and unwrapping data
+// encryption keys with external programs, such as smartcard drivers or custom
+// KMS clients, modeled after the age plugin protocol.
+//
+// A plugin named NAME is an executable called xaes-plugin-NAME in $PATH. It's
+// invoked with the single argument --xaes-plugin=v1, and communicates over
+// standard input and output with stanzas, each made of a header line and a
+// body line
+//
+//	-> TYPE [ARG...]
+//	<base64 body>
+//
+// where the body is encoded with unpadded standard base64, and may be empty.
+//
+// The client sends a single "wrap" or "unwrap" stanza, with the base64-encoded
+// plugin configuration as its argument, and the key or wrapped key as its
+// body. The plugin can then send any number of "msg" stanzas, to display a
+// message to the user, and "request-secret" stanzas, to ask the user for a
+// secret such as a PIN, with the message or prompt as the body. The client
+// replies to each of them with an "ok" stanza, with the secret as its body, or
+// with a "fail" stanza if the user interaction failed. Finally, the plugin
+// sends a "done" stanza with the result as its body, or an "error" stanza with
+// an error message as its body, and exits.
+//
+// Plugin authors can implement [Plugin] and call [Main].
+package plugin
+
+import (
+	"bufio"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Prefix is the prefix of the name of plugin executables.
+const Prefix = "xaes-plugin-"
+
+const versionFlag = "--xaes-plugin=v1"
+
+var b64 = base64.RawStdEncoding.Strict()
+
+// stanza is a protocol message.
+type stanza struct {
+	Type string
+	Args []string
+	Body []byte
+}
+
+// maxLineSize is the maximum size of a stanza line.
+const maxLineSize = 64 << 10
+
+func writeStanza(w io.Writer, s *stanza) error {
+	header := strings.Join(append([]string{"->", s.Type}, s.Args...), " ")
+	_, err := io.WriteString(w, header+"\n"+b64.EncodeToString(s.Body)+"\n")
+	return err
+}
+
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return "", errors.New("line too long")
+	}
+	if err == io.EOF && len(line) > 0 {
+		return "", io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(line[:len(line)-1]), nil
+}
+
+func readStanza(r *bufio.Reader) (*stanza, error) {
+	header, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	args := strings.Split(header, " ")
+	if len(args) < 2 || args[0] != "->" || args[1] == "" {
+		return nil, errors.New("malformed stanza header")
+	}
+	body, err := readLine(r)
+	if err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	s := &stanza{Type: args[1], Args: args[2:]}
+	if s.Body, err = b64.DecodeString(body); err != nil {
+		return nil, errors.New("malformed stanza body")
+	}
+	return s, nil
+}
+
+// UI is used by a [Provider] to let plugins interact with the user.
+type UI struct {
+	// DisplayMessage shows a message from the plugin name to the user.
+	DisplayMessage func(name, message string) error
+
+	// RequestSecret asks the user for a secret, such as a PIN, on behalf of the
+	// plugin name, and returns it.
+	RequestSecret func(name, prompt string) (string, error)
+}
+
+// Provider wraps and unwraps keys by invoking a plugin.
+type Provider struct {
+	name   string
+	config string
+	path   string
+	ui     *UI
+}
+
+// ValidName reports whether name is a valid plugin name. Names must be
+// non-empty, and consist of lowercase letters, digits, and '.', '_', and '-'.
+func ValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '.' || c == '_' || c == '-') {
+			return false
+		}
+	}
+	return true
+}
+
+// NewProvider looks up the plugin name in $PATH, and returns a Provider that
+// invokes it with config. config is an opaque string, such as a KMS key
+// identifier or a smartcard slot, interpreted by the plugin.
+//
+// ui may be nil, in which case plugins can't interact with the user.
+func NewProvider(name, config string, ui *UI) (*Provider, error) {
+	if !ValidName(name) {
+		return nil, fmt.Errorf("plugin: invalid plugin name %q", name)
+	}
+	path, err := exec.LookPath(Prefix + name)
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %s not found in $PATH", Prefix+name)
+	}
+	return &Provider{name: name, config: config, path: path, ui: ui}, nil
+}
+
+// Name returns the name of the plugin.
+func (p *Provider) Name() string {
+	return p.name
+}
+
+// Config returns the configuration passed to the plugin.
+func (p *Provider) Config() string {
+	return p.config
+}
+
+// WrapKey invokes the plugin to wrap dek. The format of the result is defined
+// by the plugin.
+func (p *Provider) WrapKey(dek xaes256gcm.Key) ([]byte, error) {
+	return p.run("wrap", dek.Bytes())
+}
+
+// UnwrapKey invokes the plugin to unwrap a key wrapped by [Provider.WrapKey]
+// with the same plugin and configuration.
+func (p *Provider) UnwrapKey(wrapped []byte) (xaes256gcm.Key, error) {
+	dek, err := p.run("unwrap", wrapped)
+	if err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	k, err := xaes256gcm.NewKey(dek)
+	if err != nil {
+		return xaes256gcm.Key{}, fmt.Errorf("plugin: %s returned a key of the wrong size", p.name)
+	}
+	return k, nil
+}
+
+func (p *Provider) run(command string, body []byte) ([]byte, error) {
+	cmd := exec.Command(p.path, versionFlag)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("plugin: failed to start %s: %v", p.name, err)
+	}
+	result, err := p.converse(command, body, stdin, bufio.NewReaderSize(stdout, maxLineSize))
+	stdin.Close()
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("plugin: %s failed: %v", p.name, err)
+	}
+	return result, nil
+}
+
+func (p *Provider) converse(command string, body []byte, w io.Writer, r *bufio.Reader) ([]byte, error) {
+	req := &stanza{Type: command, Args: []string{b64.EncodeToString([]byte(p.config))}, Body: body}
+	if err := writeStanza(w, req); err != nil {
+		return nil, fmt.Errorf("plugin: failed to write to %s: %v", p.name, err)
+	}
+	for {
+		s, err := readStanza(r)
+		if err != nil {
+			return nil, fmt.Errorf("plugin: failed to read from %s: %v", p.name, err)
+		}
+		var reply *stanza
+		switch s.Type {
+		case "done":
+			return s.Body, nil
+		case "error":
+			return nil, fmt.Errorf("plugin: %s: %s", p.name, s.Body)
+		case "msg":
+			reply = &stanza{Type: "fail"}
+			if p.ui != nil && p.ui.DisplayMessage != nil {
+				if err := p.ui.DisplayMessage(p.name, string(s.Body)); err == nil {
+					reply = &stanza{Type: "ok"}
+				}
+			}
+		case "request-secret":
+			reply = &stanza{Type: "fail"}
+			if p.ui != nil && p.ui.RequestSecret != nil {
+				if secret, err := p.ui.RequestSecret(p.name, string(s.Body)); err == nil {
+					reply = &stanza{Type: "ok", Body: []byte(secret)}
+				}
+			}
+		default:
+			return nil, fmt.Errorf("plugin: %s sent unknown stanza %q", p.name, s.Type)
+		}
+		if err := writeStanza(w, reply); err != nil {
+			return nil, fmt.Errorf("plugin: failed to write to %s: %v", p.name, err)
+		}
+	}
+}
+
+// Plugin is implemented by plugin programs, and passed to [Main].
+type Plugin interface {
+	// WrapKey wraps dek according to config.
+	WrapKey(config string, dek xaes256gcm.Key, c *Conn) ([]byte, error)
+
+	// UnwrapKey unwraps a key returned by WrapKey with the same config.
+	UnwrapKey(config string, wrapped []byte, c *Conn) (xaes256gcm.Key, error)
+}
+
+// Conn is the plugin side of a connection to a [Provider], used to interact
+// with the user.
+type Conn struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// errUI is returned by Conn methods when the client reports a failure.
+var errUI = errors.New("plugin: user interaction failed")
+
+func (c *Conn) roundTrip(s *stanza) ([]byte, error) {
+	if err := writeStanza(c.w, s); err != nil {
+		return nil, err
+	}
+	reply, err := readStanza(c.r)
+	if err != nil {
+		return nil, err
+	}
+	switch reply.Type {
+	case "ok":
+		return reply.Body, nil
+	case "fail":
+		return nil, errUI
+	default:
+		return nil, fmt.Errorf("plugin: unexpected stanza %q", reply.Type)
+	}
+}
+
+// DisplayMessage asks the client to show message to the user.
+func (c *Conn) DisplayMessage(message string) error {
+	_, err := c.roundTrip(&stanza{Type: "msg", Body: []byte(message)})
+	return err
+}
+
+// RequestSecret asks the client to prompt the user for a secret, such as a
+// PIN, and returns it.
+func (c *Conn) RequestSecret(prompt string) (string, error) {
+	secret, err := c.roundTrip(&stanza{Type: "request-secret", Body: []byte(prompt)})
+	return string(secret), err
+}
+
+// Serve runs the plugin side of the protocol for a single request, reading
+// from r and writing to w. Errors returned by p are sent to the client, and
+// also returned.
+func Serve(p Plugin, r io.Reader, w io.Writer) error {
+	c := &Conn{r: bufio.NewReaderSize(r, maxLineSize), w: w}
+	req, err := readStanza(c.r)
+	if err != nil {
+		return err
+	}
+	result, err := serve(p, c, req)
+	if err != nil {
+		writeStanza(w, &stanza{Type: "error", Body: []byte(err.Error())})
+		return err
+	}
+	return writeStanza(w, &stanza{Type: "done", Body: result})
+}
+
+func serve(p Plugin, c *Conn, req *stanza) ([]byte, error) {
+	if len(req.Args) != 1 {
+		return nil, errors.New("plugin: malformed request")
+	}
+	config, err := b64.DecodeString(req.Args[0])
+	if err != nil {
+		return nil, errors.New("plugin: malformed request")
+	}
+	switch req.Type {
+	case "wrap":
+		dek, err := xaes256gcm.NewKey(req.Body)
+		if err != nil {
+			return nil, errors.New("plugin: malformed request")
+		}
+		return p.WrapKey(string(config), dek, c)
+	case "unwrap":
+		dek, err := p.UnwrapKey(string(config), req.Body, c)
+		if err != nil {
+			return nil, err
+		}
+		return dek.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("plugin: unknown request %q", req.Type)
+	}
+}
+
+// Main runs p as a plugin program, on standard input and output, and exits.
+// It must be called from the main function of a program named
+// xaes-plugin-NAME.
+func Main(p Plugin) {
+	if len(os.Args) != 2 || os.Args[1] != versionFlag {
+		fmt.Fprintf(os.Stderr, "%s is an xaes plugin, and is not meant to be run directly\n", os.Args[0])
+		os.Exit(2)
+	}
+	if err := Serve(p, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", os.Args[0], err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
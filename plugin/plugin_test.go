@@ -0,0 +1,140 @@
+package plugin_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/plugin"
+)
+
+// testPlugin wraps keys with a KEK derived from the config, after asking for
+// a PIN.
+type testPlugin struct{}
+
+func (testPlugin) kek(config string, c *plugin.Conn) (xaes256gcm.Key, error) {
+	if err := c.DisplayMessage("touch the token"); err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	pin, err := c.RequestSecret("PIN: ")
+	if err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	if pin != "1234" {
+		return xaes256gcm.Key{}, errors.New("wrong PIN")
+	}
+	h := sha256.Sum256([]byte(config))
+	return xaes256gcm.NewKey(h[:])
+}
+
+func (p testPlugin) WrapKey(config string, dek xaes256gcm.Key, c *plugin.Conn) ([]byte, error) {
+	kek, err := p.kek(config, c)
+	if err != nil {
+		return nil, err
+	}
+	return xaes256gcm.WrapKey(kek, dek), nil
+}
+
+func (p testPlugin) UnwrapKey(config string, wrapped []byte, c *plugin.Conn) (xaes256gcm.Key, error) {
+	kek, err := p.kek(config, c)
+	if err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	return xaes256gcm.UnwrapKey(kek, wrapped)
+}
+
+func TestMain(m *testing.M) {
+	if strings.HasPrefix(filepath.Base(os.Args[0]), plugin.Prefix) {
+		plugin.Main(testPlugin{})
+	}
+	os.Exit(m.Run())
+}
+
+// installTestPlugin makes the test binary available as a plugin named test.
+func installTestPlugin(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := os.Symlink(exe, filepath.Join(dir, plugin.Prefix+"test")); err != nil {
+		t.Skip("symlinks not supported:", err)
+	}
+	t.Setenv("PATH", dir)
+}
+
+func TestRoundTrip(t *testing.T) {
+	installTestPlugin(t)
+	var messages []string
+	ui := &plugin.UI{
+		DisplayMessage: func(name, message string) error {
+			messages = append(messages, name+": "+message)
+			return nil
+		},
+		RequestSecret: func(name, prompt string) (string, error) {
+			return "1234", nil
+		},
+	}
+	p, err := plugin.NewProvider("test", "slot 9a", ui)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dek := xaes256gcm.GenerateKey()
+	wrapped, err := p.WrapKey(dek)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := p.UnwrapKey(wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got.Bytes(), dek.Bytes()) {
+		t.Errorf("unwrapped key mismatch")
+	}
+	if len(messages) != 2 || messages[0] != "test: touch the token" {
+		t.Errorf("unexpected messages: %q", messages)
+	}
+
+	other, err := plugin.NewProvider("test", "slot 9c", ui)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := other.UnwrapKey(wrapped); err == nil {
+		t.Errorf("unwrapped with a different config")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	installTestPlugin(t)
+	if _, err := plugin.NewProvider("missing", "", nil); err == nil {
+		t.Errorf("found a missing plugin")
+	}
+	if _, err := plugin.NewProvider("../test", "", nil); err == nil {
+		t.Errorf("accepted an invalid name")
+	}
+
+	p, err := plugin.NewProvider("test", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.WrapKey(xaes256gcm.GenerateKey()); err == nil {
+		t.Errorf("plugin succeeded without a UI")
+	}
+
+	wrongPIN := &plugin.UI{
+		DisplayMessage: func(name, message string) error { return nil },
+		RequestSecret:  func(name, prompt string) (string, error) { return "0000", nil },
+	}
+	p, err = plugin.NewProvider("test", "", wrongPIN)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.WrapKey(xaes256gcm.GenerateKey()); err == nil || !strings.Contains(err.Error(), "wrong PIN") {
+		t.Errorf("expected wrong PIN error, got %v", err)
+	}
+}
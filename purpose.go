@@ -0,0 +1,33 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// NewWithPurpose is like [New], but uses a key derived from key and purpose
+// with HKDF-SHA256. purpose must not be empty, and should name the subsystem
+// or data type the AEAD is used for, such as "backup" or "session token".
+//
+// AEADs with different purposes are cryptographically separated, even if they
+// share the same key by mistake: ciphertexts produced with one purpose don't
+// decrypt with any other, or with [New]. Unlike additional data, the purpose
+// can't be forgotten at a call site.
+func NewWithPurpose(key []byte, purpose string) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, errors.New("xaes256gcm: bad key length")
+	}
+	if purpose == "" {
+		return nil, errors.New("xaes256gcm: empty purpose")
+	}
+	derived := make([]byte, KeySize)
+	info := "filippo.io/xaes256gcm purpose\x00" + purpose
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(info)), derived); err != nil {
+		return nil, err
+	}
+	return New(derived)
+}
@@ -0,0 +1,42 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestNewWithPurpose(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	backup, err := xaes256gcm.NewWithPurpose(key, "backup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := xaes256gcm.NewWithPurpose(key, "session token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain := xaes256gcm.MustNew(key)
+
+	ciphertext := backup.Seal(nil, nil, []byte("hello"), nil)
+	if got, err := backup.Open(nil, nil, ciphertext, nil); err != nil || string(got) != "hello" {
+		t.Errorf("Open: %q, %v", got, err)
+	}
+	if _, err := session.Open(nil, nil, ciphertext, nil); err == nil {
+		t.Errorf("opened with a different purpose")
+	}
+	if _, err := plain.Open(nil, nil, ciphertext, nil); err == nil {
+		t.Errorf("opened without a purpose")
+	}
+	if _, err := backup.Open(nil, nil, plain.Seal(nil, nil, []byte("hello"), nil), nil); err == nil {
+		t.Errorf("opened a ciphertext without a purpose")
+	}
+
+	if _, err := xaes256gcm.NewWithPurpose(key, ""); err == nil {
+		t.Errorf("empty purpose accepted")
+	}
+	if _, err := xaes256gcm.NewWithPurpose(key[:16], "backup"); err == nil {
+		t.Errorf("short key accepted")
+	}
+}
@@ -0,0 +1,136 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+// RatchetOverhead is the difference between the lengths of a plaintext and its
+// ciphertext, when sealed by a [Ratchet].
+const RatchetOverhead = 8 + Overhead
+
+// Ratchet provides forward secrecy by deterministically advancing its key with
+// a one-way function, and erasing the previous key, after a number of messages
+// or on demand. If the current state is compromised, messages sealed before the
+// last advance can't be decrypted.
+//
+// Each key is used for one epoch, and the ciphertext is prefixed by the 8-byte
+// big-endian epoch number. A Ratchet created from the same initial key can open
+// ciphertexts of the current and any later epoch, by advancing to it. This
+// suits log shipping and messaging, where messages are mostly opened in order.
+// See [Session] for two-way messaging with out-of-order delivery.
+//
+// A Ratchet is safe for concurrent use.
+type Ratchet struct {
+	mu    sync.Mutex
+	chain [32]byte
+	epoch uint64
+	count uint64
+	every uint64
+	aead  cipher.AEAD
+}
+
+// NewRatchet returns a Ratchet starting at epoch zero from key. If every is not
+// zero, Seal advances to the next epoch after every messages.
+func NewRatchet(key Key, every uint64) *Ratchet {
+	return &Ratchet{chain: key.k, every: every, aead: messageKey(&key.k).AEAD()}
+}
+
+// maxRatchetSkip is the maximum number of epochs Open advances by, to bound
+// the work done for a single ciphertext.
+const maxRatchetSkip = 1 << 16
+
+// nextChain replaces chain with the chain key of the next epoch.
+func nextChain(chain *[32]byte) {
+	h := hmac.New(sha256.New, chain[:])
+	h.Write([]byte("filippo.io/xaes256gcm ratchet chain key"))
+	h.Sum(chain[:0])
+}
+
+// messageKey returns the message key for the epoch of chain.
+func messageKey(chain *[32]byte) Key {
+	var k Key
+	h := hmac.New(sha256.New, chain[:])
+	h.Write([]byte("filippo.io/xaes256gcm ratchet message key"))
+	h.Sum(k.k[:0])
+	return k
+}
+
+func (r *Ratchet) advance() {
+	nextChain(&r.chain)
+	r.epoch++
+	r.count = 0
+	r.aead = messageKey(&r.chain).AEAD()
+}
+
+// Advance moves to the next epoch, erasing the current key.
+func (r *Ratchet) Advance() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.advance()
+}
+
+// Epoch returns the current epoch.
+func (r *Ratchet) Epoch() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.epoch
+}
+
+// Seal encrypts and authenticates plaintext and additionalData with the key of
+// the current epoch, and appends the result to dst.
+func (r *Ratchet) Seal(dst, plaintext, additionalData []byte) []byte {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.every != 0 && r.count == r.every {
+		r.advance()
+	}
+	r.count++
+	dst = binary.BigEndian.AppendUint64(dst, r.epoch)
+	return r.aead.Seal(dst, nil, plaintext, epochAD(r.epoch, additionalData))
+}
+
+// Open decrypts and authenticates a ciphertext produced by Seal, and appends
+// the plaintext to dst. If the ciphertext is from a later epoch, the Ratchet
+// advances to it, and ciphertexts from earlier epochs can't be opened anymore.
+// The Ratchet only advances if the ciphertext is authentic, and by at most 2¹⁶
+// epochs at a time.
+func (r *Ratchet) Open(dst, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < RatchetOverhead {
+		return nil, errOpen
+	}
+	epoch := binary.BigEndian.Uint64(ciphertext)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if epoch < r.epoch {
+		return nil, errors.New("xaes256gcm: ciphertext from an erased ratchet epoch")
+	}
+	if epoch-r.epoch > maxRatchetSkip {
+		return nil, errors.New("xaes256gcm: ciphertext from a too distant ratchet epoch")
+	}
+	aead, chain := r.aead, r.chain
+	for e := r.epoch; e < epoch; e++ {
+		nextChain(&chain)
+	}
+	if epoch != r.epoch {
+		aead = messageKey(&chain).AEAD()
+	}
+	out, err := aead.Open(dst, nil, ciphertext[8:], epochAD(epoch, additionalData))
+	if err != nil {
+		return nil, err
+	}
+	if epoch != r.epoch {
+		r.chain, r.epoch, r.count, r.aead = chain, epoch, 0, aead
+	}
+	return out, nil
+}
+
+func epochAD(epoch uint64, additionalData []byte) []byte {
+	ad := make([]byte, 0, 8+len(additionalData))
+	ad = binary.BigEndian.AppendUint64(ad, epoch)
+	return append(ad, additionalData...)
+}
@@ -0,0 +1,64 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestRatchet(t *testing.T) {
+	key := xaes256gcm.MustKey(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	sender := xaes256gcm.NewRatchet(key, 2)
+	receiver := xaes256gcm.NewRatchet(key, 0)
+
+	var ciphertexts [][]byte
+	for i := 0; i < 6; i++ {
+		ciphertexts = append(ciphertexts, sender.Seal(nil, []byte{byte(i)}, []byte("ad")))
+	}
+	if sender.Epoch() != 2 {
+		t.Errorf("Epoch = %d, expected 2", sender.Epoch())
+	}
+	if len(ciphertexts[0]) != 1+xaes256gcm.RatchetOverhead {
+		t.Errorf("unexpected ciphertext length %d", len(ciphertexts[0]))
+	}
+
+	if got, err := receiver.Open(nil, ciphertexts[0], []byte("ad")); err != nil || !bytes.Equal(got, []byte{0}) {
+		t.Fatalf("Open: %x, %v", got, err)
+	}
+	// Skip to epoch 1.
+	if got, err := receiver.Open(nil, ciphertexts[3], []byte("ad")); err != nil || !bytes.Equal(got, []byte{3}) {
+		t.Fatalf("Open: %x, %v", got, err)
+	}
+	if receiver.Epoch() != 1 {
+		t.Errorf("Epoch = %d, expected 1", receiver.Epoch())
+	}
+	if got, err := receiver.Open(nil, ciphertexts[2], []byte("ad")); err != nil || !bytes.Equal(got, []byte{2}) {
+		t.Fatalf("Open: %x, %v", got, err)
+	}
+	if _, err := receiver.Open(nil, ciphertexts[1], []byte("ad")); err == nil {
+		t.Errorf("ciphertext from erased epoch opened")
+	}
+
+	// A forged ciphertext from a later epoch doesn't advance the receiver.
+	forged := append([]byte{}, ciphertexts[5]...)
+	forged[len(forged)-1] ^= 1
+	if _, err := receiver.Open(nil, forged, []byte("ad")); err == nil {
+		t.Errorf("forged ciphertext opened")
+	}
+	if receiver.Epoch() != 1 {
+		t.Errorf("forged ciphertext advanced the ratchet")
+	}
+	// The epoch is authenticated.
+	moved := append([]byte{}, ciphertexts[3]...)
+	moved[7] = 2
+	if _, err := receiver.Open(nil, moved, []byte("ad")); err == nil {
+		t.Errorf("ciphertext opened with modified epoch")
+	}
+
+	receiver.Advance()
+	receiver.Advance()
+	if _, err := receiver.Open(nil, ciphertexts[5], []byte("ad")); err == nil {
+		t.Errorf("ciphertext opened after advancing past its epoch")
+	}
+}
@@ -0,0 +1,262 @@
+// Package remote forwards XAES-256-GCM Seal and Open operations to a service
+// that holds the key, so that application processes never possess the key
+// material, for example a sidecar listening on a Unix socket.
+//
+// [Handler] serves the protocol with a key, and [Client] sends requests to it.
+// Each request is a POST with a JSON body carrying a batch of operations, and
+// the response carries one result per operation, in order. Binary values are
+// base64-encoded, as by [encoding/json].
+//
+// The client sends the remaining time before its context deadline in the
+// Xaes-Timeout header, and the server stops processing the batch once it
+// expires, so a slow or overloaded service doesn't do work no one is waiting
+// for anymore.
+//
+// The service uses random nonces, like [filippo.io/xaes256gcm.New], so clients
+// can't cause nonce reuse. The protocol provides no authentication or
+// confidentiality: the handler must only be reachable over a channel that
+// provides both, such as a Unix socket with restrictive permissions or mutual
+// TLS. Anyone who can reach it can encrypt and decrypt with the key.
+package remote
+
+import (
+	"bytes"
+	"context"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"filippo.io/xaes256gcm"
+)
+
+const (
+	// TimeoutHeader is the header that carries the client's remaining time
+	// budget, formatted by [time.Duration.String].
+	TimeoutHeader = "Xaes-Timeout"
+
+	// MaxRequestSize is the maximum size of a request body accepted by
+	// [Handler].
+	MaxRequestSize = 64 << 20
+)
+
+// Op is a single Seal or Open operation.
+type Op struct {
+	// Open is true for Open operations, and false for Seal operations.
+	Open bool `json:"open,omitempty"`
+
+	// Data is the plaintext for Seal, or the ciphertext for Open.
+	Data []byte `json:"data"`
+
+	AdditionalData []byte `json:"ad,omitempty"`
+}
+
+// Result is the result of an Op.
+type Result struct {
+	// Data is the ciphertext for Seal, or the plaintext for Open.
+	Data []byte
+
+	// Err is set if the operation failed, for example because the ciphertext
+	// failed to authenticate.
+	Err error
+}
+
+type request struct {
+	Ops []Op `json:"ops"`
+}
+
+type result struct {
+	Data  []byte `json:"data,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+type response struct {
+	Results []result `json:"results"`
+}
+
+// Handler returns an HTTP handler that performs the operations sent by a
+// [Client] with key.
+func Handler(key xaes256gcm.Key) http.Handler {
+	aead := key.AEAD()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "remote: method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		ctx := r.Context()
+		if t := r.Header.Get(TimeoutHeader); t != "" {
+			d, err := time.ParseDuration(t)
+			if err != nil {
+				http.Error(w, "remote: malformed timeout", http.StatusBadRequest)
+				return
+			}
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, d)
+			defer cancel()
+		}
+
+		var req request
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, MaxRequestSize)).Decode(&req); err != nil {
+			http.Error(w, "remote: malformed request", http.StatusBadRequest)
+			return
+		}
+
+		resp := response{Results: make([]result, 0, len(req.Ops))}
+		for _, op := range req.Ops {
+			if ctx.Err() != nil {
+				http.Error(w, "remote: deadline exceeded", http.StatusGatewayTimeout)
+				return
+			}
+			if !op.Open {
+				resp.Results = append(resp.Results, result{Data: aead.Seal(nil, nil, op.Data, op.AdditionalData)})
+				continue
+			}
+			plaintext, err := aead.Open(nil, nil, op.Data, op.AdditionalData)
+			if err != nil {
+				resp.Results = append(resp.Results, result{Error: err.Error()})
+				continue
+			}
+			resp.Results = append(resp.Results, result{Data: plaintext})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// Client sends operations to a [Handler].
+type Client struct {
+	url    string
+	client *http.Client
+}
+
+// NewClient returns a Client that sends requests to url with client. If client
+// is nil, [http.DefaultClient] is used.
+func NewClient(url string, client *http.Client) *Client {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &Client{url: url, client: client}
+}
+
+// Do sends a batch of operations in a single request, and returns their
+// results in order. It returns an error only if the request as a whole failed,
+// in which case none of the results are available. Failures of individual
+// operations are reported in [Result.Err].
+func (c *Client) Do(ctx context.Context, ops []Op) ([]Result, error) {
+	body, err := json.Marshal(request{Ops: ops})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if deadline, ok := ctx.Deadline(); ok {
+		d := time.Until(deadline)
+		if d <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		req.Header.Set(TimeoutHeader, d.String())
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("remote: server returned %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	var r response
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return nil, fmt.Errorf("remote: malformed response: %v", err)
+	}
+	if len(r.Results) != len(ops) {
+		return nil, errors.New("remote: wrong number of results in response")
+	}
+	results := make([]Result, len(ops))
+	for i, res := range r.Results {
+		results[i].Data = res.Data
+		if res.Error != "" {
+			results[i].Err = errors.New(res.Error)
+		}
+	}
+	return results, nil
+}
+
+func (c *Client) do(ctx context.Context, op Op) ([]byte, error) {
+	results, err := c.Do(ctx, []Op{op})
+	if err != nil {
+		return nil, err
+	}
+	return results[0].Data, results[0].Err
+}
+
+// Seal encrypts and authenticates plaintext and additionalData with the
+// service's key, and returns the ciphertext, including the random nonce.
+func (c *Client) Seal(ctx context.Context, plaintext, additionalData []byte) ([]byte, error) {
+	return c.do(ctx, Op{Data: plaintext, AdditionalData: additionalData})
+}
+
+// Open decrypts and authenticates ciphertext and additionalData with the
+// service's key, and returns the plaintext.
+func (c *Client) Open(ctx context.Context, ciphertext, additionalData []byte) ([]byte, error) {
+	return c.do(ctx, Op{Open: true, Data: ciphertext, AdditionalData: additionalData})
+}
+
+// AEAD returns a [cipher.AEAD] that forwards each call to the service, with a
+// deadline of timeout. It behaves like the AEAD returned by
+// [filippo.io/xaes256gcm.New]: the nonce must be empty, and Overhead returns
+// [filippo.io/xaes256gcm.Overhead].
+//
+// Since Seal can't return an error, it panics if the request fails.
+// Applications that need to handle service failures should use
+// [Client.Seal] instead.
+func (c *Client) AEAD(timeout time.Duration) cipher.AEAD {
+	return &remoteAEAD{c: c, timeout: timeout}
+}
+
+type remoteAEAD struct {
+	c       *Client
+	timeout time.Duration
+}
+
+func (*remoteAEAD) NonceSize() int {
+	return 0
+}
+
+func (*remoteAEAD) Overhead() int {
+	return xaes256gcm.Overhead
+}
+
+func (a *remoteAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != 0 {
+		panic("remote: non-empty nonce passed to Seal")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	ciphertext, err := a.c.Seal(ctx, plaintext, additionalData)
+	if err != nil {
+		panic("remote: Seal failed: " + err.Error())
+	}
+	return append(dst, ciphertext...)
+}
+
+func (a *remoteAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != 0 {
+		return nil, errors.New("remote: non-empty nonce passed to Open")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), a.timeout)
+	defer cancel()
+	plaintext, err := a.c.Open(ctx, ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	return append(dst, plaintext...), nil
+}
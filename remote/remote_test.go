@@ -0,0 +1,109 @@
+package remote_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/remote"
+)
+
+func TestRoundTrip(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	srv := httptest.NewServer(remote.Handler(key))
+	defer srv.Close()
+	c := remote.NewClient(srv.URL, srv.Client())
+
+	aead := c.AEAD(time.Minute)
+	ciphertext := aead.Seal([]byte("prefix"), nil, []byte("hello"), []byte("ad"))
+	if string(ciphertext[:6]) != "prefix" {
+		t.Errorf("dst not preserved")
+	}
+	ciphertext = ciphertext[6:]
+	if len(ciphertext) != len("hello")+aead.Overhead() {
+		t.Errorf("unexpected ciphertext length %d", len(ciphertext))
+	}
+	if p, err := key.AEAD().Open(nil, nil, ciphertext, []byte("ad")); err != nil {
+		t.Fatal(err)
+	} else if string(p) != "hello" {
+		t.Errorf("got %q", p)
+	}
+	if p, err := aead.Open(nil, nil, ciphertext, []byte("ad")); err != nil {
+		t.Fatal(err)
+	} else if string(p) != "hello" {
+		t.Errorf("got %q", p)
+	}
+	if _, err := aead.Open(nil, nil, ciphertext, []byte("wrong")); err == nil {
+		t.Errorf("wrong additional data accepted")
+	}
+}
+
+func TestBatch(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	srv := httptest.NewServer(remote.Handler(key))
+	defer srv.Close()
+	c := remote.NewClient(srv.URL, srv.Client())
+
+	good := key.AEAD().Seal(nil, nil, []byte("good"), nil)
+	results, err := c.Do(context.Background(), []remote.Op{
+		{Data: []byte("one")},
+		{Open: true, Data: good},
+		{Open: true, Data: []byte("garbage")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results", len(results))
+	}
+	if results[0].Err != nil || len(results[0].Data) != len("one")+xaes256gcm.Overhead {
+		t.Errorf("unexpected Seal result: %v", results[0].Err)
+	}
+	if results[1].Err != nil || string(results[1].Data) != "good" {
+		t.Errorf("unexpected Open result: %q, %v", results[1].Data, results[1].Err)
+	}
+	if results[2].Err == nil {
+		t.Errorf("garbage ciphertext opened")
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	var timeout string
+	h := remote.Handler(xaes256gcm.GenerateKey())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		timeout = r.Header.Get(remote.TimeoutHeader)
+		h.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	c := remote.NewClient(srv.URL, srv.Client())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if _, err := c.Seal(ctx, []byte("hello"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := time.ParseDuration(timeout); err != nil || d <= 0 || d > time.Minute {
+		t.Errorf("unexpected timeout header %q", timeout)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader(`{"ops":[{"data":""}]}`))
+	req.Header.Set(remote.TimeoutHeader, "1ns")
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Errorf("expired request succeeded")
+	}
+
+	ctx, cancel = context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+	if _, err := c.Seal(ctx, []byte("hello"), nil); err == nil {
+		t.Errorf("expired context succeeded")
+	}
+}
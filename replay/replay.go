@@ -0,0 +1,133 @@
+// Package replay implements anti-replay protection for XAES-256-GCM messages
+// that carry a sequence number in their nonce, such as datagrams or messages
+// delivered by a message bus, which might be duplicated, reordered, or
+// replayed by the network.
+package replay
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"filippo.io/xaes256gcm"
+)
+
+// WindowSize is the number of sequence numbers, up to and including the
+// highest accepted one, that a [Window] keeps track of. Older messages are
+// rejected.
+const WindowSize = 1024
+
+const blocks = WindowSize/64 + 1
+
+// Window is a sliding window of recently accepted sequence numbers, based on
+// the bitmap ring of RFC 6479.
+//
+// The zero value is an empty window. A Window is not safe for concurrent use.
+type Window struct {
+	top  uint64 // one more than the highest accepted sequence number
+	ring [blocks]uint64
+}
+
+// Check reports whether seq is neither a replay nor too old to tell.
+//
+// Check doesn't modify the window: after the message is authenticated, the
+// caller must call [Window.Accept].
+func (w *Window) Check(seq uint64) bool {
+	if seq == 1<<64-1 {
+		return false
+	}
+	if seq >= w.top {
+		return true
+	}
+	if w.top-seq > WindowSize {
+		return false
+	}
+	return w.ring[seq/64%blocks]&(1<<(seq%64)) == 0
+}
+
+// Accept records seq as seen, sliding the window forward if necessary. seq
+// must have been checked with [Window.Check].
+func (w *Window) Accept(seq uint64) {
+	if seq >= w.top {
+		var from uint64
+		if w.top > 0 {
+			from = (w.top-1)/64 + 1
+		}
+		to := seq / 64
+		if to >= from && to-from >= blocks {
+			w.ring = [blocks]uint64{}
+		} else {
+			for b := from; b <= to; b++ {
+				w.ring[b%blocks] = 0
+			}
+		}
+		w.top = seq + 1
+	}
+	w.ring[seq/64%blocks] |= 1 << (seq % 64)
+}
+
+// ErrReplay is returned by [Opener.Open] for messages that were already
+// opened, or that are too old to tell.
+var ErrReplay = errors.New("replay: message replayed or too old")
+
+// Opener wraps an AEAD returned by [xaes256gcm.NewWithManualNonces], and
+// rejects messages whose sequence number was already accepted.
+//
+// The sequence number is the big-endian uint64 in the last 8 bytes of the
+// nonce. The sender must never reuse a sequence number with the same key, and
+// should send them in a mostly increasing order.
+//
+// An Opener is safe for concurrent use.
+type Opener struct {
+	aead cipher.AEAD
+
+	mu sync.Mutex
+	w  Window
+}
+
+// NewOpener returns a new Opener for aead, which must use 24-byte nonces.
+func NewOpener(aead cipher.AEAD) *Opener {
+	if aead.NonceSize() != xaes256gcm.NonceSize {
+		panic("replay: AEAD doesn't use manual nonces")
+	}
+	return &Opener{aead: aead}
+}
+
+// Nonce returns a 24-byte nonce carrying the sequence number seq.
+func Nonce(seq uint64) []byte {
+	nonce := make([]byte, xaes256gcm.NonceSize)
+	binary.BigEndian.PutUint64(nonce[xaes256gcm.NonceSize-8:], seq)
+	return nonce
+}
+
+// Open works like [cipher.AEAD.Open], but returns [ErrReplay] without
+// attempting decryption if the sequence number of nonce was already accepted,
+// and only accepts it if decryption succeeds.
+func (o *Opener) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(nonce) != xaes256gcm.NonceSize {
+		return nil, errors.New("replay: bad nonce length")
+	}
+	seq := binary.BigEndian.Uint64(nonce[xaes256gcm.NonceSize-8:])
+
+	o.mu.Lock()
+	ok := o.w.Check(seq)
+	o.mu.Unlock()
+	if !ok {
+		return nil, ErrReplay
+	}
+
+	plaintext, err := o.aead.Open(dst, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check again, in case a concurrent Open accepted the same message.
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.w.Check(seq) {
+		return nil, ErrReplay
+	}
+	o.w.Accept(seq)
+	return plaintext, nil
+}
@@ -0,0 +1,64 @@
+package replay_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/replay"
+)
+
+func TestWindow(t *testing.T) {
+	var w replay.Window
+	accept := func(seq uint64, ok bool) {
+		t.Helper()
+		if got := w.Check(seq); got != ok {
+			t.Fatalf("Check(%d) = %v, want %v", seq, got, ok)
+		}
+		if ok {
+			w.Accept(seq)
+		}
+	}
+	accept(0, true)
+	accept(0, false)
+	accept(2, true)
+	accept(1, true)
+	accept(1, false)
+	accept(2, false)
+	accept(replay.WindowSize+2, true)
+	accept(2, false)
+	accept(3, true)
+	accept(3, false)
+	accept(100_000, true)
+	accept(100_000-replay.WindowSize+1, true)
+	accept(100_000-replay.WindowSize, false)
+	accept(99_999, true)
+	accept(1<<64-1, false)
+}
+
+func TestOpener(t *testing.T) {
+	aead, err := xaes256gcm.NewWithManualNonces(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	o := replay.NewOpener(aead)
+
+	ciphertext := aead.Seal(nil, replay.Nonce(42), []byte("hello"), nil)
+	if _, err := o.Open(nil, replay.Nonce(41), ciphertext, nil); err == nil || errors.Is(err, replay.ErrReplay) {
+		t.Errorf("expected authentication failure, got %v", err)
+	}
+	if p, err := o.Open(nil, replay.Nonce(42), ciphertext, nil); err != nil {
+		t.Fatal(err)
+	} else if string(p) != "hello" {
+		t.Errorf("got %q", p)
+	}
+	if _, err := o.Open(nil, replay.Nonce(42), ciphertext, nil); !errors.Is(err, replay.ErrReplay) {
+		t.Errorf("expected ErrReplay, got %v", err)
+	}
+	// A failed authentication doesn't consume the sequence number.
+	ciphertext = aead.Seal(nil, replay.Nonce(41), []byte("hello"), nil)
+	if _, err := o.Open(nil, replay.Nonce(41), ciphertext, nil); err != nil {
+		t.Fatal(err)
+	}
+}
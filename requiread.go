@@ -0,0 +1,34 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// WithRequiredAD returns an AEAD that wraps aead, and rejects empty additional
+// data: Seal panics, and Open returns an error without decrypting.
+//
+// It's meant for codebases that require every ciphertext to be bound to its
+// context, such as a database row or a protocol message type, and want the
+// library to enforce it rather than code review.
+func WithRequiredAD(aead cipher.AEAD) cipher.AEAD {
+	return &requiredADAEAD{aead}
+}
+
+type requiredADAEAD struct {
+	cipher.AEAD
+}
+
+func (a *requiredADAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(additionalData) == 0 {
+		panic("xaes256gcm: empty additional data passed to Seal")
+	}
+	return a.AEAD.Seal(dst, nonce, plaintext, additionalData)
+}
+
+func (a *requiredADAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(additionalData) == 0 {
+		return nil, errors.New("xaes256gcm: empty additional data passed to Open")
+	}
+	return a.AEAD.Open(dst, nonce, ciphertext, additionalData)
+}
@@ -0,0 +1,26 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestRequiredAD(t *testing.T) {
+	inner := xaes256gcm.MustNew(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	c := xaes256gcm.WithRequiredAD(inner)
+	ciphertext := c.Seal(nil, nil, []byte("hello"), []byte("users.email:42"))
+	if got, err := c.Open(nil, nil, ciphertext, []byte("users.email:42")); err != nil || string(got) != "hello" {
+		t.Fatalf("Open: %q, %v", got, err)
+	}
+	if _, err := c.Open(nil, nil, inner.Seal(nil, nil, []byte("hello"), nil), nil); err == nil {
+		t.Errorf("Open accepted empty additional data")
+	}
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Seal didn't panic on empty additional data")
+		}
+	}()
+	c.Seal(nil, nil, []byte("hello"), []byte{})
+}
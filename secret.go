@@ -0,0 +1,67 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"runtime"
+	"strconv"
+)
+
+// Secret is a decrypted plaintext that can be explicitly wiped from memory,
+// returned by [OpenSecret]. It's meant for short-lived credentials, such as
+// passwords and tokens, whose plaintext should stay in memory as briefly as
+// possible.
+//
+// The contents are zeroed by Wipe, or when the Secret is garbage collected.
+// Since the contents may be zeroed as soon as the Secret is unreachable, the
+// Secret must be kept alive, for example with [runtime.KeepAlive], for as long
+// as the slice returned by Bytes is in use.
+//
+// Wiping is best effort: copies made by the application, or by the AEAD
+// implementation internally, are not zeroed. Secret doesn't expose its contents
+// when formatted.
+type Secret struct {
+	b []byte
+}
+
+// OpenSecret decrypts and authenticates ciphertext with aead, like aead.Open,
+// and returns the plaintext as a Secret. The plaintext is decrypted directly
+// into a buffer owned by the Secret, so no other copies are left behind.
+func OpenSecret(aead cipher.AEAD, nonce, ciphertext, additionalData []byte) (*Secret, error) {
+	buf := make([]byte, 0, len(ciphertext))
+	plaintext, err := aead.Open(buf, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	s := &Secret{b: plaintext}
+	runtime.SetFinalizer(s, (*Secret).Wipe)
+	return s, nil
+}
+
+// Bytes returns the contents of the Secret, without copying them. It returns
+// nil after Wipe.
+func (s *Secret) Bytes() []byte {
+	return s.b
+}
+
+// Len returns the length of the contents, or zero after Wipe.
+func (s *Secret) Len() int {
+	return len(s.b)
+}
+
+// Wipe zeroes the contents of the Secret. It's safe to call multiple times.
+func (s *Secret) Wipe() {
+	clear(s.b[:cap(s.b)])
+	s.b = nil
+	runtime.SetFinalizer(s, nil)
+}
+
+// String returns a representation of the Secret that includes its length, but
+// not its contents.
+func (s *Secret) String() string {
+	return "xaes256gcm.Secret(" + strconv.Itoa(s.Len()) + " bytes)"
+}
+
+// GoString is like String, so that %#v doesn't print the contents.
+func (s *Secret) GoString() string {
+	return s.String()
+}
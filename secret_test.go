@@ -0,0 +1,43 @@
+package xaes256gcm_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestOpenSecret(t *testing.T) {
+	a := xaes256gcm.GenerateKey().AEAD()
+	ciphertext := a.Seal(nil, nil, []byte("hunter2"), []byte("ad"))
+
+	s, err := xaes256gcm.OpenSecret(a, nil, ciphertext, []byte("ad"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(s.Bytes()) != "hunter2" || s.Len() != 7 {
+		t.Errorf("got %q", s.Bytes())
+	}
+	for _, format := range []string{"%v", "%s", "%#v", "%+v"} {
+		if out := fmt.Sprintf(format, s); strings.Contains(out, "hunter2") {
+			t.Errorf("%s: %q", format, out)
+		}
+	}
+
+	b := s.Bytes()
+	s.Wipe()
+	if s.Bytes() != nil || s.Len() != 0 {
+		t.Errorf("Bytes() = %q after Wipe", s.Bytes())
+	}
+	for _, c := range b {
+		if c != 0 {
+			t.Fatalf("contents not zeroed: %q", b)
+		}
+	}
+	s.Wipe()
+
+	if _, err := xaes256gcm.OpenSecret(a, nil, ciphertext, nil); err == nil {
+		t.Errorf("opened with the wrong additional data")
+	}
+}
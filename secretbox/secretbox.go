@@ -0,0 +1,47 @@
+// Package secretbox is a drop-in replacement for the API of
+// [golang.org/x/crypto/nacl/secretbox] that uses XAES-256-GCM, so that code
+// migrating to it only needs to change an import path.
+//
+// Like secretbox, XAES-256-GCM takes a 32-byte key and a 24-byte nonce, and
+// random nonces are safe to use for a practically unlimited number of
+// messages, so existing nonce generation and storage can be kept as is.
+//
+// Boxes are not compatible with those produced by nacl/secretbox, so existing
+// data must be decrypted with the old package and re-encrypted with this one.
+// The authentication tag is appended to the ciphertext, rather than prepended.
+package secretbox
+
+import (
+	"crypto/cipher"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Overhead is the number of bytes of overhead when boxing a message.
+const Overhead = xaes256gcm.OverheadWithManualNonces
+
+// Seal appends an encrypted and authenticated copy of message to out, which
+// must not overlap message. The key and nonce pair must be unique for each
+// distinct message and the output will be Overhead bytes longer than message.
+func Seal(out, message []byte, nonce *[24]byte, key *[32]byte) []byte {
+	return aead(key).Seal(out, nonce[:], message, nil)
+}
+
+// Open authenticates and decrypts a box produced by Seal and appends the
+// message to out, which must not overlap box. The output will be Overhead
+// bytes smaller than box.
+func Open(out, box []byte, nonce *[24]byte, key *[32]byte) ([]byte, bool) {
+	message, err := aead(key).Open(out, nonce[:], box, nil)
+	if err != nil {
+		return nil, false
+	}
+	return message, true
+}
+
+func aead(key *[32]byte) cipher.AEAD {
+	a, err := xaes256gcm.NewWithManualNonces(key[:])
+	if err != nil {
+		panic("secretbox: internal error: " + err.Error())
+	}
+	return a
+}
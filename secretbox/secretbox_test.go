@@ -0,0 +1,48 @@
+package secretbox_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/secretbox"
+)
+
+func TestSealOpen(t *testing.T) {
+	var key [32]byte
+	var nonce [24]byte
+	rand.Read(key[:])
+	rand.Read(nonce[:])
+
+	box := secretbox.Seal([]byte("prefix"), []byte("hello"), &nonce, &key)
+	if !bytes.HasPrefix(box, []byte("prefix")) {
+		t.Errorf("Seal didn't append to out")
+	}
+	box = box[len("prefix"):]
+	if len(box) != len("hello")+secretbox.Overhead {
+		t.Errorf("unexpected box length %d", len(box))
+	}
+
+	// Boxes are plain XAES-256-GCM ciphertexts.
+	aead, err := xaes256gcm.NewWithManualNonces(key[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := aead.Open(nil, nonce[:], box, nil); err != nil || string(got) != "hello" {
+		t.Errorf("XAES-256-GCM Open: %q, %v", got, err)
+	}
+
+	if got, ok := secretbox.Open(nil, box, &nonce, &key); !ok || string(got) != "hello" {
+		t.Errorf("Open: %q, %v", got, ok)
+	}
+	box[0] ^= 1
+	if _, ok := secretbox.Open(nil, box, &nonce, &key); ok {
+		t.Errorf("tampered box opened")
+	}
+	box[0] ^= 1
+	nonce[0] ^= 1
+	if _, ok := secretbox.Open(nil, box, &nonce, &key); ok {
+		t.Errorf("box opened with the wrong nonce")
+	}
+}
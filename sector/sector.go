@@ -0,0 +1,67 @@
+// Package sector implements sector-addressed encryption for block devices and
+// fixed-size record stores built on XAES-256-GCM.
+//
+// Each sector is stored as 16 random bytes followed by the XAES-256-GCM
+// ciphertext of its contents. The 24-byte nonce is the big-endian sector
+// number followed by the 16 random bytes, and the sector number is also
+// authenticated as additional data, so a sector moved to a different position
+// fails to decrypt.
+//
+// The random bytes are regenerated every time a sector is written, since
+// reusing a nonce with different contents would be catastrophic. As with any
+// such scheme, an attacker with write access to the storage can still roll a
+// sector back to a previous version undetected.
+package sector
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Overhead is the difference between the size of a stored sector and the size
+// of its contents.
+const Overhead = randomSize + xaes256gcm.OverheadWithManualNonces
+
+const randomSize = 16
+
+// Cipher encrypts and decrypts sectors. It is safe for concurrent use.
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+// New returns a new Cipher for the 32-byte key.
+func New(key []byte) (*Cipher, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+// EncryptAt encrypts the contents of sector, and appends the result, which
+// is [Overhead] bytes longer than plaintext, to dst.
+func (c *Cipher) EncryptAt(dst []byte, sector uint64, plaintext []byte) []byte {
+	var nonce [xaes256gcm.NonceSize]byte
+	binary.BigEndian.PutUint64(nonce[:8], sector)
+	if _, err := rand.Read(nonce[8:]); err != nil {
+		panic("sector: failed to generate random nonce: " + err.Error())
+	}
+	dst = append(dst, nonce[8:]...)
+	return c.aead.Seal(dst, nonce[:], plaintext, nonce[:8])
+}
+
+// DecryptAt decrypts and authenticates the stored contents of sector, and
+// appends the result to dst.
+func (c *Cipher) DecryptAt(dst []byte, sector uint64, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < Overhead {
+		return nil, errors.New("sector: ciphertext too short")
+	}
+	var nonce [xaes256gcm.NonceSize]byte
+	binary.BigEndian.PutUint64(nonce[:8], sector)
+	copy(nonce[8:], ciphertext[:randomSize])
+	return c.aead.Open(dst, nonce[:], ciphertext[randomSize:], nonce[:8])
+}
@@ -0,0 +1,35 @@
+package sector_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/sector"
+)
+
+func TestSector(t *testing.T) {
+	c, err := sector.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext := bytes.Repeat([]byte{0xaa}, 4096-sector.Overhead)
+	s1 := c.EncryptAt(nil, 1, plaintext)
+	if len(s1) != 4096 {
+		t.Errorf("stored sector is %d bytes", len(s1))
+	}
+	if again := c.EncryptAt(nil, 1, plaintext); bytes.Equal(again, s1) {
+		t.Errorf("rewriting a sector reused the nonce")
+	}
+	if got, err := c.DecryptAt(nil, 1, s1); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, plaintext) {
+		t.Errorf("plaintext and decrypted are not equal")
+	}
+	if _, err := c.DecryptAt(nil, 2, s1); err == nil {
+		t.Errorf("sector decrypted at the wrong position")
+	}
+	if _, err := c.DecryptAt(nil, 1, s1[:sector.Overhead-1]); err == nil {
+		t.Errorf("short sector decrypted")
+	}
+}
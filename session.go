@@ -0,0 +1,155 @@
+package xaes256gcm
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// SessionOverhead is the difference between the lengths of a plaintext and its
+// ciphertext, when sealed by a [Session].
+const SessionOverhead = 8 + OverheadWithManualNonces
+
+// MaxSessionSkip is the maximum number of messages a [Session] can receive out
+// of order, or miss, and still open later messages.
+const MaxSessionSkip = 1000
+
+// Session is the symmetric layer of a two-party secure messaging protocol.
+//
+// The two parties derive independent sending and receiving chains from a
+// shared root key, for example the output of a key exchange. Each message is
+// encrypted with a new key, derived from the chain like in a [Ratchet], which
+// is then erased, providing forward secrecy for each message.
+//
+// Messages are prefixed by their 8-byte big-endian sequence number, which is
+// used as the nonce and authenticated as additional data. Messages can be
+// opened out of order: the keys of messages that were skipped are kept, up to
+// [MaxSessionSkip], until they are used. Each message can be opened only once.
+//
+// A Session is safe for concurrent use.
+type Session struct {
+	mu       sync.Mutex
+	send     [32]byte
+	sendSeq  uint64
+	recv     [32]byte
+	recvSeq  uint64
+	skipped  map[uint64]Key
+	skippedQ []uint64 // in insertion order, for eviction
+}
+
+// NewSession returns a Session derived from root. The two parties must pass
+// the same root, and opposite values of initiator.
+func NewSession(root Key, initiator bool) *Session {
+	s := &Session{skipped: make(map[uint64]Key)}
+	a, b := &s.send, &s.recv
+	if !initiator {
+		a, b = b, a
+	}
+	r := hkdf.New(sha256.New, root.k[:], nil, []byte("filippo.io/xaes256gcm session"))
+	if _, err := io.ReadFull(r, a[:]); err != nil {
+		panic("xaes256gcm: internal error: " + err.Error())
+	}
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		panic("xaes256gcm: internal error: " + err.Error())
+	}
+	return s
+}
+
+func sessionAEAD(key Key, seq uint64, additionalData []byte) (aead *xaes256gcm, nonce, ad []byte) {
+	a, err := NewWithManualNonces(key.k[:])
+	if err != nil {
+		panic("xaes256gcm: internal error: " + err.Error())
+	}
+	nonce = make([]byte, NonceSize)
+	binary.BigEndian.PutUint64(nonce[NonceSize-8:], seq)
+	ad = make([]byte, 0, 8+len(additionalData))
+	ad = binary.BigEndian.AppendUint64(ad, seq)
+	ad = append(ad, additionalData...)
+	return a.(*xaes256gcm), nonce, ad
+}
+
+// Seal encrypts and authenticates plaintext and additionalData with the next
+// key of the sending chain, and appends the result to dst.
+func (s *Session) Seal(dst, plaintext, additionalData []byte) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.sendSeq == 1<<64-1 {
+		panic("xaes256gcm: session sequence number exhausted")
+	}
+	seq := s.sendSeq
+	key := messageKey(&s.send)
+	nextChain(&s.send)
+	s.sendSeq++
+
+	aead, nonce, ad := sessionAEAD(key, seq, additionalData)
+	dst = binary.BigEndian.AppendUint64(dst, seq)
+	return aead.Seal(dst, nonce, plaintext, ad)
+}
+
+// Open decrypts and authenticates a message sealed by the other party, and
+// appends the plaintext to dst. The Session state only changes if the message
+// is authentic.
+func (s *Session) Open(dst, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < SessionOverhead {
+		return nil, errOpen
+	}
+	seq := binary.BigEndian.Uint64(ciphertext)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if seq < s.recvSeq {
+		key, ok := s.skipped[seq]
+		if !ok {
+			return nil, errors.New("xaes256gcm: session message already opened or too old")
+		}
+		aead, nonce, ad := sessionAEAD(key, seq, additionalData)
+		out, err := aead.Open(dst, nonce, ciphertext[8:], ad)
+		if err != nil {
+			return nil, err
+		}
+		delete(s.skipped, seq)
+		return out, nil
+	}
+
+	if seq-s.recvSeq > MaxSessionSkip {
+		return nil, errors.New("xaes256gcm: too many skipped session messages")
+	}
+	chain := s.recv
+	var skipped []Key
+	for i := s.recvSeq; i < seq; i++ {
+		skipped = append(skipped, messageKey(&chain))
+		nextChain(&chain)
+	}
+	key := messageKey(&chain)
+	nextChain(&chain)
+	aead, nonce, ad := sessionAEAD(key, seq, additionalData)
+	out, err := aead.Open(dst, nonce, ciphertext[8:], ad)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, k := range skipped {
+		s.skipped[s.recvSeq+uint64(i)] = k
+		s.skippedQ = append(s.skippedQ, s.recvSeq+uint64(i))
+	}
+	for len(s.skipped) > MaxSessionSkip {
+		delete(s.skipped, s.skippedQ[0])
+		s.skippedQ = s.skippedQ[1:]
+	}
+	if len(s.skippedQ) > 2*MaxSessionSkip {
+		// Compact the queue, dropping entries for keys that were used.
+		q := s.skippedQ[:0]
+		for _, seq := range s.skippedQ {
+			if _, ok := s.skipped[seq]; ok {
+				q = append(q, seq)
+			}
+		}
+		s.skippedQ = q
+	}
+	s.recv, s.recvSeq = chain, seq+1
+	return out, nil
+}
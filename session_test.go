@@ -0,0 +1,68 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestSession(t *testing.T) {
+	root := xaes256gcm.GenerateKey()
+	alice := xaes256gcm.NewSession(root, true)
+	bob := xaes256gcm.NewSession(root, false)
+
+	var msgs [][]byte
+	for i := 0; i < 5; i++ {
+		msgs = append(msgs, alice.Seal(nil, []byte{byte(i)}, []byte("ad")))
+	}
+	if len(msgs[0]) != 1+xaes256gcm.SessionOverhead {
+		t.Errorf("unexpected ciphertext length %d", len(msgs[0]))
+	}
+	for _, i := range []int{0, 3, 1, 4, 2} {
+		if got, err := bob.Open(nil, msgs[i], []byte("ad")); err != nil || !bytes.Equal(got, []byte{byte(i)}) {
+			t.Fatalf("message %d: %x, %v", i, got, err)
+		}
+	}
+	for i := range msgs {
+		if _, err := bob.Open(nil, msgs[i], []byte("ad")); err == nil {
+			t.Errorf("message %d opened twice", i)
+		}
+	}
+
+	// The chains are independent.
+	reply := bob.Seal(nil, []byte("reply"), nil)
+	if _, err := bob.Open(nil, reply, nil); err == nil {
+		t.Errorf("own message opened")
+	}
+	if got, err := alice.Open(nil, reply, nil); err != nil || string(got) != "reply" {
+		t.Errorf("reply: %q, %v", got, err)
+	}
+	if bytes.Equal(reply[8:], alice.Seal(nil, []byte("reply"), nil)[8:]) {
+		t.Errorf("chains are not independent")
+	}
+
+	// Forgeries don't change the state.
+	next := alice.Seal(nil, []byte("next"), nil)
+	forged := append([]byte{}, next...)
+	forged[len(forged)-1] ^= 1
+	if _, err := bob.Open(nil, forged, nil); err == nil {
+		t.Errorf("forged message opened")
+	}
+	moved := append([]byte{}, next...)
+	moved[7]++
+	if _, err := bob.Open(nil, moved, nil); err == nil {
+		t.Errorf("message opened with modified sequence number")
+	}
+	if got, err := bob.Open(nil, next, nil); err != nil || string(got) != "next" {
+		t.Errorf("Open after forgery: %q, %v", got, err)
+	}
+
+	// Skipping too many messages fails.
+	for i := 0; i < xaes256gcm.MaxSessionSkip+1; i++ {
+		alice.Seal(nil, nil, nil)
+	}
+	if _, err := bob.Open(nil, alice.Seal(nil, nil, nil), nil); err == nil {
+		t.Errorf("opened after too many skipped messages")
+	}
+}
@@ -0,0 +1,120 @@
+// Package sharing implements Shamir's secret sharing of XAES-256-GCM keys.
+//
+// A key is split into n shares, any threshold of which can be combined to
+// recover it, while fewer than threshold shares reveal nothing about the key.
+//
+// Each share is 33 bytes: a one-byte index between 1 and 255, followed by the
+// evaluation at that index of a random polynomial over GF(2⁸) for each byte of
+// the key. The field is the one of AES, with reducing polynomial x⁸ + x⁴ + x³
+// + x + 1.
+//
+// Shares are not authenticated, so combining the wrong or corrupted shares
+// produces a different key without an error. Use [xaes256gcm.Key.Fingerprint]
+// to check the result.
+package sharing
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"filippo.io/xaes256gcm"
+)
+
+// ShareSize is the size of a share.
+const ShareSize = 1 + xaes256gcm.KeySize
+
+// Split splits key into n shares, any threshold of which can be combined with
+// [Combine] to recover key. threshold must be at least 2, and n must be at least
+// threshold and at most 255.
+func Split(key xaes256gcm.Key, n, threshold int) ([][]byte, error) {
+	if threshold < 2 {
+		return nil, errors.New("sharing: threshold must be at least 2")
+	}
+	if n < threshold || n > 255 {
+		return nil, errors.New("sharing: number of shares must be between threshold and 255")
+	}
+
+	// coefficients[i] are the coefficients of the polynomial for key byte i,
+	// starting from the constant term, which is the key byte.
+	secret := key.Bytes()
+	coefficients := make([][]byte, len(secret))
+	for i := range coefficients {
+		coefficients[i] = make([]byte, threshold)
+		coefficients[i][0] = secret[i]
+		if _, err := rand.Read(coefficients[i][1:]); err != nil {
+			return nil, err
+		}
+	}
+
+	shares := make([][]byte, n)
+	for j := range shares {
+		x := byte(j + 1)
+		share := make([]byte, ShareSize)
+		share[0] = x
+		for i, c := range coefficients {
+			// Horner's method.
+			var y byte
+			for k := len(c) - 1; k >= 0; k-- {
+				y = mul(y, x) ^ c[k]
+			}
+			share[1+i] = y
+		}
+		shares[j] = share
+	}
+	return shares, nil
+}
+
+// Combine recovers a key from at least threshold of the shares produced by
+// [Split]. The shares must have distinct indexes.
+func Combine(shares [][]byte) (xaes256gcm.Key, error) {
+	if len(shares) < 2 {
+		return xaes256gcm.Key{}, errors.New("sharing: at least two shares are required")
+	}
+	seen := make(map[byte]bool)
+	for _, s := range shares {
+		if len(s) != ShareSize || s[0] == 0 {
+			return xaes256gcm.Key{}, errors.New("sharing: malformed share")
+		}
+		if seen[s[0]] {
+			return xaes256gcm.Key{}, errors.New("sharing: duplicate share")
+		}
+		seen[s[0]] = true
+	}
+
+	// Lagrange interpolation at x = 0. In GF(2⁸), subtraction is addition.
+	secret := make([]byte, xaes256gcm.KeySize)
+	for j, sj := range shares {
+		basis := byte(1)
+		for m, sm := range shares {
+			if m == j {
+				continue
+			}
+			basis = mul(basis, mul(sm[0], inv(sm[0]^sj[0])))
+		}
+		for i := range secret {
+			secret[i] ^= mul(basis, sj[1+i])
+		}
+	}
+	return xaes256gcm.NewKey(secret)
+}
+
+// mul multiplies two elements of GF(2⁸) in constant time.
+func mul(a, b byte) byte {
+	var p byte
+	for i := 0; i < 8; i++ {
+		p ^= -(b & 1) & a
+		b >>= 1
+		a = a<<1 ^ -(a>>7)&0x1b
+	}
+	return p
+}
+
+// inv returns the multiplicative inverse of a, which is a²⁵⁴.
+func inv(a byte) byte {
+	r := a
+	for i := 0; i < 6; i++ {
+		a = mul(a, a)
+		r = mul(r, a)
+	}
+	return mul(r, r)
+}
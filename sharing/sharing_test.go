@@ -0,0 +1,63 @@
+package sharing_test
+
+import (
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/sharing"
+)
+
+func TestSplitCombine(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	shares, err := sharing.Split(key, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(shares) != 5 {
+		t.Fatalf("got %d shares", len(shares))
+	}
+	for a := 0; a < 5; a++ {
+		for b := a + 1; b < 5; b++ {
+			for c := b + 1; c < 5; c++ {
+				got, err := sharing.Combine([][]byte{shares[c], shares[a], shares[b]})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != key {
+					t.Errorf("shares %d, %d, %d: wrong key", a, b, c)
+				}
+			}
+			if got, err := sharing.Combine([][]byte{shares[a], shares[b]}); err != nil {
+				t.Fatal(err)
+			} else if got == key {
+				t.Errorf("shares %d, %d: recovered key below threshold", a, b)
+			}
+		}
+	}
+	if got, err := sharing.Combine(shares); err != nil || got != key {
+		t.Errorf("all shares: wrong key or error %v", err)
+	}
+
+	if _, err := sharing.Combine([][]byte{shares[0], shares[0], shares[1]}); err == nil {
+		t.Errorf("duplicate shares accepted")
+	}
+	if _, err := sharing.Combine([][]byte{shares[0], shares[1][:10]}); err == nil {
+		t.Errorf("malformed share accepted")
+	}
+	for _, p := range [][2]int{{1, 1}, {3, 4}, {256, 2}} {
+		if _, err := sharing.Split(key, p[0], p[1]); err == nil {
+			t.Errorf("Split(%d, %d) succeeded", p[0], p[1])
+		}
+	}
+}
+
+func TestMaxShares(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	shares, err := sharing.Split(key, 255, 255)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := sharing.Combine(shares); err != nil || got != key {
+		t.Errorf("wrong key or error %v", err)
+	}
+}
@@ -0,0 +1,39 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// ErrTooLarge is returned by the Open method of an AEAD returned by
+// [WithSizeLimit] if the ciphertext or additional data exceeds the limit.
+var ErrTooLarge = errors.New("xaes256gcm: ciphertext or additional data too large")
+
+// WithSizeLimit returns an AEAD that wraps aead, and whose Open rejects
+// ciphertexts longer than maxCiphertext bytes, or additional data longer than
+// maxAD bytes, with [ErrTooLarge], before decrypting or allocating anything.
+// The ciphertext length includes the overhead. A limit of zero or less
+// disables the corresponding check. Seal is not affected.
+//
+// It's meant for network-facing services, where a single hostile message
+// shouldn't be able to make Open allocate and process gigabytes of data only
+// for it to fail authentication. The limit should still be enforced when
+// reading the message, for example with [net/http.MaxBytesReader].
+func WithSizeLimit(aead cipher.AEAD, maxCiphertext, maxAD int) cipher.AEAD {
+	return &sizeLimitAEAD{aead, maxCiphertext, maxAD}
+}
+
+type sizeLimitAEAD struct {
+	cipher.AEAD
+	maxCiphertext, maxAD int
+}
+
+func (a *sizeLimitAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if a.maxCiphertext > 0 && len(ciphertext) > a.maxCiphertext {
+		return nil, ErrTooLarge
+	}
+	if a.maxAD > 0 && len(additionalData) > a.maxAD {
+		return nil, ErrTooLarge
+	}
+	return a.AEAD.Open(dst, nonce, ciphertext, additionalData)
+}
@@ -0,0 +1,32 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestSizeLimit(t *testing.T) {
+	inner := xaes256gcm.MustNew(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	c := xaes256gcm.WithSizeLimit(inner, 100+xaes256gcm.Overhead, 10)
+
+	ciphertext := c.Seal(nil, nil, make([]byte, 100), []byte("table:42"))
+	if got, err := c.Open(nil, nil, ciphertext, []byte("table:42")); err != nil || len(got) != 100 {
+		t.Fatalf("Open: %d bytes, %v", len(got), err)
+	}
+	large := c.Seal(nil, nil, make([]byte, 101), nil)
+	if _, err := c.Open(nil, nil, large, nil); !errors.Is(err, xaes256gcm.ErrTooLarge) {
+		t.Errorf("got error %v for large ciphertext, expected ErrTooLarge", err)
+	}
+	ad := []byte("table:4242")
+	if _, err := c.Open(nil, nil, inner.Seal(nil, nil, nil, append(ad, '2')), append(ad, '2')); !errors.Is(err, xaes256gcm.ErrTooLarge) {
+		t.Errorf("got error %v for large additional data, expected ErrTooLarge", err)
+	}
+
+	unlimited := xaes256gcm.WithSizeLimit(inner, 0, 0)
+	if _, err := unlimited.Open(nil, nil, large, nil); err != nil {
+		t.Errorf("zero limit rejected ciphertext: %v", err)
+	}
+}
@@ -0,0 +1,52 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"sync/atomic"
+)
+
+// Stats counts the operations performed by AEADs returned by [WithStats].
+//
+// The counters can be read at any time, for example to export them as metrics,
+// and are safe for concurrent use. A Stats can be shared by multiple AEADs.
+type Stats struct {
+	// Seals is the number of calls to Seal.
+	Seals atomic.Uint64
+	// Opens is the number of successful calls to Open.
+	Opens atomic.Uint64
+	// OpenFailures is the number of calls to Open that returned an error,
+	// because the ciphertext was malformed, tampered with, or encrypted with
+	// a different key or additional data.
+	OpenFailures atomic.Uint64
+	// BytesSealed is the total size of the plaintexts passed to Seal.
+	BytesSealed atomic.Uint64
+	// BytesOpened is the total size of the plaintexts returned by Open.
+	BytesOpened atomic.Uint64
+}
+
+// WithStats returns an AEAD that wraps aead, and counts its operations in s.
+func WithStats(aead cipher.AEAD, s *Stats) cipher.AEAD {
+	return &statsAEAD{aead, s}
+}
+
+type statsAEAD struct {
+	cipher.AEAD
+	s *Stats
+}
+
+func (a *statsAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	a.s.Seals.Add(1)
+	a.s.BytesSealed.Add(uint64(len(plaintext)))
+	return a.AEAD.Seal(dst, nonce, plaintext, additionalData)
+}
+
+func (a *statsAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	out, err := a.AEAD.Open(dst, nonce, ciphertext, additionalData)
+	if err != nil {
+		a.s.OpenFailures.Add(1)
+		return nil, err
+	}
+	a.s.Opens.Add(1)
+	a.s.BytesOpened.Add(uint64(len(out) - len(dst)))
+	return out, nil
+}
@@ -0,0 +1,42 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestStats(t *testing.T) {
+	c, err := xaes256gcm.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &xaes256gcm.Stats{}
+	c = xaes256gcm.WithStats(c, s)
+
+	ciphertext := c.Seal(nil, nil, []byte("hello"), nil)
+	c.Seal(nil, nil, []byte("world!"), nil)
+	if _, err := c.Open([]byte("prefix"), nil, ciphertext, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Open(nil, nil, ciphertext, []byte("wrong")); err == nil {
+		t.Fatal("Open succeeded with wrong additional data")
+	}
+
+	if got := s.Seals.Load(); got != 2 {
+		t.Errorf("Seals = %d", got)
+	}
+	if got := s.BytesSealed.Load(); got != 11 {
+		t.Errorf("BytesSealed = %d", got)
+	}
+	if got := s.Opens.Load(); got != 1 {
+		t.Errorf("Opens = %d", got)
+	}
+	if got := s.BytesOpened.Load(); got != 5 {
+		t.Errorf("BytesOpened = %d", got)
+	}
+	if got := s.OpenFailures.Load(); got != 1 {
+		t.Errorf("OpenFailures = %d", got)
+	}
+}
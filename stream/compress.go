@@ -0,0 +1,35 @@
+package stream
+
+import (
+	"compress/flate"
+	"io"
+)
+
+// NewCompressedWriter is like [NewWriter], but compresses the plaintext with
+// DEFLATE before encrypting it. The compression is marked in the nonce of every
+// chunk, so it's authenticated, and [Reader] detects it automatically.
+//
+// Compression makes the length of the ciphertext depend on the contents of the
+// plaintext. If an attacker can influence part of the plaintext, they might be
+// able to recover secrets from other parts of it by observing the ciphertext
+// length, like in the CRIME and BREACH attacks. NewCompressedWriter should only
+// be used for data that is not partially attacker-controlled, such as backups.
+//
+// Compressed streams don't support random access through [ReaderAt] or
+// [NewFS].
+func NewCompressedWriter(key []byte, dst io.Writer) (*Writer, error) {
+	w, err := NewWriter(key, dst)
+	if err != nil {
+		return nil, err
+	}
+	w.flags |= compressedFlag
+	w.zw, err = flate.NewWriter(chunkWriter{w}, flate.DefaultCompression)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+type chunkWriter struct{ w *Writer }
+
+func (c chunkWriter) Write(p []byte) (int, error) { return c.w.write(p) }
@@ -0,0 +1,106 @@
+package stream_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/xaes256gcm/stream"
+)
+
+func sealCompressed(t *testing.T, plaintext []byte) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w, err := stream.NewCompressedWriter(testKey, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompressed(t *testing.T) {
+	for _, length := range []int{0, 1, 1000, 3*stream.ChunkSize + 500, 50 * stream.ChunkSize} {
+		t.Run(fmt.Sprint(length), func(t *testing.T) {
+			plaintext := make([]byte, length)
+			for i := range plaintext {
+				plaintext[i] = byte(i / 1000)
+			}
+			ciphertext := sealCompressed(t, plaintext)
+			if length > stream.ChunkSize && len(ciphertext) > length/10 {
+				t.Errorf("ciphertext length %d for plaintext length %d", len(ciphertext), length)
+			}
+			got, err := open(ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("plaintext and decrypted are not equal")
+			}
+
+			if _, err := open(ciphertext[:len(ciphertext)-1]); err == nil {
+				t.Errorf("truncated stream opened")
+			}
+			if _, err := open(append(ciphertext, 0)); err == nil {
+				t.Errorf("stream with trailing data opened")
+			}
+			ciphertext[len(ciphertext)/2] ^= 1
+			if _, err := open(ciphertext); err == nil {
+				t.Errorf("modified stream opened")
+			}
+		})
+	}
+}
+
+func TestCompressedReaderAt(t *testing.T) {
+	ciphertext := sealCompressed(t, []byte("hello"))
+	r, err := stream.NewReaderAt(testKey, bytes.NewReader(ciphertext), int64(len(ciphertext)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.ReadAt(make([]byte, 1), 0); err == nil || err == io.EOF {
+		t.Errorf("compressed stream opened by ReaderAt: %v", err)
+	}
+}
+
+func TestCompressedFile(t *testing.T) {
+	plaintext := bytes.Repeat([]byte("compressible "), 3*stream.ChunkSize)
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	if err := os.WriteFile(name, sealCompressed(t, plaintext), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(dir, "out")
+	opts := &stream.FileOptions{Concurrency: 4}
+	if err := stream.DecryptFileContext(context.Background(), testKey, name, out, opts); err != nil {
+		t.Fatalf("DecryptFileContext with Concurrency: %v", err)
+	}
+	if got, err := os.ReadFile(out); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("DecryptFileContext with Concurrency: got %d bytes, %v", len(got), err)
+	}
+
+	if err := stream.DecryptFileMapped(testKey, name, out); err != nil {
+		t.Fatalf("DecryptFileMapped: %v", err)
+	}
+	if got, err := os.ReadFile(out); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("DecryptFileMapped: got %d bytes, %v", len(got), err)
+	}
+
+	buf := make([]byte, len(plaintext))
+	if n, err := stream.DecryptMapped(testKey, name, buf); err != nil || !bytes.Equal(buf[:n], plaintext) {
+		t.Errorf("DecryptMapped: got %d bytes, %v", n, err)
+	}
+	if _, err := stream.DecryptMapped(testKey, name, buf[:len(buf)-1]); err == nil {
+		t.Errorf("DecryptMapped: short buffer accepted")
+	}
+}
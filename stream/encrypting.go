@@ -0,0 +1,87 @@
+package stream
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"io"
+
+	"filippo.io/xaes256gcm"
+)
+
+// NewEncryptingReader returns a Reader that encrypts the plaintext read from
+// src, producing the same format as [Writer]. It can be used as the body of an
+// HTTP request or an object storage upload without an intermediate pipe.
+//
+// The returned Reader reads ahead of the data it returns by up to a chunk,
+// and reaches EOF after src does.
+func NewEncryptingReader(key []byte, src io.Reader) (io.Reader, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	r := &encryptingReader{
+		a:     aead,
+		src:   src,
+		plain: make([]byte, ChunkSize+1),
+		buf:   make([]byte, encChunkSize),
+	}
+	if _, err := rand.Read(r.nonce[:HeaderSize]); err != nil {
+		return nil, err
+	}
+	r.out = append(r.buf[:0], r.nonce[:HeaderSize]...)
+	return r, nil
+}
+
+type encryptingReader struct {
+	a   cipher.AEAD
+	src io.Reader
+
+	plain   []byte // one byte longer than a chunk, to detect the last one
+	pending int    // bytes read ahead into plain
+
+	out []byte // ciphertext not yet returned, backed by buf
+	buf []byte
+
+	nonce nonce
+	err   error // io.EOF after the last chunk
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if err := r.sealChunk(); err != nil {
+			r.err = err
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+func (r *encryptingReader) sealChunk() error {
+	n, err := io.ReadFull(r.src, r.plain[r.pending:])
+	total := r.pending + n
+	last := false
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+
+	r.nonce.setFlags(last, 0)
+	r.out = r.a.Seal(r.buf[:0], r.nonce[:], r.plain[:min(total, ChunkSize)], nil)
+	r.nonce.increment()
+
+	if last {
+		r.err = io.EOF
+		return nil
+	}
+	r.plain[0] = r.plain[ChunkSize]
+	r.pending = 1
+	return nil
+}
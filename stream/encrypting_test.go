@@ -0,0 +1,57 @@
+package stream_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/stream"
+)
+
+func TestEncryptingReader(t *testing.T) {
+	for _, length := range []int{0, 1, 1000, stream.ChunkSize - 1, stream.ChunkSize,
+		stream.ChunkSize + 1, 2 * stream.ChunkSize, 2*stream.ChunkSize + 500} {
+		t.Run(fmt.Sprint(length), func(t *testing.T) {
+			plaintext := make([]byte, length)
+			for i := range plaintext {
+				plaintext[i] = byte(i)
+			}
+			r, err := stream.NewEncryptingReader(testKey, iotest.OneByteReader(bytes.NewReader(plaintext)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ciphertext, err := io.ReadAll(iotest.HalfReader(r))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The format must match the one of Writer exactly.
+			chunks := max(1, (length+stream.ChunkSize-1)/stream.ChunkSize)
+			if exp := stream.HeaderSize + length + chunks*xaes256gcm.OverheadWithManualNonces; len(ciphertext) != exp {
+				t.Errorf("ciphertext length %d, expected %d", len(ciphertext), exp)
+			}
+			got, err := open(ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("plaintext and decrypted are not equal")
+			}
+		})
+	}
+}
+
+func TestEncryptingReaderError(t *testing.T) {
+	errTest := errors.New("test error")
+	src := io.MultiReader(bytes.NewReader(make([]byte, 1000)), iotest.ErrReader(errTest))
+	r, err := stream.NewEncryptingReader(testKey, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(r); err != errTest {
+		t.Errorf("got error %v, expected %v", err, errTest)
+	}
+}
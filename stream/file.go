@@ -0,0 +1,305 @@
+package stream
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+
+	"filippo.io/xaes256gcm"
+	"golang.org/x/crypto/hkdf"
+)
+
+// EncryptFile encrypts the file at src as a stream, and atomically replaces
+// dst with the result. src and dst may be the same path.
+//
+// The encrypted file is written to a temporary file in the same directory as
+// dst, flushed to stable storage, and then renamed to dst, so that dst is
+// never observed partially written. The permission bits of src are preserved.
+func EncryptFile(key []byte, src, dst string) error {
+	return EncryptFileContext(context.Background(), key, src, dst, nil)
+}
+
+// FileOptions are optional settings for [EncryptFileContext] and
+// [DecryptFileContext]. A nil *FileOptions is equivalent to the zero value.
+type FileOptions struct {
+	// Progress, if not nil, is called periodically with the number of
+	// plaintext bytes processed so far, and the total plaintext size, or -1 if
+	// it's unknown.
+	Progress func(processed, total int64)
+
+	// Concurrency, if greater than one, is the number of goroutines that
+	// encrypt or decrypt chunks in parallel, reading and writing them at their
+	// offsets. It's ignored if src is not a regular file, and when decrypting
+	// compressed streams, which are always decrypted serially. src must not
+	// change while it's being processed.
+	Concurrency int
+
+	// Tracer, if not nil, is used to create a span around each
+	// EncryptFileContext or DecryptFileContext call.
+	Tracer Tracer
+
+	// FileID, if not empty, makes the operation use the key derived from the
+	// key argument and FileID with [DeriveFileKey], instead of the key
+	// argument itself.
+	FileID string
+}
+
+// DeriveFileKey derives the key of a single file from master and fileID, which
+// identifies the file, for example its path relative to the root of an archive.
+// It uses HKDF-SHA256 with fileID as info.
+//
+// Per-file keys can be shared, for example with support staff, without
+// exposing any other file or the master key. To re-key a single file, encrypt
+// it again with a new identifier, such as the path followed by a version
+// number, and record it along with the file.
+func DeriveFileKey(master []byte, fileID string) ([]byte, error) {
+	if len(master) != xaes256gcm.KeySize {
+		return nil, errors.New("stream: bad master key length")
+	}
+	if fileID == "" {
+		return nil, errors.New("stream: empty file ID")
+	}
+	key := make([]byte, xaes256gcm.KeySize)
+	info := "filippo.io/xaes256gcm/stream file key\x00" + fileID
+	if _, err := io.ReadFull(hkdf.New(sha256.New, master, nil, []byte(info)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// key returns the key to use for an operation with key.
+func (opts *FileOptions) key(key []byte) ([]byte, error) {
+	if opts == nil || opts.FileID == "" {
+		return key, nil
+	}
+	return DeriveFileKey(key, opts.FileID)
+}
+
+// Tracer creates spans around file operations, for example to measure the
+// cost of encryption in distributed traces. It's a minimal interface that
+// doesn't depend on any tracing library, and can be implemented by a small
+// adapter around an OpenTelemetry trace.Tracer.
+//
+// Only [EncryptFileContext] and [DecryptFileContext] use a Tracer. Operations
+// on a [Reader] or [Writer] are driven by the caller, which can wrap them in
+// its own span, and count the bytes with [Reader.SetProgress] or
+// [Writer.SetProgress].
+type Tracer interface {
+	// Start starts a span for operation, which is "stream.EncryptFile" or
+	// "stream.DecryptFile". keyFingerprint identifies the key, as returned
+	// by [xaes256gcm.Key.Fingerprint], and is never the key material.
+	Start(ctx context.Context, operation, keyFingerprint string) (context.Context, Span)
+}
+
+// Span is a span started by [Tracer.Start].
+type Span interface {
+	// End ends the span, with the number of plaintext bytes processed, and
+	// the error returned by the operation, if any.
+	End(plaintextBytes int64, err error)
+}
+
+func (opts *FileOptions) progress() func(processed, total int64) {
+	if opts == nil {
+		return nil
+	}
+	return opts.Progress
+}
+
+// trace starts a span if opts has a Tracer, and returns a progress function
+// that calls the Progress callback, and a function that ends the span.
+func (opts *FileOptions) trace(ctx context.Context, operation string, key []byte) (context.Context, func(processed, total int64), func(error)) {
+	progress := opts.progress()
+	if opts == nil || opts.Tracer == nil {
+		return ctx, progress, func(error) {}
+	}
+	var fingerprint string
+	if k, err := xaes256gcm.NewKey(key); err == nil {
+		fingerprint = k.Fingerprint()
+	}
+	ctx, span := opts.Tracer.Start(ctx, operation, fingerprint)
+	var mu sync.Mutex
+	var processed int64
+	record := func(n, total int64) {
+		mu.Lock()
+		processed = n
+		mu.Unlock()
+		if progress != nil {
+			progress(n, total)
+		}
+	}
+	end := func(err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		span.End(processed, err)
+	}
+	return ctx, record, end
+}
+
+func (opts *FileOptions) concurrency() int {
+	if opts == nil {
+		return 1
+	}
+	return opts.Concurrency
+}
+
+// EncryptFileContext is like [EncryptFile], but stops and returns ctx.Err() if
+// ctx is canceled before the encryption is complete. dst is left untouched,
+// and the temporary file is removed.
+func EncryptFileContext(ctx context.Context, key []byte, src, dst string, opts *FileOptions) (err error) {
+	key, err = opts.key(key)
+	if err != nil {
+		return err
+	}
+	ctx, progress, end := opts.trace(ctx, "stream.EncryptFile", key)
+	defer func() { end(err) }()
+	return replaceFile(ctx, src, dst, func(in, out *os.File, size int64) error {
+		if size >= 0 && opts.concurrency() > 1 {
+			return encryptParallel(ctx, key, in, out, size, opts.concurrency(), progress)
+		}
+		w, err := NewWriter(key, out)
+		if err != nil {
+			return err
+		}
+		if progress != nil {
+			w.SetProgress(func(n int64) { progress(n, size) })
+		}
+		if _, err := io.Copy(w, &ctxReader{ctx, in}); err != nil {
+			return err
+		}
+		return w.Close()
+	})
+}
+
+// DecryptFile decrypts the stream at src, and atomically replaces dst with the
+// plaintext, like [EncryptFile].
+//
+// If src is corrupted or truncated, dst is left untouched, and no plaintext is
+// written to it.
+func DecryptFile(key []byte, src, dst string) error {
+	return DecryptFileContext(context.Background(), key, src, dst, nil)
+}
+
+// DecryptFileContext is like [DecryptFile], but stops and returns ctx.Err() if
+// ctx is canceled before the decryption is complete, like
+// [EncryptFileContext].
+func DecryptFileContext(ctx context.Context, key []byte, src, dst string, opts *FileOptions) (err error) {
+	key, err = opts.key(key)
+	if err != nil {
+		return err
+	}
+	ctx, progress, end := opts.trace(ctx, "stream.DecryptFile", key)
+	defer func() { end(err) }()
+	return replaceFile(ctx, src, dst, func(in, out *os.File, size int64) error {
+		if size >= 0 && opts.concurrency() > 1 && isPlainStream(key, in, size) {
+			return decryptParallel(ctx, key, in, out, size, opts.concurrency(), progress)
+		}
+		r, err := NewReader(key, &ctxReader{ctx, in})
+		if err != nil {
+			return err
+		}
+		if progress != nil {
+			total, err := DecryptedSize(size)
+			if err != nil {
+				total = -1
+			}
+			r.SetProgress(func(n int64) { progress(n, total) })
+		}
+		_, err = io.Copy(out, r)
+		return err
+	})
+}
+
+// VerifyFile checks the integrity of the stream at path, like [Verify].
+func VerifyFile(key []byte, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return Verify(key, f)
+}
+
+func replaceFile(ctx context.Context, src, dst string, f func(in, out *os.File, size int64) error) (err error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+
+	dir, base := filepath.Split(dst)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := os.CreateTemp(dir, "."+base+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	size := int64(-1)
+	if info.Mode().IsRegular() {
+		size = info.Size()
+	}
+	if err := f(in, tmp, size); err != nil {
+		return err
+	}
+	// Check again in case the cancellation raced with the end of the input.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// ctxReader is a Reader that fails with ctx.Err() once ctx is canceled.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r *ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// syncDir flushes the directory entry of a renamed file to stable storage.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		// Directories can't be synced on Windows, and renames are durable
+		// once MoveFileEx returns.
+		return nil
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
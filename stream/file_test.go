@@ -0,0 +1,304 @@
+package stream_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/stream"
+)
+
+func TestEncryptFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "config.json")
+	plaintext := []byte(`{"password": "hunter2"}`)
+	if err := os.WriteFile(name, plaintext, 0640); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(name, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := stream.EncryptFile(testKey, name, name); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := open(ciphertext); err != nil || string(got) != string(plaintext) {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	if info, err := os.Stat(name); err != nil || info.Mode().Perm() != 0640 {
+		t.Errorf("mode not preserved: %v, %v", info.Mode(), err)
+	}
+
+	// A corrupted file must not replace the destination.
+	corrupted := filepath.Join(dir, "corrupted")
+	ciphertext[len(ciphertext)-1] ^= 1
+	if err := os.WriteFile(corrupted, ciphertext, 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	if err := os.WriteFile(out, []byte("original"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.DecryptFile(testKey, corrupted, out); err == nil {
+		t.Errorf("corrupted file decrypted")
+	}
+	if got, err := os.ReadFile(out); err != nil || string(got) != "original" {
+		t.Errorf("destination modified: %q, %v", got, err)
+	}
+
+	if err := stream.DecryptFile(testKey, name, name); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(name); err != nil || string(got) != string(plaintext) {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestFileContext(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	if err := os.WriteFile(name, make([]byte, 3*stream.ChunkSize), 0600); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(dir, "out")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := stream.EncryptFileContext(ctx, testKey, name, out, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, expected context.Canceled", err)
+	}
+	if err := stream.EncryptFileContext(context.Background(), testKey, name, out, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.DecryptFileContext(ctx, testKey, out, name, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("got error %v, expected context.Canceled", err)
+	}
+	if got, err := os.ReadFile(name); err != nil || len(got) != 3*stream.ChunkSize {
+		t.Errorf("destination modified: %d bytes, %v", len(got), err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestFileProgress(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	size := int64(3*stream.ChunkSize + 100)
+	if err := os.WriteFile(name, make([]byte, size), 0600); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range []func(context.Context, []byte, string, string, *stream.FileOptions) error{
+		stream.EncryptFileContext, stream.DecryptFileContext,
+	} {
+		var calls int
+		var last int64
+		opts := &stream.FileOptions{Progress: func(processed, total int64) {
+			calls++
+			if total != size {
+				t.Errorf("total = %d, expected %d", total, size)
+			}
+			if processed <= last || processed > total {
+				t.Errorf("processed = %d after %d", processed, last)
+			}
+			last = processed
+		}}
+		if err := f(context.Background(), testKey, name, name, opts); err != nil {
+			t.Fatal(err)
+		}
+		if calls < 2 || last != size {
+			t.Errorf("%d calls, last processed %d", calls, last)
+		}
+	}
+}
+
+func TestVerifyFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	ciphertext := seal(t, make([]byte, 3*stream.ChunkSize))
+	if err := os.WriteFile(name, ciphertext, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.VerifyFile(testKey, name); err != nil {
+		t.Errorf("valid file failed verification: %v", err)
+	}
+	for _, corrupted := range [][]byte{
+		ciphertext[:len(ciphertext)-1],
+		ciphertext[:stream.HeaderSize+stream.ChunkSize+16],
+		append([]byte{0}, ciphertext[1:]...),
+	} {
+		if err := os.WriteFile(name, corrupted, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.VerifyFile(testKey, name); err == nil {
+			t.Errorf("corrupted file passed verification")
+		}
+	}
+}
+
+func TestFileConcurrency(t *testing.T) {
+	dir := t.TempDir()
+	for _, size := range []int{0, 100, stream.ChunkSize, 10*stream.ChunkSize + 7} {
+		name := filepath.Join(dir, "data")
+		plaintext := make([]byte, size)
+		for i := range plaintext {
+			plaintext[i] = byte(i * 7)
+		}
+		if err := os.WriteFile(name, plaintext, 0600); err != nil {
+			t.Fatal(err)
+		}
+		var last int64
+		opts := &stream.FileOptions{Concurrency: 4, Progress: func(processed, total int64) {
+			if processed <= last || total != int64(size) {
+				t.Errorf("progress %d/%d after %d", processed, total, last)
+			}
+			last = processed
+		}}
+		enc := filepath.Join(dir, "enc")
+		if err := stream.EncryptFileContext(context.Background(), testKey, name, enc, opts); err != nil {
+			t.Fatal(err)
+		}
+		ciphertext, err := os.ReadFile(enc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := open(ciphertext); err != nil || !bytes.Equal(got, plaintext) {
+			t.Fatalf("size %d: sequential decryption failed: %v", size, err)
+		}
+
+		last = 0
+		dec := filepath.Join(dir, "dec")
+		if err := stream.DecryptFileContext(context.Background(), testKey, enc, dec, opts); err != nil {
+			t.Fatal(err)
+		}
+		if got, err := os.ReadFile(dec); err != nil || !bytes.Equal(got, plaintext) {
+			t.Fatalf("size %d: parallel decryption failed: %v", size, err)
+		}
+
+		ciphertext[len(ciphertext)/2] ^= 1
+		if err := os.WriteFile(enc, ciphertext, 0600); err != nil {
+			t.Fatal(err)
+		}
+		opts.Progress = nil
+		var chunkErr *stream.ChunkError
+		if err := stream.DecryptFileContext(context.Background(), testKey, enc, dec, opts); !errors.As(err, &chunkErr) {
+			t.Errorf("size %d: corrupted file decrypted: %v", size, err)
+		}
+		if err := os.WriteFile(enc, ciphertext[:len(ciphertext)-1], 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.DecryptFileContext(context.Background(), testKey, enc, dec, opts); err == nil {
+			t.Errorf("size %d: truncated file decrypted", size)
+		}
+	}
+}
+
+type testTracer struct {
+	ops []string
+}
+
+type testSpan struct {
+	t     *testTracer
+	op    string
+	bytes int64
+	err   error
+}
+
+func (t *testTracer) Start(ctx context.Context, op, fingerprint string) (context.Context, stream.Span) {
+	return ctx, &testSpan{t: t, op: op + " " + fingerprint}
+}
+
+func (s *testSpan) End(n int64, err error) {
+	s.t.ops = append(s.t.ops, fmt.Sprintf("%s %d %v", s.op, n, err))
+}
+
+func TestFileTracer(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "data")
+	if err := os.WriteFile(name, make([]byte, 3*stream.ChunkSize+100), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tracer := &testTracer{}
+	fp := xaes256gcm.MustKey(testKey).Fingerprint()
+	for _, concurrency := range []int{1, 4} {
+		opts := &stream.FileOptions{Tracer: tracer, Concurrency: concurrency}
+		if err := stream.EncryptFileContext(context.Background(), testKey, name, name, opts); err != nil {
+			t.Fatal(err)
+		}
+		if err := stream.DecryptFileContext(context.Background(), testKey, name, name, opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.DecryptFileContext(context.Background(), testKey, name, name, &stream.FileOptions{Tracer: tracer}); err == nil {
+		t.Fatal("plaintext file decrypted")
+	}
+	enc := "stream.EncryptFile " + fp + " 196708 <nil>"
+	dec := "stream.DecryptFile " + fp + " 196708 <nil>"
+	expected := []string{enc, dec, enc, dec}
+	if len(tracer.ops) != 5 || strings.Join(tracer.ops[:4], "\n") != strings.Join(expected, "\n") ||
+		!strings.HasSuffix(tracer.ops[4], "stream: failed to decrypt and authenticate chunk 0") {
+		t.Errorf("unexpected spans:\n%s", strings.Join(tracer.ops, "\n"))
+	}
+}
+
+func TestFileID(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "report.pdf")
+	if err := os.WriteFile(name, []byte("quarterly numbers"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	opts := &stream.FileOptions{FileID: "reports/report.pdf"}
+	if err := stream.EncryptFileContext(context.Background(), testKey, name, name, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file decrypts with the derived key alone, but not with the master
+	// key or with the key of a different file.
+	fileKey, err := stream.DeriveFileKey(testKey, "reports/report.pdf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.VerifyFile(fileKey, name); err != nil {
+		t.Errorf("file failed to verify with the derived key: %v", err)
+	}
+	if err := stream.VerifyFile(testKey, name); err == nil {
+		t.Errorf("file verified with the master key")
+	}
+	out := filepath.Join(dir, "out")
+	other := &stream.FileOptions{FileID: "reports/other.pdf"}
+	if err := stream.DecryptFileContext(context.Background(), testKey, name, out, other); err == nil {
+		t.Errorf("file decrypted with the key of a different file")
+	}
+	if err := stream.DecryptFileContext(context.Background(), testKey, name, out, opts); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := os.ReadFile(out); err != nil || string(got) != "quarterly numbers" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	if _, err := stream.DeriveFileKey(testKey, ""); err == nil {
+		t.Errorf("empty file ID accepted")
+	}
+}
@@ -0,0 +1,139 @@
+package stream
+
+import (
+	"io"
+	"io/fs"
+
+	"filippo.io/xaes256gcm"
+)
+
+// NewFS returns a read-only [fs.FS] that decrypts the files of fsys, which
+// must all be streams encrypted with key.
+//
+// Files implement [io.Seeker] and [io.ReaderAt] if the underlying files
+// implement [io.ReaderAt], which is required for [net/http.FS]. Their size is
+// the size of the plaintext.
+func NewFS(key []byte, fsys fs.FS) (fs.FS, error) {
+	if _, err := xaes256gcm.NewWithManualNonces(key); err != nil {
+		return nil, err
+	}
+	return &encryptedFS{key: key, fsys: fsys}, nil
+}
+
+type encryptedFS struct {
+	key  []byte
+	fsys fs.FS
+}
+
+func (e *encryptedFS) Open(name string) (fs.File, error) {
+	f, err := e.fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dir{File: f, info: info}, nil
+	}
+	if !info.Mode().IsRegular() {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+
+	ef := &file{File: f}
+	if ra, ok := f.(io.ReaderAt); ok {
+		r, err := NewReaderAt(e.key, ra, info.Size())
+		if err != nil {
+			f.Close()
+			return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+		}
+		ef.sr = io.NewSectionReader(r, 0, r.Size())
+		ef.info = fileInfo{info, r.Size()}
+		return &seekableFile{ef}, nil
+	}
+	size, err := DecryptedSize(info.Size())
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	r, err := NewReader(e.key, f)
+	if err != nil {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	ef.r = r
+	ef.info = fileInfo{info, size}
+	return ef, nil
+}
+
+type file struct {
+	fs.File
+	info fileInfo
+	r    *Reader
+	sr   *io.SectionReader
+}
+
+func (f *file) Stat() (fs.FileInfo, error) { return f.info, nil }
+
+func (f *file) Read(p []byte) (int, error) {
+	if f.sr != nil {
+		return f.sr.Read(p)
+	}
+	return f.r.Read(p)
+}
+
+type seekableFile struct {
+	*file
+}
+
+func (f *seekableFile) Seek(offset int64, whence int) (int64, error) {
+	return f.sr.Seek(offset, whence)
+}
+
+func (f *seekableFile) ReadAt(p []byte, off int64) (int, error) {
+	return f.sr.ReadAt(p, off)
+}
+
+type fileInfo struct {
+	fs.FileInfo
+	size int64
+}
+
+func (fi fileInfo) Size() int64 { return fi.size }
+
+type dir struct {
+	fs.File
+	info fs.FileInfo
+}
+
+func (d *dir) Stat() (fs.FileInfo, error) { return d.info, nil }
+
+func (d *dir) ReadDir(n int) ([]fs.DirEntry, error) {
+	rd, ok := d.File.(fs.ReadDirFile)
+	if !ok {
+		return nil, &fs.PathError{Op: "readdir", Path: d.info.Name(), Err: fs.ErrInvalid}
+	}
+	entries, err := rd.ReadDir(n)
+	for i, e := range entries {
+		entries[i] = dirEntry{e}
+	}
+	return entries, err
+}
+
+type dirEntry struct {
+	fs.DirEntry
+}
+
+// Info returns the FileInfo of the entry, with the size of the plaintext for
+// regular files. If the size is not valid for a stream, it returns zero.
+func (e dirEntry) Info() (fs.FileInfo, error) {
+	info, err := e.DirEntry.Info()
+	if err != nil || !info.Mode().IsRegular() {
+		return info, err
+	}
+	size, _ := DecryptedSize(info.Size())
+	return fileInfo{info, size}, nil
+}
@@ -0,0 +1,62 @@
+package stream_test
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"filippo.io/xaes256gcm/stream"
+)
+
+func TestFS(t *testing.T) {
+	big := bytes.Repeat([]byte("0123456789abcdef"), stream.ChunkSize/8)
+	underlying := fstest.MapFS{
+		"a.txt":       {Data: seal(t, []byte("hello")), Mode: 0644},
+		"dir/big.bin": {Data: seal(t, big), Mode: 0644},
+		"empty":       {Data: seal(t, nil), Mode: 0644},
+	}
+	fsys, err := stream.NewFS(testKey, underlying)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fstest.TestFS(fsys, "a.txt", "dir/big.bin", "empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, err := fs.ReadFile(fsys, "dir/big.bin"); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(got, big) {
+		t.Errorf("dir/big.bin doesn't match")
+	}
+
+	srv := httptest.NewServer(http.FileServer(http.FS(fsys)))
+	defer srv.Close()
+	req, _ := http.NewRequest("GET", srv.URL+"/dir/big.bin", nil)
+	req.Header.Set("Range", "bytes=65530-65545")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	got, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(got, big[65530:65546]) {
+		t.Errorf("range request: %v %q", resp.Status, got)
+	}
+
+	underlying["bad"] = &fstest.MapFile{Data: seal(t, []byte("hello"))[:20]}
+	if _, err := fs.ReadFile(fsys, "bad"); err == nil {
+		t.Errorf("truncated file read")
+	}
+
+	underlying["forged"] = &fstest.MapFile{Data: make([]byte, stream.EncryptedSize(0))}
+	if _, err := fsys.Open("forged"); err == nil {
+		t.Errorf("forged empty file opened")
+	}
+}
@@ -0,0 +1,160 @@
+package stream
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"filippo.io/xaes256gcm"
+)
+
+// MaxMetadataSize is the maximum size of the metadata of a stream.
+const MaxMetadataSize = 64 * 1024
+
+const (
+	metadataFlag = 0x04
+
+	metadataPublic    = 0x00
+	metadataEncrypted = 0x01
+)
+
+// metadataNonce returns the nonce of the metadata record of a stream. Its
+// counter can't be reached by a chunk.
+func metadataNonce(prefix []byte) []byte {
+	var n nonce
+	copy(n[:], prefix)
+	for i := HeaderSize; i < len(n)-1; i++ {
+		n[i] = 0xff
+	}
+	n.setFlags(false, metadataFlag)
+	return n[:]
+}
+
+// NewWriterWithMetadata is like [NewWriter], but stores metadata in the header
+// of the stream, such as the original file name or the content type. The
+// metadata can be read with [NewReaderWithMetadata] or [ReadMetadata] without
+// decrypting the rest of the stream.
+//
+// If encrypt is false, the metadata is authenticated but stored in plaintext,
+// and can also be read without the key with [ReadPublicMetadata].
+//
+// The stream header is followed by the metadata type, 0x00 for public and 0x01
+// for encrypted, a 4-byte big-endian record length, and the metadata record.
+// The record is the metadata encrypted with XAES-256-GCM, or the metadata
+// followed by the tag of an empty plaintext encrypted with the metadata as
+// additional data. Either way, the type is also part of the additional data,
+// and the nonce is the nonce prefix, followed by eleven 0xff bytes and by 0x04.
+// The nonces of the chunks have the 0x04 bit set in their last byte, so that
+// the metadata can't be removed or added without detection.
+//
+// Streams with metadata can't be read by [NewReader], [ReaderAt], or [NewFS],
+// and can't be resumed.
+func NewWriterWithMetadata(key []byte, dst io.Writer, metadata []byte, encrypt bool) (*Writer, error) {
+	if len(metadata) > MaxMetadataSize {
+		return nil, errors.New("stream: metadata too large")
+	}
+	w, err := NewWriter(key, dst)
+	if err != nil {
+		return nil, err
+	}
+	w.flags |= metadataFlag
+
+	typ := byte(metadataPublic)
+	if encrypt {
+		typ = metadataEncrypted
+	}
+	nonce := metadataNonce(w.nonce[:HeaderSize])
+	record := make([]byte, 5, 5+len(metadata)+w.a.Overhead())
+	record[0] = typ
+	if encrypt {
+		record = w.a.Seal(record, nonce, metadata, record[:1])
+	} else {
+		ad := append([]byte{typ}, metadata...)
+		record = append(record, metadata...)
+		record = w.a.Seal(record, nonce, nil, ad)
+	}
+	binary.BigEndian.PutUint32(record[1:5], uint32(len(record)-5))
+	if _, err := dst.Write(record); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewReaderWithMetadata returns a Reader that decrypts a stream produced by a
+// Writer returned by [NewWriterWithMetadata], and the authenticated metadata.
+// It reads the stream header and the metadata from src.
+func NewReaderWithMetadata(key []byte, src io.Reader) (*Reader, []byte, error) {
+	r, err := NewReader(key, src)
+	if err != nil {
+		return nil, nil, err
+	}
+	typ, record, err := readMetadataRecord(src, r.a.Overhead())
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := metadataNonce(r.nonce[:HeaderSize])
+	var metadata []byte
+	switch typ {
+	case metadataEncrypted:
+		metadata, err = r.a.Open(nil, nonce, record, []byte{typ})
+	case metadataPublic:
+		metadata = record[:len(record)-r.a.Overhead()]
+		ad := append([]byte{typ}, metadata...)
+		_, err = r.a.Open(nil, nonce, record[len(metadata):], ad)
+	}
+	if err != nil {
+		return nil, nil, errors.New("stream: failed to decrypt and authenticate metadata")
+	}
+	r.flags = metadataFlag
+	return r, metadata, nil
+}
+
+// ReadMetadata reads and authenticates the metadata of a stream produced by
+// a Writer returned by [NewWriterWithMetadata]. It only reads the header and
+// the metadata from src.
+func ReadMetadata(key []byte, src io.Reader) ([]byte, error) {
+	_, metadata, err := NewReaderWithMetadata(key, src)
+	return metadata, err
+}
+
+// ReadPublicMetadata reads the metadata of a stream produced by a Writer
+// returned by [NewWriterWithMetadata] with encrypt set to false, without the
+// key. The metadata is NOT authenticated, and must not be trusted.
+func ReadPublicMetadata(src io.Reader) ([]byte, error) {
+	if _, err := io.ReadFull(src, make([]byte, HeaderSize)); err != nil {
+		return nil, err
+	}
+	const overhead = xaes256gcm.OverheadWithManualNonces
+	typ, record, err := readMetadataRecord(src, overhead)
+	if err != nil {
+		return nil, err
+	}
+	if typ != metadataPublic {
+		return nil, errors.New("stream: metadata is encrypted")
+	}
+	return record[:len(record)-overhead], nil
+}
+
+func readMetadataRecord(src io.Reader, overhead int) (typ byte, record []byte, err error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(src, hdr[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	typ = hdr[0]
+	length := binary.BigEndian.Uint32(hdr[1:])
+	if typ != metadataPublic && typ != metadataEncrypted ||
+		length < uint32(overhead) || length > MaxMetadataSize+uint32(overhead) {
+		return 0, nil, errors.New("stream: malformed metadata")
+	}
+	record = make([]byte, length)
+	if _, err := io.ReadFull(src, record); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	return typ, record, nil
+}
@@ -0,0 +1,98 @@
+package stream_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/stream"
+)
+
+func sealWithMetadata(t *testing.T, plaintext, metadata []byte, encrypt bool) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w, err := stream.NewWriterWithMetadata(testKey, buf, metadata, encrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestMetadata(t *testing.T) {
+	plaintext := make([]byte, 2*stream.ChunkSize+100)
+	metadata := []byte(`{"name": "backup.tar", "type": "application/x-tar"}`)
+	for _, encrypt := range []bool{false, true} {
+		ciphertext := sealWithMetadata(t, plaintext, metadata, encrypt)
+
+		r, md, err := stream.NewReaderWithMetadata(testKey, bytes.NewReader(ciphertext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(md, metadata) {
+			t.Errorf("got metadata %q", md)
+		}
+		if got, err := io.ReadAll(r); err != nil {
+			t.Fatal(err)
+		} else if !bytes.Equal(got, plaintext) {
+			t.Errorf("plaintext and decrypted are not equal")
+		}
+
+		if md, err := stream.ReadMetadata(testKey, bytes.NewReader(ciphertext)); err != nil || !bytes.Equal(md, metadata) {
+			t.Errorf("ReadMetadata returned %q, %v", md, err)
+		}
+		md, err = stream.ReadPublicMetadata(bytes.NewReader(ciphertext))
+		if encrypt && err == nil {
+			t.Errorf("ReadPublicMetadata succeeded for encrypted metadata")
+		}
+		if !encrypt && (err != nil || !bytes.Equal(md, metadata)) {
+			t.Errorf("ReadPublicMetadata returned %q, %v", md, err)
+		}
+		if encrypt && bytes.Contains(ciphertext, metadata) {
+			t.Errorf("encrypted metadata found in ciphertext")
+		}
+
+		// Streams with metadata can't be opened as regular streams.
+		if _, err := open(ciphertext); err == nil {
+			t.Errorf("stream with metadata opened by NewReader")
+		}
+		// Modifying the metadata is detected.
+		modified := bytes.Clone(ciphertext)
+		modified[stream.HeaderSize+5] ^= 1
+		if _, err := stream.ReadMetadata(testKey, bytes.NewReader(modified)); err == nil {
+			t.Errorf("modified metadata accepted")
+		}
+		// Switching the type is detected.
+		modified = bytes.Clone(ciphertext)
+		modified[stream.HeaderSize] ^= 1
+		if _, err := stream.ReadMetadata(testKey, bytes.NewReader(modified)); err == nil {
+			t.Errorf("modified metadata type accepted")
+		}
+	}
+
+	// Regular streams can't be opened as streams with metadata, and adding
+	// a metadata record to a regular stream is detected.
+	regular := seal(t, plaintext)
+	if _, _, err := stream.NewReaderWithMetadata(testKey, bytes.NewReader(regular)); err == nil {
+		t.Errorf("regular stream opened with metadata")
+	}
+	withMD := sealWithMetadata(t, nil, metadata, true)
+	mdLen := len(withMD) - xaes256gcm.OverheadWithManualNonces
+	spliced := append(bytes.Clone(withMD[:mdLen]), regular[stream.HeaderSize:]...)
+	r, _, err := stream.NewReaderWithMetadata(testKey, bytes.NewReader(spliced))
+	if err == nil {
+		if _, err := io.ReadAll(r); err == nil {
+			t.Errorf("spliced stream opened")
+		}
+	}
+
+	if _, err := stream.NewWriterWithMetadata(testKey, io.Discard, make([]byte, stream.MaxMetadataSize+1), true); err == nil {
+		t.Errorf("oversized metadata accepted")
+	}
+}
@@ -0,0 +1,154 @@
+package stream
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+
+	"filippo.io/xaes256gcm"
+)
+
+// DecryptFileMapped is like [DecryptFile], but maps src and the output file
+// into memory, and decrypts each chunk directly from one mapping into the
+// other, without copying the data through intermediate buffers.
+//
+// It is meant for multi-gigabyte files, and requires src to be a regular file
+// that doesn't change while it's being decrypted. On platforms without mmap,
+// it reads src into memory instead. Compressed streams are decrypted serially,
+// like by DecryptFile.
+func DecryptFileMapped(key []byte, src, dst string) error {
+	return replaceFile(context.Background(), src, dst, func(in, out *os.File, size int64) (err error) {
+		if size < 0 {
+			return errors.New("stream: mapped decryption requires a regular file")
+		}
+		if !isPlainStream(key, in, size) {
+			r, err := NewReader(key, in)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(out, r)
+			return err
+		}
+		total, err := DecryptedSize(size)
+		if err != nil {
+			return err
+		}
+		ciphertext, unmapIn, err := mapFile(in, size, false)
+		if err != nil {
+			return err
+		}
+		defer unmapIn()
+		if err := out.Truncate(total); err != nil {
+			return err
+		}
+		plaintext, unmapOut, err := mapFile(out, total, true)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err1 := unmapOut(); err == nil {
+				err = err1
+			}
+		}()
+		return decryptMapped(key, plaintext, ciphertext)
+	})
+}
+
+// DecryptMapped maps the stream at path into memory, and decrypts it into dst,
+// which must be at least as long as the plaintext. It returns the size of the
+// plaintext. Like [DecryptFileMapped], it avoids intermediate copies, except
+// for compressed streams, which are decrypted serially.
+//
+// If the stream is corrupted or truncated, dst is zeroed, and no plaintext is
+// returned.
+func DecryptMapped(key []byte, path string, dst []byte) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, errors.New("stream: mapped decryption requires a regular file")
+	}
+	if !isPlainStream(key, f, info.Size()) {
+		r, err := NewReader(key, f)
+		if err != nil {
+			return 0, err
+		}
+		n, err := readInto(r, dst)
+		if err != nil {
+			clear(dst[:n])
+			return 0, err
+		}
+		return n, nil
+	}
+	total, err := DecryptedSize(info.Size())
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(dst)) < total {
+		return 0, errors.New("stream: destination buffer too small")
+	}
+	ciphertext, unmap, err := mapFile(f, info.Size(), false)
+	if err != nil {
+		return 0, err
+	}
+	defer unmap()
+	if err := decryptMapped(key, dst[:total], ciphertext); err != nil {
+		clear(dst[:total])
+		return 0, err
+	}
+	return int(total), nil
+}
+
+// readInto reads r until EOF into dst, and returns an error if the contents of
+// r don't fit.
+func readInto(r io.Reader, dst []byte) (int, error) {
+	n := 0
+	for n < len(dst) {
+		m, err := r.Read(dst[n:])
+		n += m
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+	}
+	if _, err := io.CopyN(io.Discard, r, 1); err != io.EOF {
+		if err == nil {
+			err = errors.New("stream: destination buffer too small")
+		}
+		return n, err
+	}
+	return n, nil
+}
+
+// decryptMapped decrypts the whole stream src into dst, which must be exactly
+// the size of the plaintext.
+func decryptMapped(key, dst, src []byte) error {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return err
+	}
+	total := int64(len(dst))
+	chunks := max(1, (total+ChunkSize-1)/ChunkSize)
+	prefix := src[:HeaderSize]
+	for i := int64(0); i < chunks; i++ {
+		off := i * ChunkSize
+		end := min(off+ChunkSize, total)
+		start := HeaderSize + i*encChunkSize
+		ciphertext := src[start : start+end-off+xaes256gcm.OverheadWithManualNonces]
+		// Open appends to dst[off:off], which has enough capacity for the
+		// plaintext, so it writes it in place.
+		if _, err := aead.Open(dst[off:off:end], chunkNonce(prefix, i, chunks), ciphertext, nil); err != nil {
+			return &ChunkError{Index: uint64(i)}
+		}
+	}
+	return nil
+}
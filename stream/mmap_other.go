@@ -0,0 +1,24 @@
+//go:build !unix
+
+package stream
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the first size bytes of f into memory, and returns them and a
+// function that writes them back if writable is true.
+func mapFile(f *os.File, size int64, writable bool) ([]byte, func() error, error) {
+	b := make([]byte, size)
+	if writable {
+		return b, func() error {
+			_, err := f.WriteAt(b, 0)
+			return err
+		}, nil
+	}
+	if _, err := io.ReadFull(io.NewSectionReader(f, 0, size), b); err != nil {
+		return nil, nil, err
+	}
+	return b, func() error { return nil }, nil
+}
@@ -0,0 +1,73 @@
+package stream_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/xaes256gcm/stream"
+)
+
+func TestDecryptFileMapped(t *testing.T) {
+	for _, size := range []int{0, 100, stream.ChunkSize, 2*stream.ChunkSize + 1} {
+		dir := t.TempDir()
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+		name := filepath.Join(dir, "data")
+		if err := os.WriteFile(name, seal(t, plaintext), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		buf := make([]byte, size+10)
+		n, err := stream.DecryptMapped(testKey, name, buf)
+		if err != nil || !bytes.Equal(buf[:n], plaintext) {
+			t.Errorf("%d: DecryptMapped: got %d bytes, %v", size, n, err)
+		}
+		if size > 0 {
+			if _, err := stream.DecryptMapped(testKey, name, buf[:size-1]); err == nil {
+				t.Errorf("%d: short buffer accepted", size)
+			}
+		}
+
+		out := filepath.Join(dir, "out")
+		if err := stream.DecryptFileMapped(testKey, name, out); err != nil {
+			t.Fatalf("%d: %v", size, err)
+		}
+		if got, err := os.ReadFile(out); err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("%d: DecryptFileMapped: got %d bytes, %v", size, len(got), err)
+		}
+	}
+}
+
+func TestDecryptFileMappedCorrupted(t *testing.T) {
+	dir := t.TempDir()
+	ciphertext := seal(t, bytes.Repeat([]byte("A"), 3*stream.ChunkSize))
+	ciphertext[len(ciphertext)-1] ^= 1
+	name := filepath.Join(dir, "data")
+	if err := os.WriteFile(name, ciphertext, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 3*stream.ChunkSize)
+	var chunkErr *stream.ChunkError
+	if _, err := stream.DecryptMapped(testKey, name, buf); !errors.As(err, &chunkErr) || chunkErr.Index != 2 {
+		t.Errorf("expected ChunkError for chunk 2, got %v", err)
+	}
+	if !bytes.Equal(buf, make([]byte, len(buf))) {
+		t.Errorf("partial plaintext left in buffer")
+	}
+
+	out := filepath.Join(dir, "out")
+	if err := stream.DecryptFileMapped(testKey, name, out); !errors.As(err, &chunkErr) {
+		t.Errorf("expected ChunkError, got %v", err)
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Errorf("destination created for corrupted file: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
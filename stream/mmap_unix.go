@@ -0,0 +1,38 @@
+//go:build unix
+
+package stream
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mapFile maps the first size bytes of f into memory, and returns them and a
+// function that flushes any changes and unmaps them.
+func mapFile(f *os.File, size int64, writable bool) ([]byte, func() error, error) {
+	if size == 0 {
+		return nil, func() error { return nil }, nil
+	}
+	if int64(int(size)) != size {
+		return nil, nil, errors.New("stream: file too large to map")
+	}
+	prot := unix.PROT_READ
+	if writable {
+		prot |= unix.PROT_WRITE
+	}
+	b, err := unix.Mmap(int(f.Fd()), 0, int(size), prot, unix.MAP_SHARED)
+	if err != nil {
+		return nil, nil, &os.PathError{Op: "mmap", Path: f.Name(), Err: err}
+	}
+	return b, func() error {
+		if writable {
+			if err := unix.Msync(b, unix.MS_SYNC); err != nil {
+				unix.Munmap(b)
+				return &os.PathError{Op: "msync", Path: f.Name(), Err: err}
+			}
+		}
+		return unix.Munmap(b)
+	}, nil
+}
@@ -0,0 +1,104 @@
+package stream
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Formats of the messages produced by [SealTo], identified by their first byte.
+const (
+	singleShotFormat = 0x00
+	chunkedFormat    = 0x01
+)
+
+// SealTo encrypts plaintext and additionalData with key, and writes the
+// message to w. It's a convenience for the common case of encrypting a buffer
+// to a file or connection in one call.
+//
+// Plaintexts of up to [ChunkSize] bytes are encrypted as a single XAES-256-GCM
+// message with a random nonce, and larger ones as a stream, with
+// additionalData authenticated with every chunk. Either way, the message
+// starts with a byte that identifies its format, and must be read back with
+// [OpenFrom]. The format byte is authenticated along with additionalData, so
+// a message can't be reinterpreted as the other format.
+func SealTo(w io.Writer, key, plaintext, additionalData []byte) error {
+	if len(plaintext) <= ChunkSize {
+		aead, err := xaes256gcm.New(key)
+		if err != nil {
+			return err
+		}
+		msg := make([]byte, 1, 1+len(plaintext)+aead.Overhead())
+		msg[0] = singleShotFormat
+		ad := formatAD(singleShotFormat, additionalData)
+		_, err = w.Write(aead.Seal(msg, nil, plaintext, ad))
+		return err
+	}
+	if _, err := w.Write([]byte{chunkedFormat}); err != nil {
+		return err
+	}
+	sw, err := NewWriter(key, w)
+	if err != nil {
+		return err
+	}
+	ad := formatAD(chunkedFormat, additionalData)
+	sw.SetChunkAD(func(uint64) []byte { return ad })
+	if _, err := sw.Write(plaintext); err != nil {
+		return err
+	}
+	return sw.Close()
+}
+
+// OpenFrom reads a message written by [SealTo] from r until EOF, and decrypts
+// and authenticates it with key and additionalData. It returns no plaintext
+// if any part of the message is corrupted or truncated.
+func OpenFrom(r io.Reader, key, additionalData []byte) ([]byte, error) {
+	var format [1]byte
+	if _, err := io.ReadFull(r, format[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	switch format[0] {
+	case singleShotFormat:
+		aead, err := xaes256gcm.New(key)
+		if err != nil {
+			return nil, err
+		}
+		// The single-shot format is at most a chunk long, so bound the read
+		// to avoid buffering an arbitrarily large input.
+		msg, err := io.ReadAll(io.LimitReader(r, ChunkSize+int64(aead.Overhead())+1))
+		if err != nil {
+			return nil, err
+		}
+		if len(msg) > ChunkSize+aead.Overhead() {
+			return nil, errors.New("stream: message too long")
+		}
+		return aead.Open(nil, nil, msg, formatAD(singleShotFormat, additionalData))
+	case chunkedFormat:
+		sr, err := NewReader(key, r)
+		if err != nil {
+			return nil, err
+		}
+		ad := formatAD(chunkedFormat, additionalData)
+		sr.SetChunkAD(func(uint64) []byte { return ad })
+		var buf bytes.Buffer
+		if _, err := buf.ReadFrom(sr); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, errors.New("stream: unknown message format")
+	}
+}
+
+// formatAD returns the additional data used for messages of the given format,
+// which is the format byte followed by the caller's additional data. Otherwise,
+// the first chunk of a chunked message could be opened as a single-shot
+// message, silently truncating the plaintext.
+func formatAD(format byte, additionalData []byte) []byte {
+	return append([]byte{format}, additionalData...)
+}
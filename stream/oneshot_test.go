@@ -0,0 +1,65 @@
+package stream_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+
+	"filippo.io/xaes256gcm/stream"
+)
+
+func TestSealToOpenFrom(t *testing.T) {
+	for _, size := range []int{0, 100, stream.ChunkSize, stream.ChunkSize + 1, 3 * stream.ChunkSize} {
+		plaintext := make([]byte, size)
+		rand.Read(plaintext)
+		var buf bytes.Buffer
+		if err := stream.SealTo(&buf, testKey, plaintext, []byte("ad")); err != nil {
+			t.Fatal(err)
+		}
+		msg := buf.Bytes()
+		if wantChunked := size > stream.ChunkSize; (msg[0] == 0x01) != wantChunked {
+			t.Errorf("%d: unexpected format byte %#x", size, msg[0])
+		}
+
+		got, err := stream.OpenFrom(bytes.NewReader(msg), testKey, []byte("ad"))
+		if err != nil || !bytes.Equal(got, plaintext) {
+			t.Errorf("%d: got %d bytes, %v", size, len(got), err)
+		}
+		if _, err := stream.OpenFrom(bytes.NewReader(msg), testKey, []byte("other")); err == nil {
+			t.Errorf("%d: wrong additional data accepted", size)
+		}
+		if _, err := stream.OpenFrom(bytes.NewReader(msg[:len(msg)-1]), testKey, []byte("ad")); err == nil {
+			t.Errorf("%d: truncated message accepted", size)
+		}
+		swapped := bytes.Clone(msg)
+		swapped[0] ^= 1
+		if _, err := stream.OpenFrom(bytes.NewReader(swapped), testKey, []byte("ad")); err == nil {
+			t.Errorf("%d: message with swapped format accepted", size)
+		}
+	}
+	if _, err := stream.OpenFrom(bytes.NewReader(nil), testKey, nil); err == nil {
+		t.Errorf("empty message accepted")
+	}
+}
+
+func TestOpenFromRewrappedChunk(t *testing.T) {
+	plaintext := make([]byte, 3*stream.ChunkSize)
+	rand.Read(plaintext)
+	var buf bytes.Buffer
+	if err := stream.SealTo(&buf, testKey, plaintext, []byte("ad")); err != nil {
+		t.Fatal(err)
+	}
+	msg := buf.Bytes()
+
+	// Rewrap the first chunk as a single-shot message, whose nonce is the
+	// stream's nonce prefix followed by the first chunk's counter and flags.
+	header := msg[1 : 1+stream.HeaderSize]
+	chunk := msg[1+stream.HeaderSize : 1+stream.HeaderSize+stream.ChunkSize+16]
+	forged := []byte{0x00}
+	forged = append(forged, header...)
+	forged = append(forged, make([]byte, 12)...)
+	forged = append(forged, chunk...)
+	if got, err := stream.OpenFrom(bytes.NewReader(forged), testKey, []byte("ad")); err == nil {
+		t.Errorf("rewrapped chunk accepted, got %d bytes", len(got))
+	}
+}
@@ -0,0 +1,163 @@
+package stream
+
+import (
+	"context"
+	"crypto/rand"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"filippo.io/xaes256gcm"
+)
+
+// runParallel calls f for each chunk index in [0, chunks) from n goroutines,
+// stopping at the first error or when ctx is canceled.
+func runParallel(ctx context.Context, chunks int64, n int, f func(i int64, buf, in []byte) error) error {
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+	var failed atomic.Bool
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			buf, in := make([]byte, ChunkSize), make([]byte, encChunkSize)
+			for !failed.Load() {
+				i := next.Add(1) - 1
+				if i >= chunks {
+					return
+				}
+				err := ctx.Err()
+				if err == nil {
+					err = f(i, buf, in)
+				}
+				if err != nil {
+					once.Do(func() { firstErr = err })
+					failed.Store(true)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// progressCounter serializes calls to a progress function from multiple
+// goroutines.
+type progressCounter struct {
+	mu        sync.Mutex
+	f         func(processed, total int64)
+	processed int64
+	total     int64
+}
+
+func (p *progressCounter) add(n int) {
+	if p.f == nil || n == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.processed += int64(n)
+	p.f(p.processed, p.total)
+}
+
+func chunkNonce(prefix []byte, i, chunks int64) []byte {
+	var n nonce
+	copy(n[:], prefix)
+	n.setCounter(uint64(i))
+	n.setFlags(i == chunks-1, 0)
+	return n[:]
+}
+
+// isPlainStream reports whether the first chunk of the stream of size size
+// read from in decrypts with no nonce flags other than the last chunk flag,
+// which is the layout assumed by the parallel and mapped decryption paths.
+// Compressed streams, and corrupted ones, are left to the serial [Reader],
+// which detects the flags or reports the error.
+func isPlainStream(key []byte, in io.ReaderAt, size int64) bool {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return false
+	}
+	total, err := DecryptedSize(size)
+	if err != nil {
+		return false
+	}
+	buf := make([]byte, HeaderSize+min(ChunkSize, total)+xaes256gcm.OverheadWithManualNonces)
+	if n, _ := in.ReadAt(buf, 0); n < len(buf) {
+		return false
+	}
+	chunks := max(1, (total+ChunkSize-1)/ChunkSize)
+	_, err = aead.Open(nil, chunkNonce(buf[:HeaderSize], 0, chunks), buf[HeaderSize:], nil)
+	return err == nil
+}
+
+func encryptParallel(ctx context.Context, key []byte, in io.ReaderAt, out io.WriterAt, size int64,
+	concurrency int, progress func(processed, total int64)) error {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return err
+	}
+	prefix := make([]byte, HeaderSize)
+	if _, err := rand.Read(prefix); err != nil {
+		return err
+	}
+	if _, err := out.WriteAt(prefix, 0); err != nil {
+		return err
+	}
+	chunks := max(1, (size+ChunkSize-1)/ChunkSize)
+	p := &progressCounter{f: progress, total: size}
+	return runParallel(ctx, chunks, concurrency, func(i int64, buf, ciphertext []byte) error {
+		plaintext := buf[:min(ChunkSize, size-i*ChunkSize)]
+		if n, err := in.ReadAt(plaintext, i*ChunkSize); n < len(plaintext) {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		ciphertext = aead.Seal(ciphertext[:0], chunkNonce(prefix, i, chunks), plaintext, nil)
+		if _, err := out.WriteAt(ciphertext, HeaderSize+i*encChunkSize); err != nil {
+			return err
+		}
+		p.add(len(plaintext))
+		return nil
+	})
+}
+
+func decryptParallel(ctx context.Context, key []byte, in io.ReaderAt, out io.WriterAt, size int64,
+	concurrency int, progress func(processed, total int64)) error {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return err
+	}
+	total, err := DecryptedSize(size)
+	if err != nil {
+		return err
+	}
+	prefix := make([]byte, HeaderSize)
+	if _, err := in.ReadAt(prefix, 0); err != nil {
+		return err
+	}
+	chunks := max(1, (total+ChunkSize-1)/ChunkSize)
+	p := &progressCounter{f: progress, total: total}
+	return runParallel(ctx, chunks, concurrency, func(i int64, buf, ciphertext []byte) error {
+		ciphertext = ciphertext[:min(ChunkSize, total-i*ChunkSize)+xaes256gcm.OverheadWithManualNonces]
+		if n, err := in.ReadAt(ciphertext, HeaderSize+i*encChunkSize); n < len(ciphertext) {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return err
+		}
+		plaintext, err := aead.Open(buf[:0], chunkNonce(prefix, i, chunks), ciphertext, nil)
+		if err != nil {
+			return &ChunkError{Index: uint64(i)}
+		}
+		if _, err := out.WriteAt(plaintext, i*ChunkSize); err != nil {
+			return err
+		}
+		p.add(len(plaintext))
+		return nil
+	})
+}
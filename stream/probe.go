@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"strings"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Format is a set of ciphertext formats produced by this module.
+type Format uint
+
+const (
+	// FormatAuto is the output of the Seal method of an AEAD returned by
+	// [xaes256gcm.New]: the 24-byte nonce followed by the ciphertext.
+	FormatAuto Format = 1 << iota
+
+	// FormatCanonical is the self-describing envelope produced by
+	// [xaes256gcm.Marshal], which starts with [xaes256gcm.Magic].
+	FormatCanonical
+
+	// FormatStream is a chunked stream produced by [Writer], without
+	// metadata.
+	FormatStream
+
+	// FormatMessage is a message produced by [SealTo].
+	FormatMessage
+)
+
+// String returns the names of the formats in f, separated by "|".
+func (f Format) String() string {
+	var names []string
+	for _, n := range []struct {
+		f    Format
+		name string
+	}{
+		{FormatAuto, "auto"},
+		{FormatCanonical, "canonical"},
+		{FormatStream, "stream"},
+		{FormatMessage, "message"},
+	} {
+		if f&n.f != 0 {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "unknown"
+	}
+	return strings.Join(names, "|")
+}
+
+// Probe returns the set of formats data could be in, judging only from its
+// size and any fixed header, without the key. It returns zero if data is not
+// in any of them.
+//
+// Only the canonical format has a magic string, and when it's found no other
+// format is reported. The other formats look random, so a blob can often be
+// in more than one of them, and only decryption can tell. Streams with
+// metadata are not recognized.
+func Probe(data []byte) Format {
+	if len(data) >= xaes256gcm.FormatOverhead && string(data[:len(xaes256gcm.Magic)]) == xaes256gcm.Magic &&
+		data[len(xaes256gcm.Magic)] == xaes256gcm.FormatVersion {
+		return FormatCanonical
+	}
+	var f Format
+	if len(data) >= xaes256gcm.Overhead {
+		f |= FormatAuto
+	}
+	if _, err := DecryptedSize(int64(len(data))); err == nil {
+		f |= FormatStream
+	}
+	if len(data) > 0 {
+		switch body := data[1:]; data[0] {
+		case singleShotFormat:
+			if len(body) >= xaes256gcm.Overhead && len(body) <= ChunkSize+xaes256gcm.Overhead {
+				f |= FormatMessage
+			}
+		case chunkedFormat:
+			if size, err := DecryptedSize(int64(len(body))); err == nil && size > ChunkSize {
+				f |= FormatMessage
+			}
+		}
+	}
+	return f
+}
@@ -0,0 +1,48 @@
+package stream_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/stream"
+)
+
+func TestProbe(t *testing.T) {
+	aead, err := xaes256gcm.New(testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auto := aead.Seal(nil, nil, []byte("hello"), nil)
+	canonical, err := xaes256gcm.Marshal(auto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var message bytes.Buffer
+	if err := stream.SealTo(&message, testKey, make([]byte, 2*stream.ChunkSize), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		data []byte
+		want stream.Format
+	}{
+		"auto":      {auto, stream.FormatAuto},
+		"canonical": {canonical, stream.FormatCanonical},
+		"stream":    {seal(t, make([]byte, 3*stream.ChunkSize)), stream.FormatStream},
+		"message":   {message.Bytes(), stream.FormatMessage},
+		"short":     {make([]byte, 10), 0},
+	} {
+		got := stream.Probe(tc.data)
+		if got&tc.want != tc.want || tc.want == 0 && got != 0 {
+			t.Errorf("%s: got %v, expected %v", name, got, tc.want)
+		}
+		if tc.want == stream.FormatCanonical && got != tc.want {
+			t.Errorf("%s: got %v, expected only %v", name, got, tc.want)
+		}
+	}
+
+	if s := (stream.FormatAuto | stream.FormatStream).String(); s != "auto|stream" {
+		t.Errorf("unexpected String %q", s)
+	}
+}
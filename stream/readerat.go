@@ -0,0 +1,149 @@
+package stream
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+	"sync"
+
+	"filippo.io/xaes256gcm"
+)
+
+// EncryptedSize returns the size of a stream produced by [Writer] or
+// [NewEncryptingReader] for a plaintext of size plaintextSize, including the
+// header. It doesn't apply to compressed streams or streams with metadata.
+func EncryptedSize(plaintextSize int64) int64 {
+	chunks := max(1, (plaintextSize+ChunkSize-1)/ChunkSize)
+	return HeaderSize + plaintextSize + chunks*xaes256gcm.OverheadWithManualNonces
+}
+
+// DecryptedSize returns the size of the plaintext of a stream of size
+// encryptedSize, or an error if no valid stream has that size. It's the inverse
+// of [EncryptedSize].
+func DecryptedSize(encryptedSize int64) (int64, error) {
+	const overhead = xaes256gcm.OverheadWithManualNonces
+	body := encryptedSize - HeaderSize
+	if body < overhead {
+		return 0, errors.New("stream: encrypted stream is too short")
+	}
+	chunks := (body + encChunkSize - 1) / encChunkSize
+	last := body - (chunks-1)*encChunkSize
+	if last < overhead || chunks > 1 && last == overhead {
+		return 0, errors.New("stream: invalid encrypted stream size")
+	}
+	return body - chunks*overhead, nil
+}
+
+// ReaderAt provides random access to the plaintext of a stream, decrypting
+// only the chunks that are read.
+//
+// Since the size of the stream is known in advance, the last chunk is
+// identified by its position, and truncation is still detected.
+//
+// Compressed streams don't support random access, and fail to decrypt.
+type ReaderAt struct {
+	a      cipher.AEAD
+	src    io.ReaderAt
+	size   int64
+	chunks int64
+	prefix [HeaderSize]byte
+
+	mu      sync.Mutex
+	cached  int64 // index of the chunk in buf, or -1
+	buf, in []byte
+}
+
+// NewReaderAt returns a ReaderAt for the stream of size encryptedSize read
+// from src. It reads the stream header from src, and if the plaintext is empty,
+// authenticates the only chunk.
+func NewReaderAt(key []byte, src io.ReaderAt, encryptedSize int64) (*ReaderAt, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	size, err := DecryptedSize(encryptedSize)
+	if err != nil {
+		return nil, err
+	}
+	r := &ReaderAt{
+		a:      aead,
+		src:    src,
+		size:   size,
+		chunks: (encryptedSize - HeaderSize + encChunkSize - 1) / encChunkSize,
+		cached: -1,
+		buf:    make([]byte, ChunkSize),
+		in:     make([]byte, encChunkSize),
+	}
+	if n, err := src.ReadAt(r.prefix[:], 0); n < HeaderSize {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	// An empty stream has no plaintext to read, so its only chunk would never
+	// be authenticated by ReadAt.
+	if size == 0 {
+		if _, err := r.readChunk(0); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Size returns the size of the plaintext.
+func (r *ReaderAt) Size() int64 {
+	return r.size
+}
+
+// ReadAt implements [io.ReaderAt]. It is safe for concurrent use.
+func (r *ReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	if off < 0 {
+		return 0, errors.New("stream: negative offset")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for n < len(p) && off < r.size {
+		chunk, err := r.readChunk(off / ChunkSize)
+		if err != nil {
+			return n, err
+		}
+		c := copy(p[n:], chunk[off%ChunkSize:])
+		n += c
+		off += int64(c)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (r *ReaderAt) readChunk(i int64) ([]byte, error) {
+	if r.cached == i {
+		return r.buf, nil
+	}
+	r.cached = -1
+
+	off := HeaderSize + i*encChunkSize
+	in := r.in
+	if i == r.chunks-1 {
+		in = in[:r.size-i*ChunkSize+int64(r.a.Overhead())]
+	}
+	if n, err := r.src.ReadAt(in, off); n < len(in) {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+
+	var n nonce
+	copy(n[:], r.prefix[:])
+	n.setCounter(uint64(i))
+	n.setFlags(i == r.chunks-1, 0)
+	out, err := r.a.Open(r.buf[:0], n[:], in, nil)
+	if err != nil {
+		return nil, &ChunkError{Index: uint64(i)}
+	}
+	r.buf = out
+	r.cached = i
+	return out, nil
+}
@@ -0,0 +1,83 @@
+package stream
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"filippo.io/xaes256gcm"
+)
+
+const stateAD = "filippo.io/xaes256gcm/stream resume state"
+
+// Offsets returns the number of plaintext bytes that were encrypted and
+// written to the destination so far, and the number of bytes written to it,
+// including the header.
+//
+// The last chunk written by Write is always kept buffered until more data or
+// Close, so Offsets might lag behind the data passed to Write by up to
+// ChunkSize bytes.
+func (w *Writer) Offsets() (plaintext, ciphertext int64) {
+	chunks := int64(w.nonce.counter())
+	return chunks * ChunkSize, HeaderSize + chunks*encChunkSize
+}
+
+// State returns an encrypted and authenticated serialization of the state of
+// the Writer at the last chunk written to the destination, which can be passed
+// to [ResumeWriter] to continue the stream.
+//
+// Resuming reuses the chunk nonces that follow the state, so the plaintext
+// after the state must be exactly the same as the one that was passed to the
+// original Writer, if any of its chunks might have been written to the
+// destination. Otherwise, the confidentiality and authenticity of the stream
+// are lost.
+//
+// State returns an error if the Writer was closed, if it failed, or if it is
+// compressed or has metadata.
+func (w *Writer) State() ([]byte, error) {
+	if w.err != nil {
+		return nil, w.err
+	}
+	if w.flags != 0 {
+		return nil, errors.New("stream: compressed or metadata Writer can't be resumed")
+	}
+	state := make([]byte, xaes256gcm.NonceSize, xaes256gcm.NonceSize+HeaderSize+8+w.a.Overhead())
+	if _, err := rand.Read(state); err != nil {
+		return nil, err
+	}
+	plaintext := append(w.nonce[:HeaderSize:HeaderSize], make([]byte, 8)...)
+	binary.BigEndian.PutUint64(plaintext[HeaderSize:], w.nonce.counter())
+	return w.a.Seal(state, state, plaintext, []byte(stateAD)), nil
+}
+
+// ResumeWriter returns a Writer that continues the stream serialized by
+// [Writer.State], writing to dst.
+//
+// dst must be positioned at the ciphertext offset returned by
+// [Writer.Offsets] on the returned Writer, and the next call to Write must
+// start with the plaintext at the plaintext offset. See [Writer.State] for the
+// safety requirements of resuming a stream.
+func ResumeWriter(key, state []byte, dst io.Writer) (*Writer, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(state) < xaes256gcm.NonceSize {
+		return nil, errors.New("stream: invalid resume state")
+	}
+	plaintext, err := aead.Open(nil, state[:xaes256gcm.NonceSize],
+		state[xaes256gcm.NonceSize:], []byte(stateAD))
+	if err != nil || len(plaintext) != HeaderSize+8 {
+		return nil, errors.New("stream: invalid resume state")
+	}
+	w := &Writer{
+		a:   aead,
+		dst: dst,
+		buf: make([]byte, encChunkSize),
+	}
+	w.unwritten = w.buf[:0]
+	copy(w.nonce[:HeaderSize], plaintext)
+	w.nonce.setCounter(binary.BigEndian.Uint64(plaintext[HeaderSize:]))
+	return w, nil
+}
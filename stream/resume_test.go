@@ -0,0 +1,72 @@
+package stream_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm/stream"
+)
+
+func TestResume(t *testing.T) {
+	plaintext := make([]byte, 5*stream.ChunkSize+123)
+	for i := range plaintext {
+		plaintext[i] = byte(i)
+	}
+
+	buf := &bytes.Buffer{}
+	w, err := stream.NewWriter(testKey, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext[:3*stream.ChunkSize+10]); err != nil {
+		t.Fatal(err)
+	}
+	state, err := w.State()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate writing a bit more before being interrupted.
+	if _, err := w.Write(plaintext[3*stream.ChunkSize+10 : 4*stream.ChunkSize+10]); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err = stream.ResumeWriter(testKey, state, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ptOff, ctOff := w.Offsets()
+	if ptOff != 3*stream.ChunkSize {
+		t.Errorf("plaintext offset %d, expected %d", ptOff, 3*stream.ChunkSize)
+	}
+	ciphertext := buf.Bytes()[:ctOff]
+	buf = bytes.NewBuffer(ciphertext)
+	w, err = stream.ResumeWriter(testKey, state, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext[ptOff:]); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := open(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("plaintext and decrypted are not equal")
+	}
+
+	if _, err := stream.ResumeWriter(testKey, state[:len(state)-1], nil); err == nil {
+		t.Errorf("truncated state accepted")
+	}
+	state[0] ^= 1
+	if _, err := stream.ResumeWriter(testKey, state, nil); err == nil {
+		t.Errorf("modified state accepted")
+	}
+	if _, err := w.State(); err == nil {
+		t.Errorf("State succeeded after Close")
+	}
+}
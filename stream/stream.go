@@ -0,0 +1,566 @@
+// Package stream implements a chunked encryption format based on
+// XAES-256-GCM, for messages too large to be held in memory.
+//
+// A stream starts with a 12-byte random nonce prefix. It is followed by the
+// plaintext split into chunks of [ChunkSize] bytes, each encrypted with
+// XAES-256-GCM. The 24-byte nonce of each chunk is the nonce prefix, followed
+// by an 11-byte big-endian chunk counter, and by a byte set to 0x01 for the
+// last chunk and to 0x00 for every other chunk, like in the STREAM
+// construction. The last chunk may be shorter than ChunkSize, but it's empty
+// only if it's also the first, which makes truncation and reordering of chunks
+// detectable.
+//
+// Since the nonce prefix is the first half of the XAES-256-GCM nonce, each
+// stream uses a single derived AES-256-GCM key.
+//
+// Streams produced by [NewCompressedWriter] have the 0x02 bit also set in the
+// last byte of every nonce, and their plaintext is compressed with DEFLATE.
+// Streams produced by [NewWriterWithMetadata] have the 0x04 bit set, and carry
+// a metadata record after the header.
+package stream
+
+import (
+	"compress/flate"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"filippo.io/xaes256gcm"
+)
+
+// ChunkSize is the size of the plaintext of every chunk except the last.
+const ChunkSize = 64 * 1024
+
+// HeaderSize is the size of the stream header, which is the random nonce
+// prefix.
+const HeaderSize = 12
+
+const (
+	encChunkSize   = ChunkSize + xaes256gcm.OverheadWithManualNonces
+	lastChunkFlag  = 0x01
+	compressedFlag = 0x02
+)
+
+// ChunkError is returned when a chunk of a stream fails to decrypt and
+// authenticate, because the stream was corrupted, tampered with, or encrypted
+// with a different key. The plaintext of the preceding chunks, if any, is valid.
+type ChunkError struct {
+	// Index is the index of the chunk, starting at zero. The chunk's plaintext
+	// would start at offset Index * ChunkSize.
+	Index uint64
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("stream: failed to decrypt and authenticate chunk %d", e.Index)
+}
+
+type nonce [xaes256gcm.NonceSize]byte
+
+func (n *nonce) increment() {
+	for i := len(n) - 2; i >= HeaderSize; i-- {
+		n[i]++
+		if n[i] != 0 {
+			break
+		} else if i == HeaderSize {
+			// The counter is 88 bits, this is unreachable.
+			panic("stream: chunk counter wrapped around")
+		}
+	}
+}
+
+func (n *nonce) setCounter(c uint64) {
+	for i := len(n) - 2; i >= HeaderSize; i-- {
+		n[i] = byte(c)
+		c >>= 8
+	}
+}
+
+func (n *nonce) counter() uint64 {
+	var c uint64
+	for _, b := range n[len(n)-9 : len(n)-1] {
+		c = c<<8 | uint64(b)
+	}
+	return c
+}
+
+func (n *nonce) setFlags(last bool, flags byte) {
+	if last {
+		flags |= lastChunkFlag
+	}
+	n[len(n)-1] = flags
+}
+
+func (n *nonce) isFirst() bool {
+	for _, b := range n[HeaderSize : len(n)-1] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Reader decrypts a stream produced by a [Writer]. Compressed streams are
+// detected automatically, and decompressed.
+//
+// Each chunk is authenticated before its plaintext is returned. If a chunk
+// fails to decrypt, Read returns a [*ChunkError], after returning the
+// plaintext of the preceding chunks, unless partial output is disabled with
+// [Reader.SetPartialOutput].
+type Reader struct {
+	a   cipher.AEAD
+	src io.Reader
+
+	unread []byte // decrypted but unread data, backed by buf
+	buf    []byte
+	in     []byte
+
+	err   error
+	nonce nonce
+
+	started    bool
+	flags      byte // nonce flags other than lastChunkFlag
+	compressed bool
+	zr         io.ReadCloser
+	zerr       error
+
+	progress  func(int64)
+	processed int64
+	chunkAD   func(index uint64) []byte
+
+	noPartial bool
+	all       []byte // buffered plaintext, if noPartial
+	allErr    error
+	allRead   bool
+}
+
+// NewReader returns a Reader that decrypts the stream read from src. It reads
+// the stream header from src.
+func NewReader(key []byte, src io.Reader) (*Reader, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{
+		a:   aead,
+		src: src,
+		buf: make([]byte, ChunkSize),
+		in:  make([]byte, encChunkSize),
+	}
+	if _, err := io.ReadFull(src, r.nonce[:HeaderSize]); err == io.EOF {
+		return nil, io.ErrUnexpectedEOF
+	} else if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// SetProgress sets a function that is called after each Read that returns
+// data, with the total number of plaintext bytes returned so far.
+func (r *Reader) SetProgress(f func(processed int64)) {
+	r.progress = f
+}
+
+// SetChunkAD sets a function that returns the additional data of each chunk,
+// like [Writer.SetChunkAD]. It must be called before the first Read, with a
+// function that returns the same additional data the Writer used.
+func (r *Reader) SetChunkAD(f func(index uint64) []byte) {
+	r.chunkAD = f
+}
+
+// SetPartialOutput sets whether Read returns the plaintext of the chunks
+// preceding a failure. It must be called before the first Read.
+//
+// By default, partial output is allowed: the plaintext of each chunk is
+// returned as soon as it's authenticated, and a failure is reported when it's
+// reached, as a [*ChunkError] for a chunk that fails to decrypt, or as
+// [io.ErrUnexpectedEOF] for a truncated stream. All plaintext returned before
+// the error is authentic, which suits recovery tools.
+//
+// If partial output is disallowed, the first Read decrypts the whole stream
+// into memory, and returns an error without any plaintext if any part of the
+// stream fails, which suits strict pipelines that must not act on incomplete
+// data. Memory usage is then proportional to the size of the stream; for large
+// files, consider [DecryptFile], which never exposes partial output.
+func (r *Reader) SetPartialOutput(allowed bool) {
+	r.noPartial = !allowed
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	var n int
+	var err error
+	if r.noPartial {
+		n, err = r.readBuffered(p)
+	} else {
+		n, err = r.readAny(p)
+	}
+	if n > 0 && r.progress != nil {
+		r.processed += int64(n)
+		r.progress(r.processed)
+	}
+	return n, err
+}
+
+// readBuffered decrypts the whole stream on the first call, and then returns
+// its plaintext, or only the error if decryption failed.
+func (r *Reader) readBuffered(p []byte) (int, error) {
+	if !r.allRead {
+		r.allRead = true
+		buf := make([]byte, ChunkSize)
+		for {
+			n, err := r.readAny(buf)
+			r.all = append(r.all, buf[:n]...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				r.all, r.allErr = nil, err
+				break
+			}
+		}
+	}
+	if r.allErr != nil {
+		return 0, r.allErr
+	}
+	if len(r.all) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.all)
+	r.all = r.all[n:]
+	return n, nil
+}
+
+func (r *Reader) readAny(p []byte) (int, error) {
+	if !r.started {
+		// Whether the stream is compressed is only known after decrypting
+		// the first chunk.
+		r.started = true
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+		if r.compressed {
+			r.zr = flate.NewReader(chunkReader{r})
+		}
+	}
+	if r.zr != nil {
+		return r.readCompressed(p)
+	}
+	return r.read(p)
+}
+
+func (r *Reader) readCompressed(p []byte) (int, error) {
+	if r.zerr != nil {
+		return 0, r.zerr
+	}
+	n, err := r.zr.Read(p)
+	if err == io.EOF {
+		// The compressed data must end exactly with the stream, for the last
+		// chunk to be checked and for trailing data to be rejected.
+		if _, err := r.read(make([]byte, 1)); err == nil {
+			r.zerr = errors.New("stream: trailing data after end of compressed data")
+		} else if err != io.EOF {
+			r.zerr = err
+		} else {
+			r.zerr = io.EOF
+		}
+		return n, r.zerr
+	}
+	if err != nil {
+		r.zerr = err
+	}
+	return n, err
+}
+
+// chunkReader exposes the decrypted but not decompressed stream. It
+// implements io.ByteReader so that flate doesn't read ahead of the end of the
+// compressed data.
+type chunkReader struct{ r *Reader }
+
+func (c chunkReader) Read(p []byte) (int, error) { return c.r.read(p) }
+
+func (c chunkReader) ReadByte() (byte, error) {
+	var b [1]byte
+	for {
+		n, err := c.r.read(b[:])
+		if n == 1 {
+			return b[0], nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+func (r *Reader) read(p []byte) (int, error) {
+	if len(r.unread) > 0 {
+		n := copy(p, r.unread)
+		r.unread = r.unread[n:]
+		return n, nil
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	if err := r.fill(); err != nil {
+		return 0, err
+	}
+
+	n := copy(p, r.unread)
+	r.unread = r.unread[n:]
+	return n, nil
+}
+
+// fill decrypts the next chunk into r.unread, and if it's the last chunk
+// checks that the stream ends after it, setting r.err.
+func (r *Reader) fill() error {
+	last, err := r.readChunk()
+	if err != nil {
+		r.err = err
+		return err
+	}
+
+	if last {
+		// Ensure there is an EOF after the last chunk as expected. In other
+		// words, check for trailing data after a full-length final chunk.
+		if _, err := r.src.Read(make([]byte, 1)); err == nil {
+			r.err = errors.New("stream: trailing data after end of encrypted stream")
+		} else if err != io.EOF {
+			r.err = fmt.Errorf("stream: non-EOF error reading after end of encrypted stream: %w", err)
+		} else {
+			r.err = io.EOF
+		}
+	}
+
+	return nil
+}
+
+// readChunk reads the next chunk of ciphertext from r.src and makes it
+// available in r.unread. last is true if the chunk was marked as the end of the
+// stream. readChunk must not be called again after returning a last chunk or
+// an error.
+func (r *Reader) readChunk() (last bool, err error) {
+	if len(r.unread) != 0 {
+		panic("stream: internal error: readChunk called with dirty buffer")
+	}
+
+	in := r.in
+	n, err := io.ReadFull(r.src, in)
+	switch {
+	case err == io.EOF:
+		// A stream can't end without a marked chunk. This stream is truncated.
+		return false, io.ErrUnexpectedEOF
+	case err == io.ErrUnexpectedEOF:
+		// The last chunk can be short, but not empty unless it's the first and
+		// only chunk.
+		if !r.nonce.isFirst() && n == r.a.Overhead() {
+			return false, errors.New("stream: last chunk is empty")
+		}
+		in = in[:n]
+		last = true
+	case err != nil:
+		return false, err
+	}
+
+	out, err := r.open(in, last)
+	if err != nil && !last {
+		// Check if this was a full-length final chunk.
+		last = true
+		out, err = r.open(in, last)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	r.nonce.increment()
+	r.unread = out
+	return last, nil
+}
+
+// open decrypts a chunk. For the first chunk, it tries both the compressed and
+// uncompressed nonce flags, and sets r.compressed accordingly.
+func (r *Reader) open(in []byte, last bool) ([]byte, error) {
+	candidates := []byte{r.flags}
+	if r.nonce.isFirst() {
+		candidates = []byte{r.flags, r.flags | compressedFlag}
+	}
+	var ad []byte
+	if r.chunkAD != nil {
+		ad = r.chunkAD(r.nonce.counter())
+	}
+	for _, flags := range candidates {
+		r.nonce.setFlags(last, flags)
+		if out, err := r.a.Open(r.buf[:0], r.nonce[:], in, ad); err == nil {
+			r.flags = flags
+			r.compressed = flags&compressedFlag != 0
+			return out, nil
+		}
+	}
+	return nil, &ChunkError{Index: r.nonce.counter()}
+}
+
+// Verify reads the whole stream from src, and returns an error if any chunk
+// fails to decrypt and authenticate, or if the stream is truncated. It can be
+// used to check the integrity of a stream before processing its plaintext.
+//
+// Note that since the stream is read twice, src could change between Verify
+// and the decryption, and the latter must still check for errors.
+func Verify(key []byte, src io.Reader) error {
+	r, err := NewReader(key, src)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(io.Discard, r)
+	return err
+}
+
+// NewDecryptingReader is like [NewReader], and is provided for symmetry with
+// [NewEncryptingReader].
+func NewDecryptingReader(key []byte, src io.Reader) (io.Reader, error) {
+	return NewReader(key, src)
+}
+
+// Writer encrypts a stream. Writes are buffered and flushed in chunks of
+// [ChunkSize] bytes. Close must be called to write the last chunk.
+type Writer struct {
+	a         cipher.AEAD
+	dst       io.Writer
+	unwritten []byte // backed by buf
+	buf       []byte
+	nonce     nonce
+	err       error
+
+	flags byte // nonce flags other than lastChunkFlag
+	zw    *flate.Writer
+
+	progress  func(int64)
+	processed int64
+	chunkAD   func(index uint64) []byte
+}
+
+// NewWriter returns a Writer that encrypts a stream to dst. It generates a
+// random nonce prefix and writes the stream header to dst.
+func NewWriter(key []byte, dst io.Writer) (*Writer, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	w := &Writer{
+		a:   aead,
+		dst: dst,
+		buf: make([]byte, encChunkSize),
+	}
+	w.unwritten = w.buf[:0]
+	if _, err := rand.Read(w.nonce[:HeaderSize]); err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(w.nonce[:HeaderSize]); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// SetProgress sets a function that is called after each Write, with the total
+// number of plaintext bytes written so far. The last chunk is buffered until
+// more data or Close, so data might not be encrypted yet when f is called.
+func (w *Writer) SetProgress(f func(processed int64)) {
+	w.progress = f
+}
+
+// SetChunkAD sets a function that returns additional data to authenticate
+// with each chunk, given its index, starting at zero. It can bind the stream to
+// application-level structure, for example an object ID and chunk index from
+// an external manifest. It must be called before the first Write.
+//
+// The additional data is not stored in the stream, so the stream can only be
+// decrypted by a [Reader] with a matching [Reader.SetChunkAD] function. A
+// Reader with a different function, or none, fails with a [ChunkError] on
+// the first mismatching chunk.
+func (w *Writer) SetChunkAD(f func(index uint64) []byte) {
+	w.chunkAD = f
+}
+
+func (w *Writer) Write(p []byte) (n int, err error) {
+	if w.zw != nil {
+		if w.err != nil {
+			return 0, w.err
+		}
+		n, err = w.zw.Write(p)
+	} else {
+		n, err = w.write(p)
+	}
+	if n > 0 && w.progress != nil {
+		w.processed += int64(n)
+		w.progress(w.processed)
+	}
+	return n, err
+}
+
+func (w *Writer) write(p []byte) (n int, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	total := len(p)
+	for len(p) > 0 {
+		freeBuf := w.buf[len(w.unwritten):ChunkSize]
+		n := copy(freeBuf, p)
+		p = p[n:]
+		w.unwritten = w.unwritten[:len(w.unwritten)+n]
+
+		// Only flush a full chunk if there is more data, since the last chunk
+		// needs to be marked as such when Close is called.
+		if len(w.unwritten) == ChunkSize && len(p) > 0 {
+			if err := w.flushChunk(false); err != nil {
+				w.err = err
+				return 0, err
+			}
+		}
+	}
+	return total, nil
+}
+
+// Close flushes the last chunk. It does not close the underlying Writer.
+func (w *Writer) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if w.zw != nil {
+		if err := w.zw.Close(); err != nil {
+			w.err = err
+			return err
+		}
+	}
+
+	w.err = w.flushChunk(true)
+	if w.err != nil {
+		return w.err
+	}
+
+	w.err = errors.New("stream: Writer is already closed")
+	return nil
+}
+
+func (w *Writer) flushChunk(last bool) error {
+	if !last && len(w.unwritten) != ChunkSize {
+		panic("stream: internal error: flush called with partial chunk")
+	}
+
+	w.nonce.setFlags(last, w.flags)
+	var ad []byte
+	if w.chunkAD != nil {
+		ad = w.chunkAD(w.nonce.counter())
+	}
+	buf := w.a.Seal(w.buf[:0], w.nonce[:], w.unwritten, ad)
+	_, err := w.dst.Write(buf)
+	w.unwritten = w.buf[:0]
+	w.nonce.increment()
+	return err
+}
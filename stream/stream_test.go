@@ -0,0 +1,239 @@
+package stream_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/stream"
+)
+
+var testKey = bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+
+func seal(t *testing.T, plaintext []byte) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	w, err := stream.NewWriter(testKey, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func open(ciphertext []byte) ([]byte, error) {
+	r, err := stream.NewReader(testKey, bytes.NewReader(ciphertext))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(r)
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, length := range []int{0, 1, 1000, stream.ChunkSize - 1, stream.ChunkSize,
+		stream.ChunkSize + 1, 2 * stream.ChunkSize, 2*stream.ChunkSize + 500} {
+		t.Run(fmt.Sprint(length), func(t *testing.T) {
+			plaintext := make([]byte, length)
+			for i := range plaintext {
+				plaintext[i] = byte(i)
+			}
+			ciphertext := seal(t, plaintext)
+			chunks := max(1, (length+stream.ChunkSize-1)/stream.ChunkSize)
+			if exp := stream.HeaderSize + length + chunks*xaes256gcm.OverheadWithManualNonces; len(ciphertext) != exp {
+				t.Errorf("ciphertext length %d, expected %d", len(ciphertext), exp)
+			}
+			got, err := open(ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plaintext) {
+				t.Errorf("plaintext and decrypted are not equal")
+			}
+
+			if _, err := open(ciphertext[:len(ciphertext)-1]); err == nil {
+				t.Errorf("truncated stream opened")
+			}
+			if _, err := open(append(ciphertext, 0)); err == nil {
+				t.Errorf("stream with trailing data opened")
+			}
+			ciphertext[len(ciphertext)/2] ^= 1
+			if _, err := open(ciphertext); err == nil {
+				t.Errorf("modified stream opened")
+			}
+		})
+	}
+}
+
+func TestTruncatedAtChunkBoundary(t *testing.T) {
+	ciphertext := seal(t, make([]byte, 2*stream.ChunkSize))
+	encChunkSize := stream.ChunkSize + xaes256gcm.OverheadWithManualNonces
+	if _, err := open(ciphertext[:stream.HeaderSize+encChunkSize]); err == nil {
+		t.Errorf("stream truncated at chunk boundary opened")
+	}
+}
+
+func TestChunkError(t *testing.T) {
+	ciphertext := seal(t, make([]byte, 3*stream.ChunkSize))
+	encChunkSize := stream.ChunkSize + xaes256gcm.OverheadWithManualNonces
+	ciphertext[stream.HeaderSize+2*encChunkSize+10] ^= 1
+
+	r, err := stream.NewDecryptingReader(testKey, bytes.NewReader(ciphertext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(r)
+	var chunkErr *stream.ChunkError
+	if !errors.As(err, &chunkErr) {
+		t.Fatalf("got error %v, expected a ChunkError", err)
+	}
+	if chunkErr.Index != 2 {
+		t.Errorf("ChunkError.Index = %d, expected 2", chunkErr.Index)
+	}
+	if len(got) != 2*stream.ChunkSize {
+		t.Errorf("got %d bytes before the error", len(got))
+	}
+
+	ra, err := stream.NewReaderAt(testKey, bytes.NewReader(ciphertext), int64(len(ciphertext)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ra.ReadAt(make([]byte, 10), 2*stream.ChunkSize+5); !errors.As(err, &chunkErr) || chunkErr.Index != 2 {
+		t.Errorf("ReadAt returned %v, expected a ChunkError for chunk 2", err)
+	}
+}
+
+func TestReaderAtForgedEmpty(t *testing.T) {
+	forged := make([]byte, stream.EncryptedSize(0))
+	if _, err := stream.NewReaderAt(testKey, bytes.NewReader(forged), int64(len(forged))); err == nil {
+		t.Errorf("forged empty stream accepted")
+	}
+	empty := seal(t, nil)
+	r, err := stream.NewReaderAt(testKey, bytes.NewReader(empty), int64(len(empty)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, err := r.ReadAt(make([]byte, 1), 0); n != 0 || err != io.EOF {
+		t.Errorf("ReadAt: %d, %v", n, err)
+	}
+}
+
+func TestPartialOutput(t *testing.T) {
+	ciphertext := seal(t, make([]byte, 3*stream.ChunkSize))
+	encChunkSize := stream.ChunkSize + xaes256gcm.OverheadWithManualNonces
+
+	for name, bad := range map[string][]byte{
+		"corrupted": func() []byte {
+			c := bytes.Clone(ciphertext)
+			c[stream.HeaderSize+2*encChunkSize+10] ^= 1
+			return c
+		}(),
+		"truncated": ciphertext[:stream.HeaderSize+2*encChunkSize],
+	} {
+		t.Run(name, func(t *testing.T) {
+			r, err := stream.NewReader(testKey, bytes.NewReader(bad))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err == nil || len(got) != 2*stream.ChunkSize {
+				t.Errorf("partial output: got %d bytes, %v", len(got), err)
+			}
+
+			r, err = stream.NewReader(testKey, bytes.NewReader(bad))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.SetPartialOutput(false)
+			got, err = io.ReadAll(r)
+			if err == nil || len(got) != 0 {
+				t.Errorf("no partial output: got %d bytes, %v", len(got), err)
+			}
+			if _, err2 := r.Read(make([]byte, 10)); err2 != err {
+				t.Errorf("second Read returned %v, expected %v", err2, err)
+			}
+		})
+	}
+
+	r, err := stream.NewReader(testKey, bytes.NewReader(ciphertext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetPartialOutput(false)
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, make([]byte, 3*stream.ChunkSize)) {
+		t.Errorf("no partial output: got %d bytes, %v", len(got), err)
+	}
+}
+
+func TestSizes(t *testing.T) {
+	for _, length := range []int{0, 1, stream.ChunkSize - 1, stream.ChunkSize,
+		stream.ChunkSize + 1, 2 * stream.ChunkSize, 5*stream.ChunkSize + 3} {
+		ciphertext := seal(t, make([]byte, length))
+		if got := stream.EncryptedSize(int64(length)); got != int64(len(ciphertext)) {
+			t.Errorf("EncryptedSize(%d) = %d, expected %d", length, got, len(ciphertext))
+		}
+		if got, err := stream.DecryptedSize(int64(len(ciphertext))); err != nil || got != int64(length) {
+			t.Errorf("DecryptedSize(%d) = %d, %v, expected %d", len(ciphertext), got, err, length)
+		}
+	}
+	for _, size := range []int64{0, stream.HeaderSize, stream.HeaderSize + 15,
+		stream.HeaderSize + stream.ChunkSize + 16 + 16} {
+		if _, err := stream.DecryptedSize(size); err == nil {
+			t.Errorf("DecryptedSize(%d) succeeded", size)
+		}
+	}
+}
+
+func TestChunkAD(t *testing.T) {
+	adFor := func(object string) func(uint64) []byte {
+		return func(index uint64) []byte {
+			return []byte(fmt.Sprintf("%s/%d", object, index))
+		}
+	}
+	plaintext := make([]byte, 2*stream.ChunkSize+100)
+	buf := &bytes.Buffer{}
+	w, err := stream.NewWriter(testKey, buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.SetChunkAD(adFor("object-1"))
+	if _, err := w.Write(plaintext); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := stream.NewReader(testKey, bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetChunkAD(adFor("object-1"))
+	if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("stream with chunk AD failed to decrypt: %v", err)
+	}
+
+	for _, f := range []func(uint64) []byte{nil, adFor("object-2"), func(index uint64) []byte {
+		if index == 2 {
+			return nil
+		}
+		return adFor("object-1")(index)
+	}} {
+		r, err := stream.NewReader(testKey, bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.SetChunkAD(f)
+		var chunkErr *stream.ChunkError
+		if _, err := io.ReadAll(r); !errors.As(err, &chunkErr) {
+			t.Errorf("stream decrypted with the wrong chunk AD: %v", err)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+)
+
+// b64url is the encoding of keys and messages in [EncryptString] and
+// [DecryptString].
+var b64url = base64.RawURLEncoding.Strict()
+
+// GenerateKeyString returns a new random key, encoded as unpadded base64url,
+// for use with [EncryptString] and [DecryptString].
+func GenerateKeyString() string {
+	k := GenerateKey()
+	return b64url.EncodeToString(k.k[:])
+}
+
+// EncryptString encrypts plaintext with key, which must be the unpadded
+// base64url encoding of a 32-byte key, such as one returned by
+// [GenerateKeyString]. It returns the unpadded base64url encoding of the
+// message, including a random nonce, which is safe to use in configuration
+// files, environment variables, and URL parameters.
+//
+// The message is the output of the Seal method of the AEAD returned by [New],
+// so it can also be decoded and decrypted with that AEAD.
+func EncryptString(key, plaintext string) (string, error) {
+	aead, err := stringKey(key)
+	if err != nil {
+		return "", err
+	}
+	return b64url.EncodeToString(aead.Seal(nil, nil, []byte(plaintext), nil)), nil
+}
+
+// DecryptString decrypts a message returned by [EncryptString] with key.
+func DecryptString(key, ciphertext string) (string, error) {
+	aead, err := stringKey(key)
+	if err != nil {
+		return "", err
+	}
+	msg, err := b64url.DecodeString(ciphertext)
+	if err != nil {
+		return "", errors.New("xaes256gcm: message is not valid base64url")
+	}
+	plaintext, err := aead.Open(nil, nil, msg, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+func stringKey(key string) (cipher.AEAD, error) {
+	k, err := b64url.DecodeString(key)
+	if err != nil {
+		return nil, errors.New("xaes256gcm: key is not valid base64url")
+	}
+	return New(k)
+}
@@ -0,0 +1,41 @@
+package xaes256gcm_test
+
+import (
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestEncryptString(t *testing.T) {
+	key := xaes256gcm.GenerateKeyString()
+	if len(key) != 43 {
+		t.Errorf("unexpected key length %d", len(key))
+	}
+	msg, err := xaes256gcm.EncryptString(key, "postgres://user:hunter2@db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.ContainsAny(msg, "+/=") {
+		t.Errorf("message is not unpadded base64url: %q", msg)
+	}
+	if got, err := xaes256gcm.DecryptString(key, msg); err != nil || got != "postgres://user:hunter2@db" {
+		t.Errorf("got %q, %v", got, err)
+	}
+
+	if _, err := xaes256gcm.DecryptString(xaes256gcm.GenerateKeyString(), msg); err == nil {
+		t.Errorf("decrypted with the wrong key")
+	}
+	if _, err := xaes256gcm.DecryptString(key, msg[:len(msg)-2]); err == nil {
+		t.Errorf("decrypted a truncated message")
+	}
+	if _, err := xaes256gcm.DecryptString(key, msg+"="); err == nil {
+		t.Errorf("decrypted a padded message")
+	}
+	if _, err := xaes256gcm.EncryptString(key[:40], "x"); err == nil {
+		t.Errorf("accepted a short key")
+	}
+	if _, err := xaes256gcm.EncryptString(key+"=", "x"); err == nil {
+		t.Errorf("accepted a padded key")
+	}
+}
@@ -0,0 +1,68 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"time"
+)
+
+// timestampSize is the size of the big-endian Unix milliseconds timestamp at
+// the start of nonces generated by NewWithTimestampNonces.
+const timestampSize = 6
+
+// NewWithTimestampNonces is like [New], but the first 6 bytes of each generated
+// nonce are the current time as big-endian Unix milliseconds, and the remaining
+// 18 bytes are random. key must be exactly 32 bytes long.
+//
+// Since the nonce is prepended to the ciphertext, ciphertexts sort by creation
+// time, with millisecond precision, when compared as byte strings. The time can
+// be extracted without the key with [NonceTime], for example to apply retention
+// policies. Note that this reveals when each message was encrypted.
+//
+// The ciphertexts are compatible with [New], and with [NewWithManualNonces] if
+// the nonce is split from the ciphertext. The 18 random bytes are enough to
+// make collisions negligible even for very large numbers of messages encrypted
+// in the same millisecond.
+func NewWithTimestampNonces(key []byte) (cipher.AEAD, error) {
+	x, err := NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	return &timestampNonces{randomNonces{x.(*xaes256gcm), rand.Reader}}, nil
+}
+
+type timestampNonces struct {
+	randomNonces
+}
+
+func (t *timestampNonces) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != 0 {
+		panic("xaes256gcm: non-empty nonce passed to Seal with automatic nonces")
+	}
+
+	ret, n := sliceForAppend(dst, NonceSize)
+	ms := time.Now().UnixMilli()
+	for i := timestampSize - 1; i >= 0; i-- {
+		n[i] = byte(ms)
+		ms >>= 8
+	}
+	if _, err := rand.Read(n[timestampSize:]); err != nil {
+		panic("xaes256gcm: failed to generate random nonce: " + err.Error())
+	}
+	return t.x.Seal(ret, n, plaintext, additionalData)
+}
+
+// NonceTime returns the time encoded in the nonce of a ciphertext produced by
+// an AEAD returned by [NewWithTimestampNonces]. The ciphertext is not
+// authenticated, so the time must not be trusted until it is decrypted.
+func NonceTime(ciphertext []byte) (time.Time, error) {
+	if len(ciphertext) < Overhead {
+		return time.Time{}, errors.New("xaes256gcm: ciphertext too short")
+	}
+	var ms int64
+	for _, b := range ciphertext[:timestampSize] {
+		ms = ms<<8 | int64(b)
+	}
+	return time.UnixMilli(ms), nil
+}
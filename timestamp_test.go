@@ -0,0 +1,56 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestTimestampNonces(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	c, err := xaes256gcm.NewWithTimestampNonces(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.NonceSize() != 0 || c.Overhead() != xaes256gcm.Overhead {
+		t.Errorf("NonceSize() = %d, Overhead() = %d", c.NonceSize(), c.Overhead())
+	}
+
+	before := time.Now().Truncate(time.Millisecond)
+	first := c.Seal([]byte("prefix"), nil, []byte("hello"), []byte("aad"))[len("prefix"):]
+	time.Sleep(2 * time.Millisecond)
+	second := c.Seal(nil, nil, []byte("hello"), []byte("aad"))
+	after := time.Now()
+
+	ts, err := xaes256gcm.NonceTime(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ts.Before(before) || ts.After(after) {
+		t.Errorf("NonceTime = %v, expected between %v and %v", ts, before, after)
+	}
+	if bytes.Compare(first, second) >= 0 {
+		t.Errorf("ciphertexts don't sort by time")
+	}
+
+	a, err := xaes256gcm.New(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, ciphertext := range [][]byte{first, second} {
+		if plaintext, err := c.Open(nil, nil, ciphertext, []byte("aad")); err != nil {
+			t.Fatal(err)
+		} else if string(plaintext) != "hello" {
+			t.Errorf("unexpected plaintext %q", plaintext)
+		}
+		if _, err := a.Open(nil, nil, ciphertext, []byte("aad")); err != nil {
+			t.Errorf("ciphertext not compatible with New: %v", err)
+		}
+	}
+
+	if _, err := xaes256gcm.NonceTime(first[:10]); err == nil {
+		t.Errorf("NonceTime succeeded on short ciphertext")
+	}
+}
@@ -0,0 +1,142 @@
+// Package token implements encrypted, authenticated, expiring tokens, for
+// example for API credentials, password reset links, or session handles.
+//
+// A token is the unpadded base64url encoding of a 1-byte version followed by a
+// ciphertext produced by a [keyset.Keyset], which starts with the 4-byte
+// big-endian ID of the key that encrypted it. For version 0x01, the plaintext
+// is the expiration time, as an 8-byte big-endian Unix timestamp, followed by
+// the payload. Version 0x02, produced by [Codec.IssueNotBefore], adds the
+// not-before time, in the same encoding, before the expiration time. The
+// version is authenticated as additional data.
+//
+// Since the key ID is in the clear, a verifier holding the keys of many
+// tenants in a single Keyset selects the right one without trial decryption.
+// The key ID can also be extracted with [KeyID] to route tokens before
+// verification.
+package token
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"filippo.io/xaes256gcm/keyset"
+)
+
+const (
+	version          = 0x01
+	versionNotBefore = 0x02
+)
+
+// ErrExpired is returned by [Codec.Verify] for authentic but expired tokens.
+var ErrExpired = errors.New("token: expired")
+
+// ErrNotYetValid is returned by [Codec.Verify] for authentic tokens whose
+// not-before time is in the future.
+var ErrNotYetValid = errors.New("token: not yet valid")
+
+var b64 = base64.RawURLEncoding
+
+// Codec issues and verifies tokens. It is safe for concurrent use.
+type Codec struct {
+	// Now, if not nil, is used instead of time.Now to compute and check
+	// expiration and not-before times, for example in tests or in systems with
+	// simulated clocks.
+	Now func() time.Time
+
+	ks *keyset.Keyset
+}
+
+// New returns a Codec that issues tokens with the primary key of ks, and
+// verifies tokens issued by any key in ks. Changes to ks, such as rotations,
+// apply to the Codec.
+func New(ks *keyset.Keyset) *Codec {
+	return &Codec{ks: ks}
+}
+
+func (c *Codec) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Issue returns a token for payload that expires after ttl.
+func (c *Codec) Issue(payload []byte, ttl time.Duration) (string, error) {
+	plaintext := make([]byte, 8, 8+len(payload))
+	binary.BigEndian.PutUint64(plaintext, uint64(c.now().Add(ttl).Unix()))
+	plaintext = append(plaintext, payload...)
+	token, err := c.ks.Seal([]byte{version}, plaintext, []byte{version})
+	if err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(token), nil
+}
+
+// IssueNotBefore returns a token for payload that is valid from notBefore, and
+// expires ttl after it.
+func (c *Codec) IssueNotBefore(payload []byte, notBefore time.Time, ttl time.Duration) (string, error) {
+	plaintext := make([]byte, 16, 16+len(payload))
+	binary.BigEndian.PutUint64(plaintext, uint64(notBefore.Unix()))
+	binary.BigEndian.PutUint64(plaintext[8:], uint64(notBefore.Add(ttl).Unix()))
+	plaintext = append(plaintext, payload...)
+	token, err := c.ks.Seal([]byte{versionNotBefore}, plaintext, []byte{versionNotBefore})
+	if err != nil {
+		return "", err
+	}
+	return b64.EncodeToString(token), nil
+}
+
+// Verify decrypts and authenticates token, checks that it's not expired and,
+// if it has a not-before time, that it's already valid, and returns its
+// payload.
+func (c *Codec) Verify(token string) ([]byte, error) {
+	data, err := decode(token)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := c.ks.Open(nil, data[1:], data[:1])
+	if err != nil || len(plaintext) < 8 {
+		return nil, errors.New("token: invalid token")
+	}
+	now := c.now()
+	if data[0] == versionNotBefore {
+		if len(plaintext) < 16 {
+			return nil, errors.New("token: invalid token")
+		}
+		notBefore := time.Unix(int64(binary.BigEndian.Uint64(plaintext)), 0)
+		if now.Before(notBefore) {
+			return nil, ErrNotYetValid
+		}
+		plaintext = plaintext[8:]
+	}
+	expires := time.Unix(int64(binary.BigEndian.Uint64(plaintext)), 0)
+	if !now.Before(expires) {
+		return nil, ErrExpired
+	}
+	return plaintext[8:], nil
+}
+
+// KeyID returns the ID of the key that issued token, without verifying it.
+func KeyID(token string) (uint32, error) {
+	data, err := decode(token)
+	if err != nil {
+		return 0, err
+	}
+	return keyset.KeyID(data[1:])
+}
+
+func decode(token string) ([]byte, error) {
+	data, err := b64.DecodeString(token)
+	if err != nil {
+		return nil, errors.New("token: invalid encoding")
+	}
+	if len(data) < 1+keyset.Overhead+8 {
+		return nil, errors.New("token: token too short")
+	}
+	if data[0] != version && data[0] != versionNotBefore {
+		return nil, errors.New("token: unsupported version")
+	}
+	return data, nil
+}
@@ -0,0 +1,115 @@
+package token_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/keyset"
+	"filippo.io/xaes256gcm/token"
+)
+
+func TestToken(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ks := keyset.New()
+	alice, err := ks.Add(xaes256gcm.GenerateKey(), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob, err := ks.Add(xaes256gcm.GenerateKey(), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.SetPrimary(alice); err != nil {
+		t.Fatal(err)
+	}
+	c := token.New(ks)
+	c.Now = func() time.Time { return now }
+
+	tok, err := c.Issue([]byte("user=alice"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := token.KeyID(tok); err != nil || id != alice {
+		t.Errorf("KeyID = %d, %v, expected %d", id, err, alice)
+	}
+	if err := ks.SetPrimary(bob); err != nil {
+		t.Fatal(err)
+	}
+	other, err := c.Issue([]byte("user=bob"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := token.KeyID(other); err != nil || id != bob {
+		t.Errorf("KeyID = %d, %v, expected %d", id, err, bob)
+	}
+
+	if got, err := c.Verify(tok); err != nil || string(got) != "user=alice" {
+		t.Errorf("Verify: %q, %v", got, err)
+	}
+	if got, err := c.Verify(other); err != nil || string(got) != "user=bob" {
+		t.Errorf("Verify: %q, %v", got, err)
+	}
+
+	for _, invalid := range []string{"", "!!!", other[:len(other)-1], "B" + other[1:]} {
+		if _, err := c.Verify(invalid); err == nil {
+			t.Errorf("invalid token %q verified", invalid)
+		}
+	}
+
+	if err := ks.Remove(alice); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Verify(tok); err == nil {
+		t.Errorf("token verified after its key was removed")
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := c.Verify(other); !errors.Is(err, token.ErrExpired) {
+		t.Errorf("expired token: %v", err)
+	}
+}
+
+func TestNotBefore(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ks := keyset.New()
+	id, err := ks.Add(xaes256gcm.GenerateKey(), time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.SetPrimary(id); err != nil {
+		t.Fatal(err)
+	}
+	c := token.New(ks)
+	c.Now = func() time.Time { return now }
+
+	tok, err := c.IssueNotBefore([]byte("user=alice"), now.Add(time.Hour), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Verify(tok); !errors.Is(err, token.ErrNotYetValid) {
+		t.Errorf("early token: %v", err)
+	}
+	now = now.Add(time.Hour)
+	if got, err := c.Verify(tok); err != nil || string(got) != "user=alice" {
+		t.Errorf("Verify: %q, %v", got, err)
+	}
+	now = now.Add(time.Hour)
+	if _, err := c.Verify(tok); !errors.Is(err, token.ErrExpired) {
+		t.Errorf("expired token: %v", err)
+	}
+
+	// The version is authenticated, so a version 0x02 token can't be passed
+	// off as a version 0x01 token without a not-before time.
+	data, err := base64.RawURLEncoding.DecodeString(tok)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data[0] = 0x01
+	now = now.Add(-2 * time.Hour)
+	if _, err := c.Verify(base64.RawURLEncoding.EncodeToString(data)); err == nil {
+		t.Errorf("downgraded token verified")
+	}
+}
@@ -0,0 +1,16 @@
+module filippo.io/xaes256gcm/tpm
+
+go 1.21
+
+require (
+	filippo.io/xaes256gcm v0.0.0-00010101000000-000000000000
+	github.com/google/go-tpm v0.9.0
+	github.com/google/go-tpm-tools v0.3.13-0.20230620182252-4639ecce2aba
+)
+
+require (
+	golang.org/x/crypto v0.24.0 // indirect
+	golang.org/x/sys v0.23.0 // indirect
+)
+
+replace filippo.io/xaes256gcm => ../
@@ -0,0 +1,198 @@
+// Package tpm seals XAES-256-GCM keys to a TPM 2.0, so that a long-term key
+// is never stored on disk in plaintext, and can only be recovered by the TPM
+// that sealed it, optionally only while some PCRs have the value they had at
+// sealing time.
+//
+// The sealed key is a TPM keyed hash object under a primary storage key
+// derived from the owner hierarchy with the standard ECC P-256 template, which
+// is recreated by [Unseal] and never persisted in the TPM.
+//
+// This package is a separate module, so that its dependency on
+// github.com/google/go-tpm is not imposed on users of filippo.io/xaes256gcm.
+package tpm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"filippo.io/xaes256gcm"
+	"github.com/google/go-tpm/legacy/tpm2"
+	"github.com/google/go-tpm/tpmutil"
+)
+
+// Open opens the TPM of the system, /dev/tpmrm0 or /dev/tpm0 on Linux.
+func Open() (io.ReadWriteCloser, error) {
+	rw, err := tpm2.OpenTPM()
+	if err != nil {
+		return nil, fmt.Errorf("tpm: failed to open TPM: %w", err)
+	}
+	return rw, nil
+}
+
+const sealedVersion = 0x01
+
+var srkTemplate = tpm2.Public{
+	Type:    tpm2.AlgECC,
+	NameAlg: tpm2.AlgSHA256,
+	Attributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent | tpm2.FlagSensitiveDataOrigin |
+		tpm2.FlagUserWithAuth | tpm2.FlagRestricted | tpm2.FlagDecrypt | tpm2.FlagNoDA,
+	ECCParameters: &tpm2.ECCParams{
+		Symmetric: &tpm2.SymScheme{Alg: tpm2.AlgAES, KeyBits: 128, Mode: tpm2.AlgCFB},
+		CurveID:   tpm2.CurveNISTP256,
+	},
+}
+
+// Seal seals key to the TPM at rw, and returns a blob that can be stored on
+// disk and passed to [Unseal].
+//
+// If pcrs is not empty, the key can only be unsealed while the SHA-256 bank of
+// those PCRs has the same values it has now, for example to bind the key to
+// the boot chain measured by Secure Boot in PCR 7.
+func Seal(rw io.ReadWriter, key xaes256gcm.Key, pcrs ...int) ([]byte, error) {
+	if len(pcrs) > 24 {
+		return nil, errors.New("tpm: too many PCRs")
+	}
+	for _, pcr := range pcrs {
+		if pcr < 0 || pcr >= 24 {
+			return nil, fmt.Errorf("tpm: invalid PCR %d", pcr)
+		}
+	}
+	srk, err := createSRK(rw)
+	if err != nil {
+		return nil, err
+	}
+	defer tpm2.FlushContext(rw, srk)
+
+	public := tpm2.Public{
+		Type:       tpm2.AlgKeyedHash,
+		NameAlg:    tpm2.AlgSHA256,
+		Attributes: tpm2.FlagFixedTPM | tpm2.FlagFixedParent,
+	}
+	if len(pcrs) == 0 {
+		public.Attributes |= tpm2.FlagUserWithAuth
+	} else {
+		session, err := pcrSession(rw, pcrs, tpm2.SessionTrial)
+		if err != nil {
+			return nil, err
+		}
+		policy, err := tpm2.PolicyGetDigest(rw, session)
+		tpm2.FlushContext(rw, session)
+		if err != nil {
+			return nil, fmt.Errorf("tpm: failed to compute PCR policy: %w", err)
+		}
+		public.AuthPolicy = policy
+	}
+	priv, pub, _, _, _, err := tpm2.CreateKeyWithSensitive(rw, srk, tpm2.PCRSelection{},
+		"", "", public, key.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("tpm: failed to seal key: %w", err)
+	}
+
+	// The blob is the version byte, the number of PCRs, the PCR indexes, and
+	// the public and private areas of the sealed object, each prefixed by a
+	// 2-byte big-endian length.
+	blob := []byte{sealedVersion, byte(len(pcrs))}
+	for _, pcr := range pcrs {
+		blob = append(blob, byte(pcr))
+	}
+	blob = binary.BigEndian.AppendUint16(blob, uint16(len(pub)))
+	blob = append(blob, pub...)
+	blob = binary.BigEndian.AppendUint16(blob, uint16(len(priv)))
+	blob = append(blob, priv...)
+	return blob, nil
+}
+
+// Unseal recovers a key sealed with [Seal] by the TPM at rw. It fails if the
+// blob was sealed by a different TPM, or if the PCRs the key is bound to have
+// changed.
+func Unseal(rw io.ReadWriter, sealed []byte) (xaes256gcm.Key, error) {
+	pcrs, pub, priv, err := parseSealed(sealed)
+	if err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	srk, err := createSRK(rw)
+	if err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	defer tpm2.FlushContext(rw, srk)
+
+	handle, _, err := tpm2.Load(rw, srk, "", pub, priv)
+	if err != nil {
+		return xaes256gcm.Key{}, fmt.Errorf("tpm: failed to load sealed key: %w", err)
+	}
+	defer tpm2.FlushContext(rw, handle)
+
+	var key []byte
+	if len(pcrs) == 0 {
+		key, err = tpm2.Unseal(rw, handle, "")
+	} else {
+		session, serr := pcrSession(rw, pcrs, tpm2.SessionPolicy)
+		if serr != nil {
+			return xaes256gcm.Key{}, serr
+		}
+		defer tpm2.FlushContext(rw, session)
+		key, err = tpm2.UnsealWithSession(rw, session, handle, "")
+	}
+	if err != nil {
+		return xaes256gcm.Key{}, fmt.Errorf("tpm: failed to unseal key: %w", err)
+	}
+	return xaes256gcm.NewKey(key)
+}
+
+func parseSealed(sealed []byte) (pcrs []int, pub, priv []byte, err error) {
+	if len(sealed) < 2 || sealed[0] != sealedVersion {
+		return nil, nil, nil, errors.New("tpm: invalid sealed key")
+	}
+	n := int(sealed[1])
+	rest := sealed[2:]
+	if n > 24 || len(rest) < n {
+		return nil, nil, nil, errors.New("tpm: invalid sealed key")
+	}
+	for _, pcr := range rest[:n] {
+		pcrs = append(pcrs, int(pcr))
+	}
+	rest = rest[n:]
+	next := func() []byte {
+		if len(rest) < 2 {
+			return nil
+		}
+		l := int(binary.BigEndian.Uint16(rest))
+		if len(rest) < 2+l {
+			return nil
+		}
+		b := rest[2 : 2+l]
+		rest = rest[2+l:]
+		return b
+	}
+	pub, priv = next(), next()
+	if pub == nil || priv == nil || len(rest) != 0 {
+		return nil, nil, nil, errors.New("tpm: invalid sealed key")
+	}
+	return pcrs, pub, priv, nil
+}
+
+func createSRK(rw io.ReadWriter) (tpmutil.Handle, error) {
+	srk, _, err := tpm2.CreatePrimary(rw, tpm2.HandleOwner, tpm2.PCRSelection{}, "", "", srkTemplate)
+	if err != nil {
+		return 0, fmt.Errorf("tpm: failed to create storage root key: %w", err)
+	}
+	return srk, nil
+}
+
+// pcrSession starts a policy or trial session bound to the current values of
+// the SHA-256 bank of pcrs.
+func pcrSession(rw io.ReadWriter, pcrs []int, typ tpm2.SessionType) (tpmutil.Handle, error) {
+	session, _, err := tpm2.StartAuthSession(rw, tpm2.HandleNull, tpm2.HandleNull,
+		make([]byte, 16), nil, typ, tpm2.AlgNull, tpm2.AlgSHA256)
+	if err != nil {
+		return 0, fmt.Errorf("tpm: failed to start session: %w", err)
+	}
+	sel := tpm2.PCRSelection{Hash: tpm2.AlgSHA256, PCRs: pcrs}
+	if err := tpm2.PolicyPCR(rw, session, nil, sel); err != nil {
+		tpm2.FlushContext(rw, session)
+		return 0, fmt.Errorf("tpm: failed to apply PCR policy: %w", err)
+	}
+	return session, nil
+}
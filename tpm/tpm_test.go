@@ -0,0 +1,62 @@
+package tpm_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/tpm"
+	"github.com/google/go-tpm-tools/simulator"
+	"github.com/google/go-tpm/legacy/tpm2"
+)
+
+func TestSealUnseal(t *testing.T) {
+	rw, err := simulator.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rw.Close()
+
+	key := xaes256gcm.GenerateKey()
+	for _, pcrs := range [][]int{nil, {7}, {0, 7, 16}} {
+		sealed, err := tpm.Seal(rw, key, pcrs...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if bytes.Contains(sealed, key.Bytes()) {
+			t.Errorf("sealed key contains the key")
+		}
+		unsealed, err := tpm.Unseal(rw, sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unsealed != key {
+			t.Errorf("%v: unsealed the wrong key", pcrs)
+		}
+
+		sealed[len(sealed)-1] ^= 1
+		if _, err := tpm.Unseal(rw, sealed); err == nil {
+			t.Errorf("%v: unsealed corrupted blob", pcrs)
+		}
+		if _, err := tpm.Unseal(rw, sealed[:len(sealed)-1]); err == nil {
+			t.Errorf("%v: unsealed truncated blob", pcrs)
+		}
+	}
+
+	sealed, err := tpm.Seal(rw, key, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	digest := sha256.Sum256([]byte("measurement"))
+	if err := tpm2.PCRExtend(rw, 16, tpm2.AlgSHA256, digest[:], ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tpm.Unseal(rw, sealed); err == nil {
+		t.Errorf("unsealed key after PCR change")
+	}
+
+	if _, err := tpm.Seal(rw, key, 24); err == nil {
+		t.Errorf("sealed to invalid PCR")
+	}
+}
@@ -0,0 +1,59 @@
+package xaes256gcm
+
+import (
+	"crypto/cipher"
+	"sync/atomic"
+)
+
+// UsageLimit tracks the number of messages sealed by AEADs returned by
+// [WithUsageLimit], and enforces thresholds on it. A UsageLimit can be shared
+// by multiple AEADs for the same key.
+//
+// With random nonces, XAES-256-GCM can safely encrypt an effectively unlimited
+// number of messages under a key, so limits are mostly useful to enforce
+// rotation policies, or as a backstop for other AEADs or nonce schemes.
+type UsageLimit struct {
+	// Warn, if not zero, is the number of messages at which OnWarn is called.
+	Warn uint64
+	// OnWarn is called once, from the Seal call that reaches Warn.
+	OnWarn func(sealed uint64)
+	// Max, if not zero, is the maximum number of messages. Seal panics
+	// instead of sealing more messages.
+	Max uint64
+
+	sealed atomic.Uint64
+}
+
+// Sealed returns the number of messages sealed so far.
+func (l *UsageLimit) Sealed() uint64 {
+	return l.sealed.Load()
+}
+
+// SetSealed sets the number of messages sealed so far, for example to restore
+// a count persisted across restarts.
+func (l *UsageLimit) SetSealed(n uint64) {
+	l.sealed.Store(n)
+}
+
+// WithUsageLimit returns an AEAD that wraps aead, and counts its calls to Seal
+// in l, enforcing its thresholds.
+func WithUsageLimit(aead cipher.AEAD, l *UsageLimit) cipher.AEAD {
+	return &usageAEAD{aead, l}
+}
+
+type usageAEAD struct {
+	cipher.AEAD
+	l *UsageLimit
+}
+
+func (a *usageAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	n := a.l.sealed.Add(1)
+	if a.l.Max != 0 && n > a.l.Max {
+		a.l.sealed.Add(^uint64(0))
+		panic("xaes256gcm: key usage limit exceeded, the key must be rotated")
+	}
+	if a.l.Warn != 0 && n == a.l.Warn && a.l.OnWarn != nil {
+		a.l.OnWarn(n)
+	}
+	return a.AEAD.Seal(dst, nonce, plaintext, additionalData)
+}
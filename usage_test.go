@@ -0,0 +1,44 @@
+package xaes256gcm_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestUsageLimit(t *testing.T) {
+	c, err := xaes256gcm.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warned []uint64
+	l := &xaes256gcm.UsageLimit{Warn: 3, Max: 5, OnWarn: func(n uint64) { warned = append(warned, n) }}
+	c = xaes256gcm.WithUsageLimit(c, l)
+	for i := 0; i < 5; i++ {
+		c.Seal(nil, nil, []byte("hello"), nil)
+	}
+	if l.Sealed() != 5 {
+		t.Errorf("Sealed() = %d", l.Sealed())
+	}
+	if len(warned) != 1 || warned[0] != 3 {
+		t.Errorf("OnWarn calls: %v", warned)
+	}
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("Seal over the limit didn't panic")
+			}
+		}()
+		c.Seal(nil, nil, []byte("hello"), nil)
+	}()
+	if l.Sealed() != 5 {
+		t.Errorf("Sealed() = %d after failed Seal", l.Sealed())
+	}
+
+	l.SetSealed(1)
+	c.Seal(nil, nil, []byte("hello"), nil)
+	if l.Sealed() != 2 {
+		t.Errorf("Sealed() = %d after SetSealed", l.Sealed())
+	}
+}
@@ -0,0 +1,147 @@
+// Package vectors provides the [C2SP XAES-256-GCM] test vectors, for use in
+// the test suites of other implementations and of wrappers.
+//
+// Implementations are expected to expose a [cipher.AEAD] that takes 24-byte
+// nonces, like [filippo.io/xaes256gcm.NewWithManualNonces].
+//
+// [C2SP XAES-256-GCM]: https://c2sp.org/XAES-256-GCM
+package vectors
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"fmt"
+
+	"golang.org/x/crypto/sha3"
+)
+
+// Vector is a single XAES-256-GCM test case.
+type Vector struct {
+	Key            []byte
+	Nonce          []byte
+	Plaintext      []byte
+	AdditionalData []byte
+	Ciphertext     []byte
+}
+
+// Vectors returns the test vectors from the specification. The returned
+// slices can be modified by the caller.
+func Vectors() []Vector {
+	return []Vector{
+		{
+			Key:        bytes.Repeat([]byte{0x01}, 32),
+			Nonce:      []byte("ABCDEFGHIJKLMNOPQRSTUVWX"),
+			Plaintext:  []byte("XAES-256-GCM"),
+			Ciphertext: mustDecodeHex("ce546ef63c9cc60765923609b33a9a1974e96e52daf2fcf7075e2271"),
+		},
+		{
+			Key:            bytes.Repeat([]byte{0x03}, 32),
+			Nonce:          []byte("ABCDEFGHIJKLMNOPQRSTUVWX"),
+			Plaintext:      []byte("XAES-256-GCM"),
+			AdditionalData: []byte("c2sp.org/XAES-256-GCM"),
+			Ciphertext:     mustDecodeHex("986ec1832593df5443a179437fd083bf3fdb41abd740a21f71eb769d"),
+		},
+	}
+}
+
+// AccumulatedVector is the expected result of [Accumulate] for a number of
+// iterations.
+type AccumulatedVector struct {
+	Iterations int
+	Digest     []byte
+}
+
+// AccumulatedVectors returns the accumulated test vectors from the
+// specification, ordered by increasing number of iterations.
+func AccumulatedVectors() []AccumulatedVector {
+	return []AccumulatedVector{
+		{10_000, mustDecodeHex("e6b9edf2df6cec60c8cbd864e2211b597fb69a529160cd040d56c0c210081939")},
+		{1_000_000, mustDecodeHex("2163ae1445985a30b60585ee67daa55674df06901b890593e824b8a7c885ab15")},
+	}
+}
+
+// Run checks the AEADs returned by newAEAD against [Vectors], including that
+// Open rejects modified ciphertexts.
+func Run(newAEAD func(key []byte) (cipher.AEAD, error)) error {
+	for i, v := range Vectors() {
+		a, err := newAEAD(v.Key)
+		if err != nil {
+			return fmt.Errorf("vector %d: %v", i, err)
+		}
+		if got := a.Seal(nil, v.Nonce, v.Plaintext, v.AdditionalData); !bytes.Equal(got, v.Ciphertext) {
+			return fmt.Errorf("vector %d: Seal returned %x, expected %x", i, got, v.Ciphertext)
+		}
+		if got, err := a.Open(nil, v.Nonce, v.Ciphertext, v.AdditionalData); err != nil {
+			return fmt.Errorf("vector %d: Open failed: %v", i, err)
+		} else if !bytes.Equal(got, v.Plaintext) {
+			return fmt.Errorf("vector %d: Open returned %x, expected %x", i, got, v.Plaintext)
+		}
+		v.Ciphertext[0] ^= 1
+		if _, err := a.Open(nil, v.Nonce, v.Ciphertext, v.AdditionalData); err == nil {
+			return fmt.Errorf("vector %d: Open accepted a modified ciphertext", i)
+		}
+	}
+	return nil
+}
+
+// Accumulate runs the accumulated test for the given number of iterations, and
+// returns the digest, to be compared with [AccumulatedVectors].
+//
+// Each iteration reads from a SHAKE128 instance with empty input a 32-byte
+// key, a 24-byte nonce, a one-byte plaintext length, the plaintext, a one-byte
+// additional data length, and the additional data. The ciphertext is checked
+// to decrypt successfully, and is written to a second SHAKE128 instance, whose
+// 32-byte output is the digest.
+func Accumulate(newAEAD func(key []byte) (cipher.AEAD, error), iterations int) ([]byte, error) {
+	return AccumulateCases(newAEAD, iterations, nil)
+}
+
+// AccumulateCases is like [Accumulate], but also calls f, if not nil, with the
+// test case of each iteration.
+func AccumulateCases(newAEAD func(key []byte) (cipher.AEAD, error), iterations int, f func(Vector)) ([]byte, error) {
+	s, d := sha3.NewShake128(), sha3.NewShake128()
+	for i := 0; i < iterations; i++ {
+		key := make([]byte, 32)
+		s.Read(key)
+		nonce := make([]byte, 24)
+		s.Read(nonce)
+		lenByte := make([]byte, 1)
+		s.Read(lenByte)
+		plaintext := make([]byte, int(lenByte[0]))
+		s.Read(plaintext)
+		s.Read(lenByte)
+		aad := make([]byte, int(lenByte[0]))
+		s.Read(aad)
+
+		a, err := newAEAD(key)
+		if err != nil {
+			return nil, fmt.Errorf("iteration %d: %v", i, err)
+		}
+		ciphertext := a.Seal(nil, nonce, plaintext, aad)
+		decrypted, err := a.Open(nil, nonce, ciphertext, aad)
+		if err != nil {
+			return nil, fmt.Errorf("iteration %d: Open failed: %v", i, err)
+		}
+		if !bytes.Equal(plaintext, decrypted) {
+			return nil, fmt.Errorf("iteration %d: plaintext and decrypted are not equal", i)
+		}
+
+		d.Write(ciphertext)
+		if f != nil {
+			f(Vector{Key: key, Nonce: nonce, Plaintext: plaintext,
+				AdditionalData: aad, Ciphertext: ciphertext})
+		}
+	}
+	digest := make([]byte, 32)
+	d.Read(digest)
+	return digest, nil
+}
+
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
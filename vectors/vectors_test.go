@@ -0,0 +1,30 @@
+package vectors_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/vectors"
+)
+
+func TestRun(t *testing.T) {
+	if err := vectors.Run(xaes256gcm.NewWithManualNonces); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestAccumulate(t *testing.T) {
+	for _, v := range vectors.AccumulatedVectors() {
+		if testing.Short() && v.Iterations > 10_000 {
+			continue
+		}
+		got, err := vectors.Accumulate(xaes256gcm.NewWithManualNonces, v.Iterations)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, v.Digest) {
+			t.Errorf("%d iterations: got %x", v.Iterations, got)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package vectors
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// WycheproofFile is a test vector file in the [Wycheproof] AEAD format, with
+// algorithm "XAES-256-GCM".
+//
+// [Wycheproof]: https://github.com/C2SP/wycheproof
+type WycheproofFile struct {
+	Algorithm     string            `json:"algorithm"`
+	NumberOfTests int               `json:"numberOfTests"`
+	Header        []string          `json:"header"`
+	TestGroups    []WycheproofGroup `json:"testGroups"`
+}
+
+// WycheproofGroup is a group of Wycheproof test cases.
+type WycheproofGroup struct {
+	Type    string           `json:"type"`
+	KeySize int              `json:"keySize"`
+	IVSize  int              `json:"ivSize"`
+	TagSize int              `json:"tagSize"`
+	Tests   []WycheproofCase `json:"tests"`
+}
+
+// WycheproofCase is a Wycheproof test case. The byte strings are hex-encoded,
+// and Result is one of "valid", "invalid", or "acceptable".
+type WycheproofCase struct {
+	TcID    int      `json:"tcId"`
+	Comment string   `json:"comment"`
+	Key     string   `json:"key"`
+	IV      string   `json:"iv"`
+	AAD     string   `json:"aad"`
+	Msg     string   `json:"msg"`
+	CT      string   `json:"ct"`
+	Tag     string   `json:"tag"`
+	Result  string   `json:"result"`
+	Flags   []string `json:"flags"`
+}
+
+// Result is the outcome of a Wycheproof test case.
+type Result struct {
+	TcID    int
+	Comment string
+	// Passed is true if the behavior of the AEAD matched the expected result.
+	Passed bool
+	// Skipped is true if the test case was not run, because its tag size is
+	// not the one of the AEAD.
+	Skipped bool
+	// Reason describes why the test case failed or was skipped.
+	Reason string
+}
+
+// RunWycheproof parses data as a [WycheproofFile], and runs its test cases
+// against the AEADs returned by newAEAD, which must take nonces of the size
+// specified by the test groups.
+//
+// For valid test cases, Seal must produce the expected ciphertext and tag, and
+// Open must produce the expected message. For invalid ones, Open must fail.
+// Acceptable test cases pass either way, unless Seal or Open panic.
+//
+// An error is returned only if data can't be parsed or is for a different
+// algorithm, and failures are reported in the results.
+func RunWycheproof(data []byte, newAEAD func(key []byte) (cipher.AEAD, error)) ([]Result, error) {
+	var f WycheproofFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("vectors: malformed Wycheproof file: %v", err)
+	}
+	if f.Algorithm != "XAES-256-GCM" {
+		return nil, fmt.Errorf("vectors: unexpected algorithm %q", f.Algorithm)
+	}
+	var results []Result
+	for _, g := range f.TestGroups {
+		for _, tc := range g.Tests {
+			r := Result{TcID: tc.TcID, Comment: tc.Comment}
+			if err := runWycheproofCase(g, tc, newAEAD); errors.Is(err, errSkipped) {
+				r.Skipped = true
+				r.Reason = err.Error()
+			} else if err != nil {
+				r.Reason = err.Error()
+			} else {
+				r.Passed = true
+			}
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+var errSkipped = errors.New("unsupported tag size")
+
+func runWycheproofCase(g WycheproofGroup, tc WycheproofCase, newAEAD func(key []byte) (cipher.AEAD, error)) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	var key, iv, aad, msg, ct, tag []byte
+	for _, f := range []struct {
+		dst *[]byte
+		s   string
+	}{{&key, tc.Key}, {&iv, tc.IV}, {&aad, tc.AAD}, {&msg, tc.Msg}, {&ct, tc.CT}, {&tag, tc.Tag}} {
+		if *f.dst, err = hex.DecodeString(f.s); err != nil {
+			return fmt.Errorf("malformed test case: %v", err)
+		}
+	}
+
+	a, err := newAEAD(key)
+	if err != nil {
+		if tc.Result == "valid" {
+			return fmt.Errorf("failed to create AEAD: %v", err)
+		}
+		return nil
+	}
+	if g.TagSize != 8*a.Overhead() {
+		return errSkipped
+	}
+	ciphertext := append(ct, tag...)
+
+	switch tc.Result {
+	case "valid":
+		if got := a.Seal(nil, iv, msg, aad); !bytes.Equal(got, ciphertext) {
+			return fmt.Errorf("Seal returned %x, expected %x", got, ciphertext)
+		}
+		if got, err := a.Open(nil, iv, ciphertext, aad); err != nil {
+			return fmt.Errorf("Open failed: %v", err)
+		} else if !bytes.Equal(got, msg) {
+			return fmt.Errorf("Open returned %x, expected %x", got, msg)
+		}
+	case "invalid":
+		if _, err := a.Open(nil, iv, ciphertext, aad); err == nil {
+			return errors.New("Open succeeded for an invalid case")
+		}
+	case "acceptable":
+		a.Open(nil, iv, ciphertext, aad)
+	default:
+		return fmt.Errorf("unknown result %q", tc.Result)
+	}
+	return nil
+}
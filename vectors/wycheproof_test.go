@@ -0,0 +1,71 @@
+package vectors_test
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/vectors"
+)
+
+func TestRunWycheproof(t *testing.T) {
+	g := vectors.WycheproofGroup{Type: "AeadTest", KeySize: 256, IVSize: 192, TagSize: 128}
+	for i, v := range vectors.Vectors() {
+		ct, tag := v.Ciphertext[:len(v.Plaintext)], v.Ciphertext[len(v.Plaintext):]
+		tc := vectors.WycheproofCase{
+			TcID: i + 1, Key: hex.EncodeToString(v.Key), IV: hex.EncodeToString(v.Nonce),
+			AAD: hex.EncodeToString(v.AdditionalData), Msg: hex.EncodeToString(v.Plaintext),
+			CT: hex.EncodeToString(ct), Tag: hex.EncodeToString(tag), Result: "valid",
+		}
+		g.Tests = append(g.Tests, tc)
+		tc.TcID += 10
+		tc.Result = "invalid"
+		tag[0] ^= 1
+		tc.Tag = hex.EncodeToString(tag)
+		g.Tests = append(g.Tests, tc)
+		tc.TcID += 10
+		tc.IV = tc.IV[:24]
+		g.Tests = append(g.Tests, tc)
+	}
+	short := vectors.WycheproofGroup{Type: "AeadTest", KeySize: 256, IVSize: 192, TagSize: 96,
+		Tests: []vectors.WycheproofCase{g.Tests[0]}}
+	short.Tests[0].TcID = 100
+	wrong := g.Tests[0]
+	wrong.TcID = 200
+	wrong.Msg = "00"
+	file := vectors.WycheproofFile{Algorithm: "XAES-256-GCM",
+		TestGroups: []vectors.WycheproofGroup{g, short, {TagSize: 128, Tests: []vectors.WycheproofCase{wrong}}}}
+	data, err := json.Marshal(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := vectors.RunWycheproof(data, xaes256gcm.NewWithManualNonces)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 8 {
+		t.Fatalf("got %d results", len(results))
+	}
+	for _, r := range results {
+		switch r.TcID {
+		case 100:
+			if !r.Skipped {
+				t.Errorf("test case with short tag not skipped: %+v", r)
+			}
+		case 200:
+			if r.Passed || r.Skipped {
+				t.Errorf("wrong test case passed: %+v", r)
+			}
+		default:
+			if !r.Passed {
+				t.Errorf("test case failed: %+v", r)
+			}
+		}
+	}
+
+	if _, err := vectors.RunWycheproof([]byte(`{"algorithm": "AES-GCM"}`), xaes256gcm.NewWithManualNonces); err == nil {
+		t.Errorf("file for a different algorithm accepted")
+	}
+}
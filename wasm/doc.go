@@ -0,0 +1,21 @@
+// Package wasm exposes XAES-256-GCM to JavaScript, when compiled to
+// WebAssembly with GOOS=js GOARCH=wasm.
+//
+// A program that calls [Register] and then blocks forever
+//
+//	func main() {
+//		wasm.Register("xaes256gcm")
+//		select {}
+//	}
+//
+// defines a global JavaScript object with the following functions, which take
+// and return Uint8Array values, and return Promises that are rejected with an
+// Error on failure.
+//
+//	keygen(): Promise<Uint8Array>
+//	seal(key, plaintext, additionalData?): Promise<Uint8Array>
+//	open(key, ciphertext, additionalData?): Promise<Uint8Array>
+//
+// seal and open use random nonces like [filippo.io/xaes256gcm.New], so their
+// ciphertexts interoperate with Go programs using the same format.
+package wasm
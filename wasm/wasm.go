@@ -0,0 +1,94 @@
+//go:build js && wasm
+
+package wasm
+
+import (
+	"errors"
+	"syscall/js"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Register defines the global JavaScript object name. See the package
+// documentation for its functions.
+func Register(name string) {
+	obj := js.Global().Get("Object").New()
+	obj.Set("keygen", promiseFunc(func([]js.Value) ([]byte, error) {
+		return xaes256gcm.GenerateKey().Bytes(), nil
+	}))
+	obj.Set("seal", promiseFunc(func(args []js.Value) ([]byte, error) {
+		key, plaintext, ad, err := parseArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		a, err := xaes256gcm.New(key)
+		if err != nil {
+			return nil, err
+		}
+		return a.Seal(nil, nil, plaintext, ad), nil
+	}))
+	obj.Set("open", promiseFunc(func(args []js.Value) ([]byte, error) {
+		key, ciphertext, ad, err := parseArgs(args)
+		if err != nil {
+			return nil, err
+		}
+		a, err := xaes256gcm.New(key)
+		if err != nil {
+			return nil, err
+		}
+		return a.Open(nil, nil, ciphertext, ad)
+	}))
+	js.Global().Set(name, obj)
+}
+
+func parseArgs(args []js.Value) (key, data, ad []byte, err error) {
+	if len(args) < 2 || len(args) > 3 {
+		return nil, nil, nil, errors.New("expected two or three arguments")
+	}
+	if key, err = bytesFromJS(args[0]); err != nil {
+		return nil, nil, nil, err
+	}
+	if data, err = bytesFromJS(args[1]); err != nil {
+		return nil, nil, nil, err
+	}
+	if len(args) == 3 && !args[2].IsUndefined() && !args[2].IsNull() {
+		if ad, err = bytesFromJS(args[2]); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return key, data, ad, nil
+}
+
+func bytesFromJS(v js.Value) ([]byte, error) {
+	if !v.InstanceOf(js.Global().Get("Uint8Array")) {
+		return nil, errors.New("arguments must be Uint8Array")
+	}
+	b := make([]byte, v.Length())
+	js.CopyBytesToGo(b, v)
+	return b, nil
+}
+
+// promiseFunc returns a JavaScript function that runs f in a goroutine, and
+// returns a Promise for its result.
+func promiseFunc(f func(args []js.Value) ([]byte, error)) js.Func {
+	return js.FuncOf(func(this js.Value, args []js.Value) any {
+		// args are only valid during the call, but js.Value can be retained.
+		args = append([]js.Value(nil), args...)
+		executor := js.FuncOf(func(this js.Value, p []js.Value) any {
+			resolve, reject := p[0], p[1]
+			go func() {
+				out, err := f(args)
+				if err != nil {
+					reject.Invoke(js.Global().Get("Error").New(err.Error()))
+					return
+				}
+				u := js.Global().Get("Uint8Array").New(len(out))
+				js.CopyBytesToJS(u, out)
+				resolve.Invoke(u)
+			}()
+			return nil
+		})
+		defer executor.Release()
+		return js.Global().Get("Promise").New(executor)
+	})
+}
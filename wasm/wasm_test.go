@@ -0,0 +1,86 @@
+//go:build js && wasm
+
+package wasm_test
+
+import (
+	"bytes"
+	"syscall/js"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/wasm"
+)
+
+func await(p js.Value) (js.Value, error) {
+	ch := make(chan js.Value, 1)
+	errCh := make(chan error, 1)
+	then := js.FuncOf(func(this js.Value, args []js.Value) any { ch <- args[0]; return nil })
+	defer then.Release()
+	catch := js.FuncOf(func(this js.Value, args []js.Value) any {
+		errCh <- js.Error{Value: args[0]}
+		return nil
+	})
+	defer catch.Release()
+	p.Call("then", then, catch)
+	select {
+	case v := <-ch:
+		return v, nil
+	case err := <-errCh:
+		return js.Value{}, err
+	}
+}
+
+func toJS(b []byte) js.Value {
+	u := js.Global().Get("Uint8Array").New(len(b))
+	js.CopyBytesToJS(u, b)
+	return u
+}
+
+func toGo(v js.Value) []byte {
+	b := make([]byte, v.Length())
+	js.CopyBytesToGo(b, v)
+	return b
+}
+
+func TestRegister(t *testing.T) {
+	wasm.Register("xaes256gcmTest")
+	obj := js.Global().Get("xaes256gcmTest")
+
+	key, err := await(obj.Call("keygen"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Length() != xaes256gcm.KeySize {
+		t.Fatalf("key is %d bytes", key.Length())
+	}
+
+	ciphertext, err := await(obj.Call("seal", key, toJS([]byte("hello")), toJS([]byte("aad"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := xaes256gcm.New(toGo(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plaintext, err := a.Open(nil, nil, toGo(ciphertext), []byte("aad")); err != nil {
+		t.Fatal(err)
+	} else if string(plaintext) != "hello" {
+		t.Errorf("unexpected plaintext %q", plaintext)
+	}
+
+	goCiphertext := a.Seal(nil, nil, []byte("world"), nil)
+	plaintext, err := await(obj.Call("open", key, toJS(goCiphertext)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(toGo(plaintext), []byte("world")) {
+		t.Errorf("unexpected plaintext %q", toGo(plaintext))
+	}
+
+	if _, err := await(obj.Call("open", key, toJS(goCiphertext), toJS([]byte("aad")))); err == nil {
+		t.Errorf("opened with wrong additional data")
+	}
+	if _, err := await(obj.Call("seal", "not bytes", toJS(nil))); err == nil {
+		t.Errorf("sealed with a string key")
+	}
+}
@@ -0,0 +1,28 @@
+package xaes256gcm
+
+import "errors"
+
+// WrappedKeySize is the size of a key wrapped with [WrapKey].
+const WrappedKeySize = KeySize + Overhead
+
+const wrapAD = "filippo.io/xaes256gcm key wrap"
+
+// WrapKey encrypts dek with kek, for example to store a data encryption key at
+// rest protected by a key encryption key. It uses XAES-256-GCM with a random
+// nonce, and fixed additional data that separates wrapped keys from other
+// messages encrypted with kek.
+func WrapKey(kek, dek Key) []byte {
+	return kek.AEAD().Seal(make([]byte, 0, WrappedKeySize), nil, dek.k[:], []byte(wrapAD))
+}
+
+// UnwrapKey decrypts a key wrapped with [WrapKey].
+func UnwrapKey(kek Key, wrapped []byte) (Key, error) {
+	if len(wrapped) != WrappedKeySize {
+		return Key{}, errors.New("xaes256gcm: wrapped key has the wrong size")
+	}
+	dek, err := kek.AEAD().Open(nil, nil, wrapped, []byte(wrapAD))
+	if err != nil {
+		return Key{}, errors.New("xaes256gcm: failed to unwrap key")
+	}
+	return NewKey(dek)
+}
@@ -0,0 +1,34 @@
+package xaes256gcm_test
+
+import (
+	"testing"
+
+	"filippo.io/xaes256gcm"
+)
+
+func TestWrapKey(t *testing.T) {
+	kek, dek := xaes256gcm.GenerateKey(), xaes256gcm.GenerateKey()
+	wrapped := xaes256gcm.WrapKey(kek, dek)
+	if len(wrapped) != xaes256gcm.WrappedKeySize {
+		t.Errorf("wrapped key is %d bytes", len(wrapped))
+	}
+	got, err := xaes256gcm.UnwrapKey(kek, wrapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != dek {
+		t.Errorf("unwrapped the wrong key")
+	}
+
+	if _, err := xaes256gcm.UnwrapKey(dek, wrapped); err == nil {
+		t.Errorf("unwrapped with the wrong key")
+	}
+	if _, err := xaes256gcm.UnwrapKey(kek, wrapped[:len(wrapped)-1]); err == nil {
+		t.Errorf("unwrapped truncated key")
+	}
+	// Regular messages of the right size can't be unwrapped.
+	msg := kek.AEAD().Seal(nil, nil, dek.Bytes(), nil)
+	if _, err := xaes256gcm.UnwrapKey(kek, msg); err == nil {
+		t.Errorf("unwrapped a message without the wrapping context")
+	}
+}
@@ -0,0 +1,141 @@
+// Package xaesage implements [age] recipients and identities that wrap file
+// keys with a pre-shared XAES-256-GCM key.
+//
+// Recipient stanzas have the form
+//
+//	-> xaes256gcm <base64 nonce>
+//	<base64 XAES-256-GCM ciphertext of the file key>
+//
+// where the nonce is 24 random bytes, and the ciphertext binds the
+// "age-encryption.org/xaes256gcm" label as additional data.
+//
+// [age]: https://age-encryption.org
+package xaesage
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"filippo.io/age"
+	"filippo.io/xaes256gcm"
+)
+
+const (
+	stanzaType  = "xaes256gcm"
+	label       = "age-encryption.org/xaes256gcm"
+	fileKeySize = 16
+)
+
+var b64 = base64.RawStdEncoding.Strict()
+
+// Recipient is an age recipient that wraps file keys with a pre-shared
+// XAES-256-GCM key. Anyone with the key can decrypt the file.
+//
+// A Recipient can't be used together with other recipients, including other
+// Recipients, see [Recipient.WrapWithLabels].
+type Recipient struct {
+	aead cipher.AEAD
+}
+
+var _ age.Recipient = &Recipient{}
+var _ age.RecipientWithLabels = &Recipient{}
+
+// NewRecipient returns a new Recipient for the 32-byte key.
+func NewRecipient(key []byte) (*Recipient, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Recipient{aead: aead}, nil
+}
+
+func (r *Recipient) Wrap(fileKey []byte) ([]*age.Stanza, error) {
+	nonce := make([]byte, xaes256gcm.NonceSize)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return []*age.Stanza{{
+		Type: stanzaType,
+		Args: []string{b64.EncodeToString(nonce)},
+		Body: r.aead.Seal(nil, nonce, fileKey, []byte(label)),
+	}}, nil
+}
+
+// WrapWithLabels implements [age.RecipientWithLabels], returning a random
+// label, so that age refuses to encrypt a file to a Recipient and any other
+// recipient.
+//
+// Since only holders of the pre-shared key can produce a file encrypted to a
+// Recipient, such a file is implicitly authenticated by the key. If the file
+// were also encrypted to other recipients, those parties could produce
+// different files that decrypt successfully with the key.
+func (r *Recipient) WrapWithLabels(fileKey []byte) ([]*age.Stanza, []string, error) {
+	stanzas, err := r.Wrap(fileKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	random := make([]byte, 16)
+	if _, err := rand.Read(random); err != nil {
+		return nil, nil, err
+	}
+	return stanzas, []string{hex.EncodeToString(random)}, nil
+}
+
+// Identity is an age identity that unwraps file keys wrapped by a Recipient
+// with the same key.
+type Identity struct {
+	aead cipher.AEAD
+}
+
+var _ age.Identity = &Identity{}
+
+// NewIdentity returns a new Identity for the 32-byte key.
+func NewIdentity(key []byte) (*Identity, error) {
+	aead, err := xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{aead: aead}, nil
+}
+
+// Unwrap returns the file key from the first xaes256gcm stanza that was
+// wrapped with this Identity's key, or [age.ErrIncorrectIdentity] if there is
+// none.
+func (i *Identity) Unwrap(stanzas []*age.Stanza) ([]byte, error) {
+	for _, s := range stanzas {
+		if s.Type != stanzaType {
+			continue
+		}
+		fileKey, err := i.unwrap(s)
+		if errors.Is(err, age.ErrIncorrectIdentity) {
+			continue
+		}
+		return fileKey, err
+	}
+	return nil, age.ErrIncorrectIdentity
+}
+
+func (i *Identity) unwrap(s *age.Stanza) ([]byte, error) {
+	if len(s.Args) != 1 {
+		return nil, errors.New("invalid xaes256gcm recipient block")
+	}
+	nonce, err := b64.DecodeString(s.Args[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse xaes256gcm nonce: %v", err)
+	}
+	if len(nonce) != xaes256gcm.NonceSize {
+		return nil, errors.New("invalid xaes256gcm nonce size")
+	}
+	if len(s.Body) != fileKeySize+xaes256gcm.OverheadWithManualNonces {
+		return nil, errors.New("invalid xaes256gcm recipient block")
+	}
+	fileKey, err := i.aead.Open(nil, nonce, s.Body, []byte(label))
+	if err != nil {
+		return nil, age.ErrIncorrectIdentity
+	}
+	return fileKey, nil
+}
@@ -0,0 +1,78 @@
+package xaesage_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"filippo.io/age"
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaesage"
+)
+
+func TestRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	r, err := xaesage.NewRecipient(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i, err := xaesage.NewIdentity(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	w, err := age.Encrypt(buf, r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(w, "hello, age"); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := buf.Bytes()
+
+	out, err := age.Decrypt(bytes.NewReader(ciphertext), i)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := io.ReadAll(out); err != nil {
+		t.Fatal(err)
+	} else if string(got) != "hello, age" {
+		t.Errorf("got %q", got)
+	}
+
+	wrong, err := xaesage.NewIdentity(bytes.Repeat([]byte{0x02}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = age.Decrypt(bytes.NewReader(ciphertext), wrong)
+	var noMatch *age.NoIdentityMatchError
+	if !errors.As(err, &noMatch) {
+		t.Errorf("expected NoIdentityMatchError, got %v", err)
+	}
+}
+
+func TestMixedRecipients(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	r, err := xaesage.NewRecipient(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := xaesage.NewRecipient(bytes.Repeat([]byte{0x02}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	x25519, err := age.GenerateX25519Identity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, others := range []age.Recipient{other, x25519.Recipient()} {
+		if _, err := age.Encrypt(io.Discard, r, others); err == nil {
+			t.Errorf("encrypted to a Recipient mixed with %T", others)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+// Package xaescache encrypts values stored in shared caches, such as Redis or
+// memcached, so that session data and personal information are never stored
+// in the cache in plaintext.
+//
+// An encoded value is a ciphertext produced by an AEAD returned by
+// [filippo.io/xaes256gcm.New], with the cache key as additional data, so
+// values can't be moved to a different key. The plaintext is a 4-byte
+// big-endian Unix timestamp of the expiration time, or zero if the value
+// doesn't expire, followed by the value. This adds 44 bytes to each value.
+//
+// The expiration time is meant to be the same as the TTL set in the cache, and
+// lets [Codec.Decode] reject stale values if the cache didn't evict them, for
+// example after a restore from a snapshot.
+package xaescache
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Overhead is the difference between the size of an encoded value and the
+// size of the value.
+const Overhead = 4 + xaes256gcm.Overhead
+
+// ErrExpired is returned by [Codec.Decode] for authentic values that are past
+// their expiration time.
+var ErrExpired = errors.New("xaescache: value expired")
+
+// ValueCodec is the interface implemented by [Codec], for cache clients and
+// wrappers that accept a pluggable value codec.
+type ValueCodec interface {
+	// Encode returns the encoding of value to be stored at key, which expires
+	// after ttl, or never if ttl is zero.
+	Encode(key string, value []byte, ttl time.Duration) ([]byte, error)
+	// Decode returns the value stored at key.
+	Decode(key string, data []byte) ([]byte, error)
+}
+
+// Codec encodes and decodes cache values. It is safe for concurrent use.
+type Codec struct {
+	// Now, if not nil, is used instead of time.Now to compute and check
+	// expiration times.
+	Now func() time.Time
+
+	aead cipher.AEAD
+}
+
+var _ ValueCodec = (*Codec)(nil)
+
+// New returns a Codec that encrypts values with aead, which must be returned
+// by [filippo.io/xaes256gcm.New].
+func New(aead cipher.AEAD) (*Codec, error) {
+	if aead.NonceSize() != 0 {
+		return nil, errors.New("xaescache: AEAD must generate nonces automatically, use xaes256gcm.New")
+	}
+	return &Codec{aead: aead}, nil
+}
+
+func (c *Codec) now() time.Time {
+	if c.Now != nil {
+		return c.Now()
+	}
+	return time.Now()
+}
+
+// Encode encrypts value, to be stored at key. If ttl is not zero, the
+// encoded value expires after ttl, rounded up to the second.
+func (c *Codec) Encode(key string, value []byte, ttl time.Duration) ([]byte, error) {
+	if ttl < 0 {
+		return nil, errors.New("xaescache: negative TTL")
+	}
+	var expires uint32
+	if ttl > 0 {
+		exp := c.now().Add(ttl + time.Second - 1).Unix()
+		if exp > 1<<32-1 {
+			return nil, errors.New("xaescache: TTL too large")
+		}
+		expires = uint32(exp)
+	}
+	plaintext := make([]byte, 4, 4+len(value))
+	binary.BigEndian.PutUint32(plaintext, expires)
+	plaintext = append(plaintext, value...)
+	return c.aead.Seal(make([]byte, 0, len(plaintext)+xaes256gcm.Overhead), nil, plaintext, []byte(key)), nil
+}
+
+// Decode decrypts a value encoded by [Codec.Encode] for key. It returns
+// [ErrExpired] if the value is past its expiration time.
+func (c *Codec) Decode(key string, data []byte) ([]byte, error) {
+	plaintext, err := c.aead.Open(nil, nil, data, []byte(key))
+	if err != nil || len(plaintext) < 4 {
+		return nil, errors.New("xaescache: failed to decrypt value")
+	}
+	if expires := binary.BigEndian.Uint32(plaintext); expires != 0 &&
+		!c.now().Before(time.Unix(int64(expires), 0)) {
+		return nil, ErrExpired
+	}
+	return plaintext[4:], nil
+}
@@ -0,0 +1,64 @@
+package xaescache_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaescache"
+)
+
+func TestCodec(t *testing.T) {
+	c, err := xaescache.New(xaes256gcm.GenerateKey().AEAD())
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1700000000, 0)
+	c.Now = func() time.Time { return now }
+
+	data, err := c.Encode("session:alice", []byte("admin=true"), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != len("admin=true")+xaescache.Overhead {
+		t.Errorf("unexpected length %d", len(data))
+	}
+	if bytes.Contains(data, []byte("admin")) {
+		t.Errorf("value not encrypted")
+	}
+	if v, err := c.Decode("session:alice", data); err != nil || string(v) != "admin=true" {
+		t.Errorf("got %q, %v", v, err)
+	}
+	if _, err := c.Decode("session:mallory", data); err == nil {
+		t.Errorf("value moved to a different key decoded")
+	}
+
+	now = now.Add(time.Hour)
+	if _, err := c.Decode("session:alice", data); err != xaescache.ErrExpired {
+		t.Errorf("got %v, expected ErrExpired", err)
+	}
+
+	forever, err := c.Encode("config", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(1000 * 24 * time.Hour)
+	if v, err := c.Decode("config", forever); err != nil || len(v) != 0 {
+		t.Errorf("got %q, %v", v, err)
+	}
+
+	if _, err := c.Encode("k", nil, -time.Second); err == nil {
+		t.Errorf("negative TTL accepted")
+	}
+	if _, err := c.Decode("config", forever[:len(forever)-1]); err == nil {
+		t.Errorf("truncated value decoded")
+	}
+	manual, err := xaes256gcm.NewWithManualNonces(make([]byte, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xaescache.New(manual); err == nil {
+		t.Errorf("manual nonces AEAD accepted")
+	}
+}
@@ -0,0 +1,96 @@
+// Package xaesgob implements values that are transparently encrypted when
+// marshaled with encoding/gob, or with any store that uses
+// [encoding.BinaryMarshaler], such as many embedded key-value databases.
+package xaesgob
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding"
+	"encoding/gob"
+	"errors"
+)
+
+// Key provides the AEAD and context label used by an [EncryptedValue] type.
+//
+// Since encoding/gob and similar packages allocate values when decoding, and
+// have no way to pass them a key, the key is selected by a type parameter.
+// Implementations are usually empty struct types that return a package-level
+// AEAD, for example
+//
+//	type sessionKey struct{}
+//
+//	func (sessionKey) AEAD() cipher.AEAD { return sessionAEAD }
+//	func (sessionKey) Label() string     { return "example.com/session" }
+//
+//	type Session struct {
+//		ID    string
+//		Token xaesgob.EncryptedValue[[]byte, sessionKey]
+//	}
+type Key interface {
+	// AEAD returns an AEAD returned by [filippo.io/xaes256gcm.New].
+	AEAD() cipher.AEAD
+	// Label returns a label that is authenticated as additional data, and
+	// should identify the purpose of the value.
+	Label() string
+}
+
+// EncryptedValue holds a value of type T that is encrypted with the key
+// provided by K when marshaled.
+//
+// If T implements [encoding.BinaryMarshaler] and [encoding.BinaryUnmarshaler],
+// those methods are used to encode the plaintext, otherwise encoding/gob is
+// used.
+type EncryptedValue[T any, K Key] struct {
+	Value T
+}
+
+var errManualNonces = errors.New("xaesgob: AEAD must generate nonces automatically, use xaes256gcm.New")
+
+// MarshalBinary implements [encoding.BinaryMarshaler].
+func (v EncryptedValue[T, K]) MarshalBinary() ([]byte, error) {
+	var k K
+	aead := k.AEAD()
+	if aead.NonceSize() != 0 {
+		return nil, errManualNonces
+	}
+	var plaintext []byte
+	if m, ok := any(&v.Value).(encoding.BinaryMarshaler); ok {
+		var err error
+		if plaintext, err = m.MarshalBinary(); err != nil {
+			return nil, err
+		}
+	} else {
+		buf := &bytes.Buffer{}
+		if err := gob.NewEncoder(buf).Encode(&v.Value); err != nil {
+			return nil, err
+		}
+		plaintext = buf.Bytes()
+	}
+	return aead.Seal(nil, nil, plaintext, []byte(k.Label())), nil
+}
+
+// UnmarshalBinary implements [encoding.BinaryUnmarshaler].
+func (v *EncryptedValue[T, K]) UnmarshalBinary(data []byte) error {
+	var k K
+	aead := k.AEAD()
+	if aead.NonceSize() != 0 {
+		return errManualNonces
+	}
+	plaintext, err := aead.Open(nil, nil, data, []byte(k.Label()))
+	if err != nil {
+		return err
+	}
+	var value T
+	if u, ok := any(&value).(encoding.BinaryUnmarshaler); ok {
+		if err := u.UnmarshalBinary(plaintext); err != nil {
+			return err
+		}
+	} else {
+		if err := gob.NewDecoder(bytes.NewReader(plaintext)).Decode(&value); err != nil {
+			return err
+		}
+	}
+	v.Value = value
+	return nil
+}
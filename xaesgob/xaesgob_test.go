@@ -0,0 +1,68 @@
+package xaesgob_test
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/gob"
+	"net/netip"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaesgob"
+)
+
+var testAEAD, _ = xaes256gcm.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+
+type testKey struct{}
+
+func (testKey) AEAD() cipher.AEAD { return testAEAD }
+func (testKey) Label() string     { return "test" }
+
+type otherKey struct{}
+
+func (otherKey) AEAD() cipher.AEAD { return testAEAD }
+func (otherKey) Label() string     { return "other" }
+
+type secret struct {
+	Password string
+	PIN      int
+}
+
+type record struct {
+	ID     string
+	Secret xaesgob.EncryptedValue[secret, testKey]
+	Addr   xaesgob.EncryptedValue[netip.Addr, testKey]
+}
+
+func TestGob(t *testing.T) {
+	r := record{ID: "alice"}
+	r.Secret.Value = secret{Password: "hunter2", PIN: 1234}
+	r.Addr.Value = netip.MustParseAddr("192.0.2.1")
+
+	buf := &bytes.Buffer{}
+	if err := gob.NewEncoder(buf).Encode(r); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("hunter2")) {
+		t.Errorf("plaintext found in encoded value")
+	}
+	var got record
+	if err := gob.NewDecoder(bytes.NewReader(buf.Bytes())).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != r {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestLabel(t *testing.T) {
+	v := xaesgob.EncryptedValue[string, testKey]{Value: "hello"}
+	data, err := v.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var other xaesgob.EncryptedValue[string, otherKey]
+	if err := other.UnmarshalBinary(data); err == nil {
+		t.Errorf("value decrypted with a different label")
+	}
+}
@@ -0,0 +1,57 @@
+package xaesgrpc_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaesgrpc"
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+func (jsonCodec) Name() string                       { return "json" }
+
+type request struct {
+	Name string
+}
+
+func TestCodec(t *testing.T) {
+	aead, err := xaes256gcm.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := xaesgrpc.NewCodec(aead, jsonCodec{}, "example.Greeter", "json-xaes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := xaesgrpc.NewCodec(aead, jsonCodec{}, "example.Admin", "json-xaes-admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := c.Marshal(&request{Name: "Alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("Alice")) {
+		t.Errorf("plaintext found in payload")
+	}
+	var got request
+	if err := c.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "Alice" {
+		t.Errorf("got %+v", got)
+	}
+	if err := other.Unmarshal(data, &got); err == nil {
+		t.Errorf("payload decrypted with a different label")
+	}
+
+	if _, err := xaesgrpc.NewCodec(aead, jsonCodec{}, "", "json"); err == nil {
+		t.Errorf("codec with the same name as the inner codec created")
+	}
+}
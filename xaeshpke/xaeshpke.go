@@ -0,0 +1,135 @@
+// Package xaeshpke uses XAES-256-GCM as the AEAD of an HPKE context.
+//
+// HPKE (RFC 9180) only allows registered AEADs, so this package instead
+// instantiates the HPKE context with the export-only AEAD and derives an
+// XAES-256-GCM key and base nonce from the exporter secret. Sealer and Opener
+// then behave like the HPKE Seal and Open context functions, computing the
+// 24-byte nonce of each message from the base nonce and a sequence number.
+//
+// For example, with crypto/hpke:
+//
+//	enc, s, err := hpke.NewSender(pk, hpke.HKDFSHA256(), hpke.ExportOnly(), info)
+//	sealer, err := xaeshpke.NewSealer(s)
+package xaeshpke
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Exporter is the exporter interface of an HPKE context. It is implemented by
+// *crypto/hpke.Sender and *crypto/hpke.Recipient.
+type Exporter interface {
+	Export(exporterContext string, length int) ([]byte, error)
+}
+
+const (
+	keyContext   = "XAES-256-GCM key"
+	nonceContext = "XAES-256-GCM base_nonce"
+)
+
+var errLimit = errors.New("xaeshpke: message limit reached")
+
+type context struct {
+	aead      cipher.AEAD
+	baseNonce [xaes256gcm.NonceSize]byte
+	seq       uint64
+}
+
+func newContext(e Exporter) (*context, error) {
+	key, err := e.Export(keyContext, xaes256gcm.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	baseNonce, err := e.Export(nonceContext, xaes256gcm.NonceSize)
+	if err != nil {
+		return nil, err
+	}
+	c := &context{}
+	c.aead, err = xaes256gcm.NewWithManualNonces(key)
+	if err != nil {
+		return nil, err
+	}
+	copy(c.baseNonce[:], baseNonce)
+	return c, nil
+}
+
+// nonce returns the base nonce XORed with the big-endian sequence number, as
+// in RFC 9180, Section 5.2.
+func (c *context) nonce() ([]byte, error) {
+	if c.seq == 1<<64-1 {
+		return nil, errLimit
+	}
+	nonce := c.baseNonce
+	var seq [8]byte
+	binary.BigEndian.PutUint64(seq[:], c.seq)
+	subtle.XORBytes(nonce[len(nonce)-8:], nonce[len(nonce)-8:], seq[:])
+	return nonce[:], nil
+}
+
+// Sealer is the sending side of an HPKE context using XAES-256-GCM.
+//
+// Like the HPKE context it is derived from, a Sealer is stateful and is not
+// safe for concurrent use.
+type Sealer struct {
+	c *context
+}
+
+// NewSealer returns a Sealer using keys exported from e, which should be the
+// sending HPKE context.
+func NewSealer(e Exporter) (*Sealer, error) {
+	c, err := newContext(e)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{c: c}, nil
+}
+
+// Seal encrypts and authenticates plaintext, authenticates aad, and returns
+// the ciphertext. Each call uses the next sequence number.
+func (s *Sealer) Seal(aad, plaintext []byte) ([]byte, error) {
+	nonce, err := s.c.nonce()
+	if err != nil {
+		return nil, err
+	}
+	s.c.seq++
+	return s.c.aead.Seal(nil, nonce, plaintext, aad), nil
+}
+
+// Opener is the receiving side of an HPKE context using XAES-256-GCM.
+//
+// Like the HPKE context it is derived from, an Opener is stateful and is not
+// safe for concurrent use.
+type Opener struct {
+	c *context
+}
+
+// NewOpener returns an Opener using keys exported from e, which should be the
+// receiving HPKE context.
+func NewOpener(e Exporter) (*Opener, error) {
+	c, err := newContext(e)
+	if err != nil {
+		return nil, err
+	}
+	return &Opener{c: c}, nil
+}
+
+// Open decrypts and authenticates ciphertext and aad. Messages must be opened
+// in the same order they were sealed. The sequence number is only
+// incremented if Open succeeds.
+func (o *Opener) Open(aad, ciphertext []byte) ([]byte, error) {
+	nonce, err := o.c.nonce()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := o.c.aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, err
+	}
+	o.c.seq++
+	return plaintext, nil
+}
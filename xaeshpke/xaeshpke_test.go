@@ -0,0 +1,61 @@
+//go:build go1.26
+
+package xaeshpke_test
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/hpke"
+	"testing"
+
+	"filippo.io/xaes256gcm/xaeshpke"
+)
+
+func TestRoundTrip(t *testing.T) {
+	kem := hpke.DHKEM(ecdh.X25519())
+	k, err := kem.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := []byte("xaeshpke test")
+	enc, s, err := hpke.NewSender(k.PublicKey(), hpke.HKDFSHA256(), hpke.ExportOnly(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := hpke.NewRecipient(enc, k, hpke.HKDFSHA256(), hpke.ExportOnly(), info)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sealer, err := xaeshpke.NewSealer(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opener, err := xaeshpke.NewOpener(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c1, err := sealer.Seal([]byte("aad"), []byte("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := sealer.Seal(nil, []byte("second"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := opener.Open(nil, c2); err == nil {
+		t.Errorf("out of order message opened")
+	}
+	if p, err := opener.Open([]byte("aad"), c1); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(p, []byte("first")) {
+		t.Errorf("got %q", p)
+	}
+	if p, err := opener.Open(nil, c2); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(p, []byte("second")) {
+		t.Errorf("got %q", p)
+	}
+}
@@ -0,0 +1,243 @@
+// Package xaeshttp encrypts HTTP request and response bodies with
+// XAES-256-GCM, for service-to-service links that need payload encryption
+// independent of where TLS is terminated.
+//
+// [Handler] wraps a server handler and [Transport] wraps a client transport.
+// Both sides use the same key. Bodies are encrypted with the chunked format of
+// [filippo.io/xaes256gcm/stream], so large payloads are processed in constant
+// memory, and are marked with "Content-Encoding: xaes256gcm".
+//
+// The client generates a random request nonce for each request, and sends it
+// in the Xaes-Request-Nonce header. Each body is encrypted with a key derived
+// with HKDF-SHA256 from the shared key, the direction, the method, the URL
+// path, and the request nonce, so a body can't be moved to a different
+// method or path, and a response can't be served for a different request.
+// Requests are always sent with an encrypted body, even if empty, so the body
+// can't be stripped.
+//
+// The status code, the headers other than Content-Encoding, and the URL query
+// are not encrypted or authenticated. Requests can be replayed: handlers that
+// are not idempotent need their own replay protection, for example with
+// [filippo.io/xaes256gcm/replay].
+package xaeshttp
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/stream"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// ContentEncoding is the value of the Content-Encoding header of
+	// encrypted bodies.
+	ContentEncoding = "xaes256gcm"
+
+	// NonceHeader is the header that carries the request nonce.
+	NonceHeader = "Xaes-Request-Nonce"
+)
+
+func bodyKey(key xaes256gcm.Key, direction, method, path, nonce string) []byte {
+	info := "filippo.io/xaes256gcm/xaeshttp " + direction + "\x00" +
+		method + "\x00" + path + "\x00" + nonce
+	k := make([]byte, xaes256gcm.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key.Bytes(), nil, []byte(info)), k); err != nil {
+		panic("xaeshttp: internal error: " + err.Error())
+	}
+	return k
+}
+
+// Handler returns a handler that decrypts request bodies and encrypts response
+// bodies with key, and calls h.
+//
+// Requests without an encrypted body or a request nonce are rejected with 400
+// Bad Request. If a request body fails to decrypt, reads from it return an
+// error, which h must handle like any other body read error, without acting on
+// the data read so far. Response bodies are buffered in chunks, so
+// [http.Flusher] is not supported.
+func Handler(key xaes256gcm.Key, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nonce := r.Header.Get(NonceHeader)
+		if r.Header.Get("Content-Encoding") != ContentEncoding || len(nonce) != nonceLen {
+			http.Error(w, "xaeshttp: request is not encrypted", http.StatusBadRequest)
+			return
+		}
+		body, err := stream.NewReader(bodyKey(key, "request", r.Method, r.URL.Path, nonce), r.Body)
+		if err != nil {
+			http.Error(w, "xaeshttp: malformed encrypted request", http.StatusBadRequest)
+			return
+		}
+		r2 := r.Clone(r.Context())
+		r2.Body = struct {
+			io.Reader
+			io.Closer
+		}{body, r.Body}
+		r2.ContentLength = -1
+		r2.Header.Del("Content-Encoding")
+		r2.Header.Del("Content-Length")
+
+		ew := &encryptingWriter{w: w, method: r.Method,
+			key: bodyKey(key, "response", r.Method, r.URL.Path, nonce)}
+		h.ServeHTTP(ew, r2)
+		if err := ew.close(); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+// encryptingWriter is a ResponseWriter that encrypts the response body.
+type encryptingWriter struct {
+	w           http.ResponseWriter
+	method      string
+	key         []byte
+	wroteHeader bool
+	sw          *stream.Writer
+	err         error
+}
+
+func (w *encryptingWriter) Header() http.Header {
+	return w.w.Header()
+}
+
+func (w *encryptingWriter) WriteHeader(code int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+	if !bodyAllowed(w.method, code) {
+		w.w.WriteHeader(code)
+		w.err = http.ErrBodyNotAllowed
+		return
+	}
+	h := w.w.Header()
+	h.Set("Content-Encoding", ContentEncoding)
+	h.Del("Content-Length")
+	w.w.WriteHeader(code)
+	w.sw, w.err = stream.NewWriter(w.key, w.w)
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	if w.err != nil {
+		return 0, w.err
+	}
+	return w.sw.Write(p)
+}
+
+func (w *encryptingWriter) close() error {
+	w.WriteHeader(http.StatusOK)
+	if w.err == http.ErrBodyNotAllowed {
+		return nil
+	}
+	if w.err != nil {
+		return w.err
+	}
+	return w.sw.Close()
+}
+
+// bodyAllowed reports whether a response to method with status code can have
+// a body. Responses that can't are sent without encryption.
+func bodyAllowed(method string, code int) bool {
+	return method != http.MethodHead && code >= 200 &&
+		code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+const nonceLen = 22 // 16 bytes, base64url without padding
+
+// Transport returns a RoundTripper that encrypts request bodies and decrypts
+// response bodies with key, for requests to a server using [Handler]. base is
+// used to make the requests, or [http.DefaultTransport] if nil.
+//
+// Responses that are not encrypted are rejected with an error, and response
+// bodies that fail to decrypt return an error from Read.
+func Transport(key xaes256gcm.Key, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{key: key, base: base}
+}
+
+type transport struct {
+	key  xaes256gcm.Key
+	base http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var n [16]byte
+	if _, err := rand.Read(n[:]); err != nil {
+		return nil, err
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(n[:])
+	reqKey := bodyKey(t.key, "request", req.Method, req.URL.Path, nonce)
+
+	// RoundTrippers must not modify the request, and must close its body.
+	r := req.Clone(req.Context())
+	r.Header.Set(NonceHeader, nonce)
+	r.Header.Set("Content-Encoding", ContentEncoding)
+	newBody := func(body io.ReadCloser) (io.ReadCloser, error) {
+		if body == nil {
+			body = http.NoBody
+		}
+		enc, err := stream.NewEncryptingReader(reqKey, body)
+		if err != nil {
+			body.Close()
+			return nil, err
+		}
+		return struct {
+			io.Reader
+			io.Closer
+		}{enc, body}, nil
+	}
+	body, err := newBody(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Body = body
+	if req.GetBody != nil {
+		r.GetBody = func() (io.ReadCloser, error) {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			return newBody(body)
+		}
+	} else if req.Body == nil || req.Body == http.NoBody {
+		r.GetBody = func() (io.ReadCloser, error) { return newBody(nil) }
+	}
+	r.ContentLength = -1
+	if req.ContentLength > 0 || req.Body == nil || req.Body == http.NoBody {
+		r.ContentLength = stream.EncryptedSize(max(req.ContentLength, 0))
+	}
+
+	resp, err := t.base.RoundTrip(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bodyAllowed(req.Method, resp.StatusCode) {
+		return resp, nil
+	}
+	if resp.Header.Get("Content-Encoding") != ContentEncoding {
+		resp.Body.Close()
+		return nil, errors.New("xaeshttp: response is not encrypted")
+	}
+	dec, err := stream.NewReader(bodyKey(t.key, "response", req.Method, req.URL.Path, nonce), resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, errors.New("xaeshttp: malformed encrypted response")
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{dec, resp.Body}
+	resp.Header.Del("Content-Encoding")
+	resp.Header.Del("Content-Length")
+	resp.ContentLength = -1
+	resp.Uncompressed = true
+	return resp, nil
+}
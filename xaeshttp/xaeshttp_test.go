@@ -0,0 +1,134 @@
+package xaeshttp_test
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/stream"
+	"filippo.io/xaes256gcm/xaeshttp"
+)
+
+func TestRoundTrip(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	large := bytes.Repeat([]byte("xaes"), 3*stream.ChunkSize)
+	var seen [][]byte
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		seen = append(seen, body)
+		switch r.URL.Path {
+		case "/empty":
+			w.WriteHeader(http.StatusNoContent)
+		case "/large":
+			w.Write(large)
+		default:
+			w.Header().Set("Content-Length", "1000")
+			io.WriteString(w, r.Method+" "+string(body))
+		}
+	})
+	var raw []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := httptest.NewRecorder()
+		xaeshttp.Handler(key, h).ServeHTTP(rec, r)
+		raw = append(raw, rec.Body.String())
+		for k, v := range rec.Header() {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(rec.Code)
+		w.Write(rec.Body.Bytes())
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: xaeshttp.Transport(key, nil)}
+
+	resp, err := client.Post(srv.URL+"/echo", "text/plain", strings.NewReader("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || string(body) != "POST hunter2" {
+		t.Errorf("got %q, %v", body, err)
+	}
+	if strings.Contains(raw[len(raw)-1], "hunter2") {
+		t.Errorf("response not encrypted")
+	}
+
+	resp, err = client.Get(srv.URL + "/echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "GET " {
+		t.Errorf("got %q", body)
+	}
+
+	resp, err = client.Post(srv.URL+"/large", "application/octet-stream", bytes.NewReader(large))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || !bytes.Equal(body, large) || !bytes.Equal(seen[len(seen)-1], large) {
+		t.Errorf("large body corrupted: %v", err)
+	}
+
+	resp, err = client.Get(srv.URL + "/empty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("got status %d", resp.StatusCode)
+	}
+
+	// Plaintext requests are rejected.
+	resp, err = http.Post(srv.URL+"/echo", "text/plain", strings.NewReader("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("plaintext request got status %d", resp.StatusCode)
+	}
+}
+
+// rewriter is a RoundTripper that changes the path of requests after they
+// were encrypted, like a malicious intermediary.
+type rewriter struct{ path string }
+
+func (r rewriter) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Path = r.path
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestPathBinding(t *testing.T) {
+	key := xaes256gcm.GenerateKey()
+	var readErr error
+	srv := httptest.NewServer(xaeshttp.Handler(key, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, readErr = io.ReadAll(r.Body)
+		io.WriteString(w, "ok")
+	})))
+	defer srv.Close()
+	client := &http.Client{Transport: xaeshttp.Transport(key, rewriter{"/admin"})}
+	resp, err := client.Post(srv.URL+"/user", "text/plain", strings.NewReader("payload"))
+	if err == nil {
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	if readErr == nil {
+		t.Errorf("request body with rewritten path decrypted")
+	}
+	if err == nil {
+		t.Errorf("response to rewritten path decrypted")
+	}
+}
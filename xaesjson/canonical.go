@@ -0,0 +1,200 @@
+package xaesjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Canonicalize returns the canonical form of the JSON document data, as
+// specified by the JSON Canonicalization Scheme (RFC 8785), for use as
+// additional data. Documents that differ only in whitespace, object key
+// order, string escaping, or number formatting have the same canonical form,
+// so re-serializing metadata doesn't break decryption.
+//
+// Object keys are sorted by their UTF-16 code units, numbers are formatted
+// like ECMAScript's Number.prototype.toString, and strings are escaped
+// minimally. Documents with duplicate object keys, invalid UTF-8, or numbers
+// that don't fit in a float64 are rejected.
+func Canonicalize(data []byte) ([]byte, error) {
+	if !utf8.Valid(data) {
+		return nil, errors.New("xaesjson: document is not valid UTF-8")
+	}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var out bytes.Buffer
+	if err := canonicalValue(d, &out); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("xaesjson: trailing data after JSON document")
+	}
+	return out.Bytes(), nil
+}
+
+// MarshalCanonical marshals v as JSON, and returns its canonical form, like
+// [Canonicalize].
+func MarshalCanonical(v any) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return Canonicalize(data)
+}
+
+func canonicalValue(d *json.Decoder, out *bytes.Buffer) error {
+	tok, err := d.Token()
+	if err != nil {
+		return fmt.Errorf("xaesjson: invalid JSON document: %w", err)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			members := make(map[string][]byte)
+			var keys []string
+			for d.More() {
+				key, err := d.Token()
+				if err != nil {
+					return fmt.Errorf("xaesjson: invalid JSON document: %w", err)
+				}
+				k := key.(string)
+				if _, ok := members[k]; ok {
+					return fmt.Errorf("xaesjson: duplicate object key %q", k)
+				}
+				var v bytes.Buffer
+				if err := canonicalValue(d, &v); err != nil {
+					return err
+				}
+				members[k] = v.Bytes()
+				keys = append(keys, k)
+			}
+			sort.Slice(keys, func(i, j int) bool { return lessUTF16(keys[i], keys[j]) })
+			out.WriteByte('{')
+			for i, k := range keys {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				canonicalString(out, k)
+				out.WriteByte(':')
+				out.Write(members[k])
+			}
+			out.WriteByte('}')
+		case '[':
+			out.WriteByte('[')
+			for i := 0; d.More(); i++ {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				if err := canonicalValue(d, out); err != nil {
+					return err
+				}
+			}
+			out.WriteByte(']')
+		}
+		if _, err := d.Token(); err != nil {
+			return fmt.Errorf("xaesjson: invalid JSON document: %w", err)
+		}
+	case string:
+		canonicalString(out, tok)
+	case json.Number:
+		f, err := strconv.ParseFloat(string(tok), 64)
+		if err != nil || math.IsInf(f, 0) {
+			return fmt.Errorf("xaesjson: number %s out of range", tok)
+		}
+		out.WriteString(canonicalNumber(f))
+	case bool:
+		out.WriteString(strconv.FormatBool(tok))
+	case nil:
+		out.WriteString("null")
+	}
+	return nil
+}
+
+func lessUTF16(a, b string) bool {
+	ua, ub := utf16.Encode([]rune(a)), utf16.Encode([]rune(b))
+	for i := 0; i < len(ua) && i < len(ub); i++ {
+		if ua[i] != ub[i] {
+			return ua[i] < ub[i]
+		}
+	}
+	return len(ua) < len(ub)
+}
+
+func canonicalString(out *bytes.Buffer, s string) {
+	out.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			out.WriteString(`\"`)
+		case '\\':
+			out.WriteString(`\\`)
+		case '\b':
+			out.WriteString(`\b`)
+		case '\f':
+			out.WriteString(`\f`)
+		case '\n':
+			out.WriteString(`\n`)
+		case '\r':
+			out.WriteString(`\r`)
+		case '\t':
+			out.WriteString(`\t`)
+		default:
+			if r < 0x20 {
+				fmt.Fprintf(out, `\u%04x`, r)
+			} else {
+				out.WriteRune(r)
+			}
+		}
+	}
+	out.WriteByte('"')
+}
+
+// canonicalNumber formats f like ECMAScript's Number.prototype.toString.
+func canonicalNumber(f float64) string {
+	if f == 0 {
+		return "0"
+	}
+	var sign string
+	if f < 0 {
+		sign, f = "-", -f
+	}
+	// The shortest representation that round-trips, as d.ddde±x.
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	mantissa, exp, _ := strings.Cut(e, "e")
+	digits := strings.Replace(mantissa, ".", "", 1)
+	x, _ := strconv.Atoi(exp)
+	k, n := len(digits), x+1
+	switch {
+	case k <= n && n <= 21:
+		return sign + digits + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return sign + digits[:n] + "." + digits[n:]
+	case -6 < n && n <= 0:
+		return sign + "0." + strings.Repeat("0", -n) + digits
+	}
+	expSign := "+"
+	if n-1 < 0 {
+		expSign = "-"
+	}
+	exponent := strconv.Itoa(abs(n - 1))
+	if k == 1 {
+		return sign + digits + "e" + expSign + exponent
+	}
+	return sign + digits[:1] + "." + digits[1:] + "e" + expSign + exponent
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
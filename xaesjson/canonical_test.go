@@ -0,0 +1,57 @@
+package xaesjson_test
+
+import (
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaesjson"
+)
+
+func TestCanonicalize(t *testing.T) {
+	for _, tc := range []struct{ in, out string }{
+		{`{"b": 2, "a": 1}`, `{"a":1,"b":2}`},
+		{" [ 1 , { \"z\":null ,\"y\" :[true,false]} ] ", `[1,{"y":[true,false],"z":null}]`},
+		{`"\u0041\u00e9\n\u001f\/<>"`, "\"A\u00e9\\n\\u001f/<>\""},
+		// RFC 8785, Section 3.2.3: keys are sorted by UTF-16 code units.
+		{`{"\u20ac":1,"\r":2,"\ud83d\ude00":3,"1":4,"\u00f6":5,"\u0080":6}`,
+			"{\"\\r\":2,\"1\":4,\"\u0080\":6,\"\u00f6\":5,\"\u20ac\":1,\"\U0001F600\":3}"},
+		// RFC 8785, Appendix B: number serialization.
+		{`[0, -0, 1e0, 100, 1.5e2, 0.000001, 1e-7, 1e21, 1e20, 123456789012345680000,
+			4.50, 2e-3, 9007199254740992, 5e-324, -1.7976931348623157e308, 0.1, 1E+30]`,
+			`[0,0,1,100,150,0.000001,1e-7,1e+21,100000000000000000000,123456789012345680000,` +
+				`4.5,0.002,9007199254740992,5e-324,-1.7976931348623157e+308,0.1,1e+30]`},
+	} {
+		got, err := xaesjson.Canonicalize([]byte(tc.in))
+		if err != nil {
+			t.Errorf("%s: %v", tc.in, err)
+			continue
+		}
+		if string(got) != tc.out {
+			t.Errorf("%s: got %s, expected %s", tc.in, got, tc.out)
+		}
+	}
+
+	for _, bad := range []string{`{"a":1,"a":2}`, `{"a":1} 2`, `[1,`, "\"\xff\"", `1e400`, ``} {
+		if _, err := xaesjson.Canonicalize([]byte(bad)); err == nil {
+			t.Errorf("canonicalized invalid document %q", bad)
+		}
+	}
+}
+
+func TestCanonicalAD(t *testing.T) {
+	aead := xaes256gcm.GenerateKey().AEAD()
+	ad, err := xaesjson.MarshalCanonical(map[string]any{"tenant": "acme", "version": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := aead.Seal(nil, nil, []byte("secret"), ad)
+
+	// The same metadata, serialized differently by another service.
+	other, err := xaesjson.Canonicalize([]byte(`{ "version": 2.0, "tenant": "\u0061cme" }`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := aead.Open(nil, nil, ciphertext, other); err != nil {
+		t.Errorf("reserialized metadata failed to authenticate: %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package xaesjson
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// valuePrefix marks the encrypted values of a document produced by
+// [EncryptValues].
+const valuePrefix = "xaes:"
+
+// EncryptValues encrypts the values of a JSON document, leaving its structure
+// and object keys in the clear, so that encrypted configuration files remain
+// reviewable and diffable. aead must be returned by
+// [filippo.io/xaes256gcm.New].
+//
+// Every string, number, and boolean is replaced by a string holding
+// "xaes:" followed by the unpadded base64url encoding of its JSON encoding,
+// sealed with the path of the value as additional data, so that values can't
+// be moved to different fields. The path is the JSON array of the object keys
+// and array indexes leading to the value. null values are not encrypted.
+//
+// Object key order is preserved, and the output is indented with two spaces.
+// Because nonces are random, encrypting the same document twice produces
+// different values.
+func EncryptValues(aead cipher.AEAD, doc []byte) ([]byte, error) {
+	if aead.NonceSize() != 0 {
+		return nil, errManualNonces
+	}
+	return transformValues(doc, func(path []any, v json.RawMessage) (json.RawMessage, error) {
+		ad, err := json.Marshal(path)
+		if err != nil {
+			return nil, err
+		}
+		ciphertext := aead.Seal(nil, nil, v, ad)
+		return json.Marshal(valuePrefix + base64.RawURLEncoding.EncodeToString(ciphertext))
+	})
+}
+
+// DecryptValues decrypts the values of a JSON document produced by
+// [EncryptValues]. Since EncryptValues encrypts every non-null value, it
+// returns an error if any string, number, or boolean is not encrypted, so that
+// encrypted values can't be replaced with plaintext ones.
+func DecryptValues(aead cipher.AEAD, doc []byte) ([]byte, error) {
+	if aead.NonceSize() != 0 {
+		return nil, errManualNonces
+	}
+	return transformValues(doc, func(path []any, v json.RawMessage) (json.RawMessage, error) {
+		ad, err := json.Marshal(path)
+		if err != nil {
+			return nil, err
+		}
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil || !strings.HasPrefix(s, valuePrefix) {
+			return nil, fmt.Errorf("xaesjson: value at %s is not encrypted", ad)
+		}
+		ciphertext, err := base64.RawURLEncoding.DecodeString(s[len(valuePrefix):])
+		if err != nil {
+			return nil, fmt.Errorf("xaesjson: invalid encrypted value at %s", ad)
+		}
+		plaintext, err := aead.Open(nil, nil, ciphertext, ad)
+		if err != nil || !json.Valid(plaintext) {
+			return nil, fmt.Errorf("xaesjson: failed to decrypt value at %s", ad)
+		}
+		return plaintext, nil
+	})
+}
+
+// transformValues rewrites doc, replacing every non-null scalar value with
+// the result of f, and returns it indented.
+func transformValues(doc []byte, f func(path []any, v json.RawMessage) (json.RawMessage, error)) ([]byte, error) {
+	d := json.NewDecoder(bytes.NewReader(doc))
+	d.UseNumber()
+	var out bytes.Buffer
+	if err := transformValue(d, &out, nil, f); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("xaesjson: trailing data after JSON document")
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, out.Bytes(), "", "  "); err != nil {
+		return nil, err
+	}
+	indented.WriteByte('\n')
+	return indented.Bytes(), nil
+}
+
+func transformValue(d *json.Decoder, out *bytes.Buffer, path []any, f func([]any, json.RawMessage) (json.RawMessage, error)) error {
+	tok, err := d.Token()
+	if err != nil {
+		return fmt.Errorf("xaesjson: invalid JSON document: %w", err)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		switch tok {
+		case '{':
+			out.WriteByte('{')
+			for i := 0; d.More(); i++ {
+				key, err := d.Token()
+				if err != nil {
+					return fmt.Errorf("xaesjson: invalid JSON document: %w", err)
+				}
+				k, _ := json.Marshal(key.(string))
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				out.Write(k)
+				out.WriteByte(':')
+				if err := transformValue(d, out, append(path[:len(path):len(path)], key), f); err != nil {
+					return err
+				}
+			}
+			out.WriteByte('}')
+		case '[':
+			out.WriteByte('[')
+			for i := 0; d.More(); i++ {
+				if i > 0 {
+					out.WriteByte(',')
+				}
+				if err := transformValue(d, out, append(path[:len(path):len(path)], i), f); err != nil {
+					return err
+				}
+			}
+			out.WriteByte(']')
+		}
+		// Consume the closing delimiter.
+		if _, err := d.Token(); err != nil {
+			return fmt.Errorf("xaesjson: invalid JSON document: %w", err)
+		}
+		return nil
+	case nil:
+		out.WriteString("null")
+		return nil
+	default:
+		v, err := json.Marshal(tok)
+		if err != nil {
+			return err
+		}
+		if path == nil {
+			path = []any{}
+		}
+		v, err = f(path, v)
+		if err != nil {
+			return err
+		}
+		out.Write(v)
+		return nil
+	}
+}
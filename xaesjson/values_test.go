@@ -0,0 +1,71 @@
+package xaesjson_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaesjson"
+)
+
+func TestValues(t *testing.T) {
+	aead := xaes256gcm.MustNew(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	doc := []byte(`{"db": {"host": "localhost", "port": 5432, "password": "hunter2"},
+		"debug": false, "admins": ["alice", "bob"], "proxy": null, "ratio": 0.5}`)
+	encrypted, err := xaesjson.EncryptValues(aead, doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"localhost", "5432", "hunter2", "alice", "0.5"} {
+		if strings.Contains(string(encrypted), s) {
+			t.Errorf("%q not encrypted:\n%s", s, encrypted)
+		}
+	}
+	for _, s := range []string{`"db"`, `"password": "xaes:`, `"proxy": null`} {
+		if !strings.Contains(string(encrypted), s) {
+			t.Errorf("%q not preserved:\n%s", s, encrypted)
+		}
+	}
+	if strings.Index(string(encrypted), `"db"`) > strings.Index(string(encrypted), `"admins"`) {
+		t.Errorf("key order not preserved:\n%s", encrypted)
+	}
+
+	decrypted, err := xaesjson.DecryptValues(aead, encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var expected, got any
+	json.Unmarshal(doc, &expected)
+	json.Unmarshal(decrypted, &got)
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("got %s", decrypted)
+	}
+
+	// Values can't be moved between fields.
+	var m map[string]any
+	json.Unmarshal(encrypted, &m)
+	admins := m["admins"].([]any)
+	admins[0], admins[1] = admins[1], admins[0]
+	swapped, _ := json.Marshal(m)
+	if _, err := xaesjson.DecryptValues(aead, swapped); err == nil {
+		t.Errorf("decrypted document with swapped values")
+	}
+
+	// Encrypted values can't be replaced with plaintext ones.
+	m["debug"] = true
+	delete(m, "admins")
+	replaced, _ := json.Marshal(m)
+	if _, err := xaesjson.DecryptValues(aead, replaced); err == nil {
+		t.Errorf("decrypted document with a plaintext value")
+	}
+
+	if _, err := xaesjson.EncryptValues(aead, []byte(`{"a": 1} {}`)); err == nil {
+		t.Errorf("encrypted document with trailing data")
+	}
+	if _, err := xaesjson.EncryptValues(aead, []byte(`{"a": `)); err == nil {
+		t.Errorf("encrypted truncated document")
+	}
+}
@@ -0,0 +1,42 @@
+// Package xaesjson encrypts Go values as JSON documents.
+package xaesjson
+
+import (
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+)
+
+var errManualNonces = errors.New("xaesjson: AEAD must generate nonces automatically, use xaes256gcm.New")
+
+// Encrypt marshals v as JSON, and seals it with aead, which must be returned
+// by [filippo.io/xaes256gcm.New]. label is authenticated as additional data,
+// and should identify the type or purpose of v, so that a ciphertext can't be
+// decrypted as a different type.
+func Encrypt[T any](aead cipher.AEAD, v T, label string) ([]byte, error) {
+	if aead.NonceSize() != 0 {
+		return nil, errManualNonces
+	}
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, nil, plaintext, []byte(label)), nil
+}
+
+// Decrypt opens ciphertext with aead and label, and unmarshals the JSON
+// document into a new value of type T.
+func Decrypt[T any](aead cipher.AEAD, ciphertext []byte, label string) (T, error) {
+	var v T
+	if aead.NonceSize() != 0 {
+		return v, errManualNonces
+	}
+	plaintext, err := aead.Open(nil, nil, ciphertext, []byte(label))
+	if err != nil {
+		return v, err
+	}
+	if err := json.Unmarshal(plaintext, &v); err != nil {
+		return v, err
+	}
+	return v, nil
+}
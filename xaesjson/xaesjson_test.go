@@ -0,0 +1,44 @@
+package xaesjson_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaesjson"
+)
+
+type user struct {
+	Name  string
+	Email string
+}
+
+func TestRoundTrip(t *testing.T) {
+	aead, err := xaes256gcm.New(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := user{Name: "Alice", Email: "alice@example.com"}
+	ciphertext, err := xaesjson.Encrypt(aead, u, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := xaesjson.Decrypt[user](aead, ciphertext, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != u {
+		t.Errorf("got %+v", got)
+	}
+	if _, err := xaesjson.Decrypt[user](aead, ciphertext, "invoice"); err == nil {
+		t.Errorf("decrypted with the wrong label")
+	}
+
+	manual, err := xaes256gcm.NewWithManualNonces(bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xaesjson.Encrypt(manual, u, "user"); err == nil {
+		t.Errorf("encrypted with manual nonces")
+	}
+}
@@ -0,0 +1,153 @@
+// Package xaesmsg encrypts the payloads of messages sent through message
+// brokers, such as Kafka, NATS, or SQS, so that event pipelines are encrypted
+// end-to-end across brokers that are not trusted.
+//
+// Payloads are sealed with a [keyset.Keyset], so keys can be rotated while
+// messages encrypted with previous keys are still in flight. The topic,
+// subject, or queue name is bound as additional data, so messages can't be
+// moved to a different topic. The ID of the key is also set in the
+// [KeyIDHeader] message header, to help route and monitor messages without
+// decrypting them.
+//
+// Message headers other than the key ID, and the message metadata such as
+// partition keys and timestamps, are not encrypted or authenticated.
+// Messages can be replayed, duplicated, and dropped by the broker.
+//
+// This package doesn't depend on any client library. To use it with one,
+// implement [Message] with a small adapter around its message type. For
+// example, for a kafka-go message:
+//
+//	type kafkaMessage struct{ *kafka.Message }
+//
+//	func (m kafkaMessage) Topic() string       { return m.Message.Topic }
+//	func (m kafkaMessage) Payload() []byte     { return m.Value }
+//	func (m kafkaMessage) SetPayload(p []byte) { m.Value = p }
+//	func (m kafkaMessage) Header(key string) string {
+//		for _, h := range m.Headers {
+//			if h.Key == key {
+//				return string(h.Value)
+//			}
+//		}
+//		return ""
+//	}
+//	func (m kafkaMessage) SetHeader(key, value string) {
+//		m.Headers = append(m.Headers, kafka.Header{Key: key, Value: []byte(value)})
+//	}
+package xaesmsg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"filippo.io/xaes256gcm/keyset"
+)
+
+// KeyIDHeader is the message header that carries the ID of the key that
+// encrypted the payload, as eight hexadecimal digits. It's a valid header or
+// attribute name for Kafka, NATS, and SQS.
+const KeyIDHeader = "xaes-key-id"
+
+// Message is a broker message, implemented by an adapter around the message
+// type of a client library.
+type Message interface {
+	// Topic returns the topic, subject, or queue name the message is sent to.
+	Topic() string
+	// Payload returns the body of the message.
+	Payload() []byte
+	// SetPayload replaces the body of the message.
+	SetPayload([]byte)
+	// Header returns the value of a message header, or an empty string.
+	Header(key string) string
+	// SetHeader sets a message header.
+	SetHeader(key, value string)
+}
+
+// Codec encrypts and decrypts message payloads. It is safe for concurrent use.
+type Codec struct {
+	ks *keyset.Keyset
+}
+
+// New returns a Codec that encrypts payloads with the primary key of ks, and
+// decrypts them with any key of ks.
+func New(ks *keyset.Keyset) *Codec {
+	return &Codec{ks: ks}
+}
+
+func ad(topic string) []byte {
+	return []byte("filippo.io/xaes256gcm/xaesmsg\x00" + topic)
+}
+
+// Seal encrypts payload for topic. It returns the ciphertext, and the ID of
+// the key that encrypted it, to be sent in the [KeyIDHeader] header.
+func (c *Codec) Seal(topic string, payload []byte) (ciphertext []byte, keyID string, err error) {
+	ciphertext, err = c.ks.Seal(nil, payload, ad(topic))
+	if err != nil {
+		return nil, "", err
+	}
+	keyID, err = KeyID(ciphertext)
+	return ciphertext, keyID, err
+}
+
+// Open decrypts a ciphertext produced by [Codec.Seal] for topic.
+func (c *Codec) Open(topic string, ciphertext []byte) ([]byte, error) {
+	payload, err := c.ks.Open(nil, ciphertext, ad(topic))
+	if err != nil {
+		return nil, fmt.Errorf("xaesmsg: failed to decrypt message for topic %q: %w", topic, err)
+	}
+	return payload, nil
+}
+
+// SealMessage encrypts the payload of m in place, and sets its [KeyIDHeader]
+// header.
+func (c *Codec) SealMessage(m Message) error {
+	ciphertext, keyID, err := c.Seal(m.Topic(), m.Payload())
+	if err != nil {
+		return err
+	}
+	m.SetPayload(ciphertext)
+	m.SetHeader(KeyIDHeader, keyID)
+	return nil
+}
+
+// OpenMessage decrypts the payload of m in place. It returns an error if the
+// [KeyIDHeader] header is missing or doesn't match the payload, since that
+// indicates the message was not produced by [Codec.SealMessage].
+func (c *Codec) OpenMessage(m Message) error {
+	keyID, err := KeyID(m.Payload())
+	if err != nil {
+		return err
+	}
+	if h := m.Header(KeyIDHeader); h != keyID {
+		return fmt.Errorf("xaesmsg: key ID header %q doesn't match payload key ID %s", h, keyID)
+	}
+	payload, err := c.Open(m.Topic(), m.Payload())
+	if err != nil {
+		return err
+	}
+	m.SetPayload(payload)
+	return nil
+}
+
+// KeyID returns the ID of the key that encrypted ciphertext, formatted like
+// the [KeyIDHeader] header, without decrypting it.
+func KeyID(ciphertext []byte) (string, error) {
+	id, err := keyset.KeyID(ciphertext)
+	if err != nil {
+		return "", errors.New("xaesmsg: ciphertext too short")
+	}
+	return fmt.Sprintf("%08x", id), nil
+}
+
+// ParseKeyID parses the value of a [KeyIDHeader] header into a key ID, as
+// used by [keyset.Keyset].
+func ParseKeyID(header string) (uint32, error) {
+	if len(header) != 8 {
+		return 0, errors.New("xaesmsg: invalid key ID")
+	}
+	id, err := strconv.ParseUint(header, 16, 32)
+	if err != nil {
+		return 0, errors.New("xaesmsg: invalid key ID")
+	}
+	return uint32(id), nil
+}
@@ -0,0 +1,70 @@
+package xaesmsg_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm/keyset"
+	"filippo.io/xaes256gcm/xaesmsg"
+)
+
+type message struct {
+	topic   string
+	payload []byte
+	headers map[string]string
+}
+
+func (m *message) Topic() string               { return m.topic }
+func (m *message) Payload() []byte             { return m.payload }
+func (m *message) SetPayload(p []byte)         { m.payload = p }
+func (m *message) Header(key string) string    { return m.headers[key] }
+func (m *message) SetHeader(key, value string) { m.headers[key] = value }
+
+func TestCodec(t *testing.T) {
+	ks := keyset.New()
+	if _, err := ks.Rotate(0); err != nil {
+		t.Fatal(err)
+	}
+	c := xaesmsg.New(ks)
+
+	m := &message{topic: "orders", payload: []byte("order 42"), headers: map[string]string{}}
+	if err := c.SealMessage(m); err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(m.payload, []byte("order 42")) {
+		t.Errorf("payload not encrypted")
+	}
+	primary, _ := ks.Primary()
+	if id, err := xaesmsg.ParseKeyID(m.headers[xaesmsg.KeyIDHeader]); err != nil || id != primary.ID {
+		t.Errorf("key ID header %q, expected %d", m.headers[xaesmsg.KeyIDHeader], primary.ID)
+	}
+	sealed := append([]byte(nil), m.payload...)
+
+	// Messages encrypted with a previous key still decrypt after rotation.
+	if _, err := ks.Rotate(0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.OpenMessage(m); err != nil {
+		t.Fatal(err)
+	}
+	if string(m.payload) != "order 42" {
+		t.Errorf("got %q", m.payload)
+	}
+
+	moved := &message{topic: "refunds", payload: sealed, headers: m.headers}
+	if err := c.OpenMessage(moved); err == nil {
+		t.Errorf("message moved to a different topic decrypted")
+	}
+	missing := &message{topic: "orders", payload: sealed, headers: map[string]string{}}
+	if err := c.OpenMessage(missing); err == nil {
+		t.Errorf("message without key ID header decrypted")
+	}
+	if _, err := c.Open("orders", sealed[:10]); err == nil {
+		t.Errorf("truncated message decrypted")
+	}
+	for _, bad := range []string{"", "1234567", "123456789", "1234567g"} {
+		if _, err := xaesmsg.ParseKeyID(bad); err == nil {
+			t.Errorf("parsed invalid key ID %q", bad)
+		}
+	}
+}
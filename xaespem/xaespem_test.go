@@ -0,0 +1,73 @@
+package xaespem_test
+
+import (
+	"encoding/pem"
+	"errors"
+	"strings"
+	"testing"
+
+	"filippo.io/xaes256gcm/xaespem"
+)
+
+func TestRoundTrip(t *testing.T) {
+	data := []byte("private key material")
+	b, err := xaespem.EncryptPEMBlock(nil, "EC PRIVATE KEY", data, []byte("hunter2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !xaespem.IsEncryptedPEMBlock(b) {
+		t.Errorf("block not reported as encrypted")
+	}
+	if !strings.HasPrefix(b.Headers[xaespem.EncryptionHeader], "argon2id$v=19$m=65536,t=3,p=4$") {
+		t.Errorf("unexpected header %q", b.Headers[xaespem.EncryptionHeader])
+	}
+
+	b, _ = pem.Decode(pem.EncodeToMemory(b))
+	got, err := xaespem.DecryptPEMBlock(b, []byte("hunter2"))
+	if err != nil || string(got) != string(data) {
+		t.Fatalf("got %q, %v", got, err)
+	}
+
+	if _, err := xaespem.DecryptPEMBlock(b, []byte("hunter3")); err != xaespem.ErrIncorrectPassword {
+		t.Errorf("expected ErrIncorrectPassword, got %v", err)
+	}
+
+	retyped := *b
+	retyped.Type = "RSA PRIVATE KEY"
+	if _, err := xaespem.DecryptPEMBlock(&retyped, []byte("hunter2")); err != xaespem.ErrIncorrectPassword {
+		t.Errorf("changed type: expected ErrIncorrectPassword, got %v", err)
+	}
+
+	extra := *b
+	extra.Headers = map[string]string{"Comment": "added"}
+	for k, v := range b.Headers {
+		extra.Headers[k] = v
+	}
+	if _, err := xaespem.DecryptPEMBlock(&extra, []byte("hunter2")); err != xaespem.ErrIncorrectPassword {
+		t.Errorf("added header: expected ErrIncorrectPassword, got %v", err)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	plain := &pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("x")}
+	if xaespem.IsEncryptedPEMBlock(plain) {
+		t.Errorf("plain block reported as encrypted")
+	}
+	if _, err := xaespem.DecryptPEMBlock(plain, nil); err == nil {
+		t.Errorf("decrypted a plain block")
+	}
+
+	for _, h := range []string{
+		"",
+		"argon2id$v=19$m=65536,t=3,p=4$",
+		"argon2id$v=16$m=65536,t=3,p=4$AAAAAAAAAAAAAAAAAAAAAA",
+		"argon2id$v=19$m=4194304,t=3,p=4$AAAAAAAAAAAAAAAAAAAAAA",
+		"argon2id$v=19$m=65536,t=0,p=4$AAAAAAAAAAAAAAAAAAAAAA",
+		"argon2id$v=19$m=065536,t=3,p=4$AAAAAAAAAAAAAAAAAAAAAA",
+	} {
+		b := &pem.Block{Type: "X", Headers: map[string]string{xaespem.EncryptionHeader: h}}
+		if _, err := xaespem.DecryptPEMBlock(b, nil); err == nil || errors.Is(err, xaespem.ErrIncorrectPassword) {
+			t.Errorf("header %q: expected parse error, got %v", h, err)
+		}
+	}
+}
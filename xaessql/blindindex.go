@@ -0,0 +1,55 @@
+package xaessql
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"filippo.io/xaes256gcm"
+	"golang.org/x/crypto/hkdf"
+)
+
+// BlindIndex computes stable tokens for equality queries over an encrypted
+// column, which is otherwise impossible since encryption is randomized.
+//
+// Tokens are HMAC-SHA256 of the value, with a key derived from the column key
+// with HKDF-SHA256 and a label distinct from the encryption context, and the
+// table and column names. Tokens reveal which rows have equal values, but
+// nothing else about the values, unless they have low entropy and can be
+// guessed offline by someone who has the key.
+//
+//	idx, err := xaessql.NewBlindIndex(key, "users", "email")
+//	_, err = db.Exec("INSERT INTO users (email, email_idx) VALUES (?, ?)",
+//		xaessql.EncryptedString{Column: email, String: addr}, idx.Token([]byte(addr)))
+//	rows, err := db.Query("SELECT id FROM users WHERE email_idx = ?", idx.Token([]byte(addr)))
+type BlindIndex struct {
+	key []byte
+}
+
+// NewBlindIndex returns a BlindIndex for the table and column, using the same
+// 32-byte key as the encrypted column.
+func NewBlindIndex(key []byte, table, column string) (*BlindIndex, error) {
+	if len(key) != xaes256gcm.KeySize {
+		return nil, errors.New("xaessql: bad key length")
+	}
+	if len(table) > 0xffff {
+		return nil, errors.New("xaessql: table name too long")
+	}
+	info := []byte("xaessql blind index\x00")
+	info = append(info, byte(len(table)>>8), byte(len(table)))
+	info = append(info, table...)
+	info = append(info, column...)
+	b := &BlindIndex{key: make([]byte, sha256.Size)}
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, info), b.key); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Token returns the 32-byte blind index token for value.
+func (b *BlindIndex) Token(value []byte) []byte {
+	h := hmac.New(sha256.New, b.key)
+	h.Write(value)
+	return h.Sum(nil)
+}
@@ -0,0 +1,149 @@
+// Package xaessql implements transparently encrypted database/sql column
+// values.
+//
+// Values are sealed with XAES-256-GCM and random nonces when written, and
+// opened when scanned, with the table and column names bound as additional
+// data, so that a ciphertext copied to a different column fails to decrypt.
+//
+//	ssn, err := xaessql.NewColumn(key, "users", "ssn")
+//	_, err = db.Exec("INSERT INTO users (id, ssn) VALUES (?, ?)",
+//		id, xaessql.EncryptedString{Column: ssn, String: "123-45-6789"})
+//	v := xaessql.EncryptedString{Column: ssn}
+//	err = db.QueryRow("SELECT ssn FROM users WHERE id = ?", id).Scan(&v)
+//
+// Encrypted values are stored as binary blobs, [xaes256gcm.Overhead] bytes
+// longer than the plaintext.
+package xaessql
+
+import (
+	"crypto/cipher"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+
+	"filippo.io/xaes256gcm"
+)
+
+// Column is the key and context of an encrypted column. It is safe for
+// concurrent use.
+type Column struct {
+	aead cipher.AEAD
+	ad   []byte
+}
+
+// NewColumn returns a Column that encrypts values with the 32-byte key,
+// binding the table and column names. Either can be empty.
+func NewColumn(key []byte, table, column string) (*Column, error) {
+	aead, err := xaes256gcm.New(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(table) > 0xffff {
+		return nil, errors.New("xaessql: table name too long")
+	}
+	ad := make([]byte, 0, 2+len(table)+len(column))
+	ad = append(ad, byte(len(table)>>8), byte(len(table)))
+	ad = append(ad, table...)
+	ad = append(ad, column...)
+	return &Column{aead: aead, ad: ad}, nil
+}
+
+func (c *Column) seal(plaintext []byte) []byte {
+	return c.aead.Seal(nil, nil, plaintext, c.ad)
+}
+
+func (c *Column) open(src any) ([]byte, error) {
+	var ciphertext []byte
+	switch src := src.(type) {
+	case []byte:
+		ciphertext = src
+	case string:
+		ciphertext = []byte(src)
+	default:
+		return nil, fmt.Errorf("xaessql: unsupported column type %T", src)
+	}
+	plaintext, err := c.aead.Open(nil, nil, ciphertext, c.ad)
+	if err != nil {
+		return nil, errors.New("xaessql: failed to decrypt column value")
+	}
+	return plaintext, nil
+}
+
+var errNoColumn = errors.New("xaessql: Column is not set")
+
+// EncryptedBytes is a []byte column value that is encrypted by Column. A nil
+// Bytes is stored as NULL.
+type EncryptedBytes struct {
+	Column *Column
+	Bytes  []byte
+}
+
+var (
+	_ driver.Valuer = EncryptedBytes{}
+	_ sql.Scanner   = &EncryptedBytes{}
+)
+
+// Value implements [driver.Valuer] by encrypting Bytes.
+func (b EncryptedBytes) Value() (driver.Value, error) {
+	if b.Column == nil {
+		return nil, errNoColumn
+	}
+	if b.Bytes == nil {
+		return nil, nil
+	}
+	return b.Column.seal(b.Bytes), nil
+}
+
+// Scan implements [sql.Scanner] by decrypting src into Bytes.
+func (b *EncryptedBytes) Scan(src any) error {
+	if b.Column == nil {
+		return errNoColumn
+	}
+	if src == nil {
+		b.Bytes = nil
+		return nil
+	}
+	plaintext, err := b.Column.open(src)
+	if err != nil {
+		return err
+	}
+	b.Bytes = plaintext
+	return nil
+}
+
+// EncryptedString is a string column value that is encrypted by Column. It
+// can't be NULL, use [EncryptedBytes] for nullable columns.
+type EncryptedString struct {
+	Column *Column
+	String string
+}
+
+var (
+	_ driver.Valuer = EncryptedString{}
+	_ sql.Scanner   = &EncryptedString{}
+)
+
+// Value implements [driver.Valuer] by encrypting String.
+func (s EncryptedString) Value() (driver.Value, error) {
+	if s.Column == nil {
+		return nil, errNoColumn
+	}
+	return s.Column.seal([]byte(s.String)), nil
+}
+
+// Scan implements [sql.Scanner] by decrypting src into String.
+func (s *EncryptedString) Scan(src any) error {
+	if s.Column == nil {
+		return errNoColumn
+	}
+	if src == nil {
+		return errors.New("xaessql: NULL value scanned into EncryptedString")
+	}
+	plaintext, err := s.Column.open(src)
+	if err != nil {
+		return err
+	}
+	s.String = string(plaintext)
+	return nil
+}
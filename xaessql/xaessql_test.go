@@ -0,0 +1,77 @@
+package xaessql_test
+
+import (
+	"bytes"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaessql"
+)
+
+func TestColumns(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	ssn, err := xaessql.NewColumn(key, "users", "ssn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	email, err := xaessql.NewColumn(key, "users", "email")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := xaessql.EncryptedString{Column: ssn, String: "123-45-6789"}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := xaessql.EncryptedString{Column: ssn}
+	if err := s.Scan(v); err != nil {
+		t.Fatal(err)
+	}
+	if s.String != "123-45-6789" {
+		t.Errorf("got %q", s.String)
+	}
+	if err := s.Scan(string(v.([]byte))); err != nil || s.String != "123-45-6789" {
+		t.Errorf("scanning string: %q, %v", s.String, err)
+	}
+
+	wrong := xaessql.EncryptedString{Column: email}
+	if err := wrong.Scan(v); err == nil {
+		t.Errorf("value decrypted as a different column")
+	}
+	if err := s.Scan(nil); err == nil {
+		t.Errorf("NULL scanned into EncryptedString")
+	}
+
+	if v, err := (xaessql.EncryptedBytes{Column: ssn}).Value(); err != nil || v != nil {
+		t.Errorf("nil Bytes: %v, %v", v, err)
+	}
+	b := xaessql.EncryptedBytes{Column: ssn, Bytes: []byte("x")}
+	if err := b.Scan(nil); err != nil || b.Bytes != nil {
+		t.Errorf("NULL: %q, %v", b.Bytes, err)
+	}
+	if _, err := (xaessql.EncryptedBytes{Bytes: []byte("x")}).Value(); err == nil {
+		t.Errorf("Value without Column succeeded")
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	key := bytes.Repeat([]byte{0x01}, xaes256gcm.KeySize)
+	idx, err := xaessql.NewBlindIndex(key, "users", "email")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := xaessql.NewBlindIndex(key, "users", "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := idx.Token([]byte("alice@example.com"))
+	if !bytes.Equal(a, idx.Token([]byte("alice@example.com"))) {
+		t.Errorf("tokens are not stable")
+	}
+	if bytes.Equal(a, idx.Token([]byte("bob@example.com"))) {
+		t.Errorf("different values have the same token")
+	}
+	if bytes.Equal(a, other.Token([]byte("alice@example.com"))) {
+		t.Errorf("different columns have the same token")
+	}
+}
@@ -0,0 +1,248 @@
+// Package xaesssh wraps XAES-256-GCM keys to SSH public keys, so that secrets
+// can be encrypted to the existing ssh-ed25519 and ecdsa-sha2-nistp* keys of
+// their recipients, like age does.
+//
+// Ed25519 keys are converted to X25519 keys, and ECDSA keys are used for ECDH
+// on their curve. A wrapped key has the form
+//
+//	tag || ephemeral share || WrapKey(KEK, DEK)
+//
+// where tag is the first four bytes of the SHA-256 hash of the SSH public key
+// in wire format, and the KEK is derived with HKDF-SHA256 from the ECDH shared
+// secret, salted with the ephemeral share and the SSH public key.
+package xaesssh
+
+import (
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"filippo.io/edwards25519"
+	"filippo.io/xaes256gcm"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	tagSize = 4
+	info    = "filippo.io/xaes256gcm/xaesssh "
+)
+
+// ErrIncorrectIdentity is returned by [Identity.UnwrapKey] and
+// [Identity.Decrypt] if the key was wrapped to a different SSH key.
+var ErrIncorrectIdentity = errors.New("xaesssh: key not wrapped for this identity")
+
+// Recipient is an SSH public key that keys can be wrapped to.
+type Recipient struct {
+	sshKey ssh.PublicKey
+	pub    *ecdh.PublicKey
+}
+
+// NewRecipient returns a Recipient for an ssh-ed25519 or ecdsa-sha2-nistp256,
+// -nistp384, or -nistp521 public key.
+func NewRecipient(pk ssh.PublicKey) (*Recipient, error) {
+	cpk, ok := pk.(ssh.CryptoPublicKey)
+	if !ok {
+		return nil, fmt.Errorf("xaesssh: unsupported SSH key type %q", pk.Type())
+	}
+	var pub *ecdh.PublicKey
+	switch k := cpk.CryptoPublicKey().(type) {
+	case ed25519.PublicKey:
+		p, err := new(edwards25519.Point).SetBytes(k)
+		if err != nil {
+			return nil, errors.New("xaesssh: invalid Ed25519 public key")
+		}
+		pub, err = ecdh.X25519().NewPublicKey(p.BytesMontgomery())
+		if err != nil {
+			return nil, errors.New("xaesssh: invalid Ed25519 public key")
+		}
+	case *ecdsa.PublicKey:
+		var err error
+		pub, err = k.ECDH()
+		if err != nil {
+			return nil, errors.New("xaesssh: invalid ECDSA public key")
+		}
+	default:
+		return nil, fmt.Errorf("xaesssh: unsupported SSH key type %q", pk.Type())
+	}
+	return &Recipient{sshKey: pk, pub: pub}, nil
+}
+
+// ParseRecipient parses a public key in the authorized_keys format, such as a
+// line of a .pub file or of https://github.com/<username>.keys, and returns a
+// Recipient for it.
+func ParseRecipient(authorizedKey string) (*Recipient, error) {
+	pk, _, _, _, err := ssh.ParseAuthorizedKey([]byte(authorizedKey))
+	if err != nil {
+		return nil, fmt.Errorf("xaesssh: failed to parse SSH public key: %v", err)
+	}
+	return NewRecipient(pk)
+}
+
+// WrappedKeySize returns the size of keys wrapped with r.
+func (r *Recipient) WrappedKeySize() int {
+	return tagSize + len(r.pub.Bytes()) + xaes256gcm.WrappedKeySize
+}
+
+// WrapKey encrypts dek to the recipient's SSH key, using a fresh ephemeral key.
+func (r *Recipient) WrapKey(dek xaes256gcm.Key) ([]byte, error) {
+	eph, err := r.pub.Curve().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := eph.ECDH(r.pub)
+	if err != nil {
+		return nil, errors.New("xaesssh: invalid recipient key")
+	}
+	share := eph.PublicKey().Bytes()
+	kek, err := deriveKEK(r.sshKey, shared, share)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, r.WrappedKeySize())
+	out = append(out, tag(r.sshKey)...)
+	out = append(out, share...)
+	return append(out, xaes256gcm.WrapKey(kek, dek)...), nil
+}
+
+// Encrypt generates a new data encryption key, wraps it to r, and encrypts
+// plaintext with it. The wrapped key is prepended to the ciphertext.
+func (r *Recipient) Encrypt(plaintext []byte) ([]byte, error) {
+	dek := xaes256gcm.GenerateKey()
+	out, err := r.WrapKey(dek)
+	if err != nil {
+		return nil, err
+	}
+	return dek.AEAD().Seal(out, nil, plaintext, out), nil
+}
+
+// String returns the recipient's public key in the authorized_keys format.
+func (r *Recipient) String() string {
+	return strings.TrimSuffix(string(ssh.MarshalAuthorizedKey(r.sshKey)), "\n")
+}
+
+// Identity is an SSH private key that unwraps keys wrapped to the matching
+// Recipient.
+type Identity struct {
+	sshKey ssh.PublicKey
+	priv   *ecdh.PrivateKey
+}
+
+// NewIdentity returns an Identity for an ed25519.PrivateKey or
+// *ecdsa.PrivateKey, or pointers to them, as returned by
+// [ssh.ParseRawPrivateKey].
+func NewIdentity(key any) (*Identity, error) {
+	var priv *ecdh.PrivateKey
+	var pub any
+	switch k := key.(type) {
+	case *ed25519.PrivateKey:
+		return NewIdentity(*k)
+	case ed25519.PrivateKey:
+		if len(k) != ed25519.PrivateKeySize {
+			return nil, errors.New("xaesssh: invalid Ed25519 private key")
+		}
+		h := sha512.Sum512(k.Seed())
+		var err error
+		priv, err = ecdh.X25519().NewPrivateKey(h[:32])
+		if err != nil {
+			return nil, errors.New("xaesssh: invalid Ed25519 private key")
+		}
+		pub = k.Public()
+	case *ecdsa.PrivateKey:
+		var err error
+		priv, err = k.ECDH()
+		if err != nil {
+			return nil, errors.New("xaesssh: invalid ECDSA private key")
+		}
+		pub = k.Public()
+	default:
+		return nil, fmt.Errorf("xaesssh: unsupported private key type %T", key)
+	}
+	sshKey, err := ssh.NewPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{sshKey: sshKey, priv: priv}, nil
+}
+
+// ParseIdentity parses an unencrypted SSH private key in PEM or OpenSSH format
+// and returns an Identity for it.
+func ParseIdentity(pemBytes []byte) (*Identity, error) {
+	key, err := ssh.ParseRawPrivateKey(pemBytes)
+	if err != nil {
+		return nil, fmt.Errorf("xaesssh: failed to parse SSH private key: %v", err)
+	}
+	return NewIdentity(key)
+}
+
+// Recipient returns the Recipient for the identity's public key.
+func (i *Identity) Recipient() *Recipient {
+	return &Recipient{sshKey: i.sshKey, pub: i.priv.PublicKey()}
+}
+
+// UnwrapKey decrypts a key wrapped with [Recipient.WrapKey].
+func (i *Identity) UnwrapKey(wrapped []byte) (xaes256gcm.Key, error) {
+	shareSize := len(i.priv.PublicKey().Bytes())
+	if len(wrapped) != tagSize+shareSize+xaes256gcm.WrappedKeySize {
+		return xaes256gcm.Key{}, errors.New("xaesssh: wrapped key has the wrong size")
+	}
+	if string(wrapped[:tagSize]) != string(tag(i.sshKey)) {
+		return xaes256gcm.Key{}, ErrIncorrectIdentity
+	}
+	share := wrapped[tagSize : tagSize+shareSize]
+	eph, err := i.priv.Curve().NewPublicKey(share)
+	if err != nil {
+		return xaes256gcm.Key{}, errors.New("xaesssh: invalid ephemeral share")
+	}
+	shared, err := i.priv.ECDH(eph)
+	if err != nil {
+		return xaes256gcm.Key{}, errors.New("xaesssh: invalid ephemeral share")
+	}
+	kek, err := deriveKEK(i.sshKey, shared, share)
+	if err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	dek, err := xaes256gcm.UnwrapKey(kek, wrapped[tagSize+shareSize:])
+	if err != nil {
+		return xaes256gcm.Key{}, ErrIncorrectIdentity
+	}
+	return dek, nil
+}
+
+// Decrypt decrypts a ciphertext produced by [Recipient.Encrypt].
+func (i *Identity) Decrypt(ciphertext []byte) ([]byte, error) {
+	n := i.Recipient().WrappedKeySize()
+	if len(ciphertext) < n+xaes256gcm.Overhead {
+		return nil, errors.New("xaesssh: ciphertext too short")
+	}
+	dek, err := i.UnwrapKey(ciphertext[:n])
+	if err != nil {
+		return nil, err
+	}
+	return dek.AEAD().Open(nil, nil, ciphertext[n:], ciphertext[:n])
+}
+
+func tag(pk ssh.PublicKey) []byte {
+	h := sha256.Sum256(pk.Marshal())
+	return h[:tagSize]
+}
+
+func deriveKEK(pk ssh.PublicKey, shared, share []byte) (xaes256gcm.Key, error) {
+	salt := make([]byte, 0, len(share)+len(pk.Marshal()))
+	salt = append(salt, share...)
+	salt = append(salt, pk.Marshal()...)
+	kek := make([]byte, xaes256gcm.KeySize)
+	h := hkdf.New(sha256.New, shared, salt, []byte(info+pk.Type()))
+	if _, err := io.ReadFull(h, kek); err != nil {
+		return xaes256gcm.Key{}, err
+	}
+	return xaes256gcm.NewKey(kek)
+}
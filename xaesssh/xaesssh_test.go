@@ -0,0 +1,131 @@
+package xaesssh_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+
+	"filippo.io/xaes256gcm"
+	"filippo.io/xaes256gcm/xaesssh"
+	"golang.org/x/crypto/ssh"
+)
+
+func generateKeys(t *testing.T) map[string]any {
+	_, ed, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := map[string]any{"ed25519": ed}
+	for name, curve := range map[string]elliptic.Curve{
+		"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521(),
+	} {
+		k, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys[name] = k
+	}
+	return keys
+}
+
+func TestRoundTrip(t *testing.T) {
+	for name, key := range generateKeys(t) {
+		t.Run(name, func(t *testing.T) {
+			i, err := xaesssh.NewIdentity(key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sshKey, err := ssh.NewPublicKey(key.(interface{ Public() crypto.PublicKey }).Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := xaesssh.ParseRecipient(string(ssh.MarshalAuthorizedKey(sshKey)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.String() != i.Recipient().String() {
+				t.Errorf("recipient mismatch: %s != %s", r, i.Recipient())
+			}
+
+			dek := xaes256gcm.GenerateKey()
+			wrapped, err := r.WrapKey(dek)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(wrapped) != r.WrappedKeySize() {
+				t.Errorf("wrapped key is %d bytes, expected %d", len(wrapped), r.WrappedKeySize())
+			}
+			got, err := i.UnwrapKey(wrapped)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), dek.Bytes()) {
+				t.Errorf("unwrapped key mismatch")
+			}
+
+			ciphertext, err := r.Encrypt([]byte("hello, ssh"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			plaintext, err := i.Decrypt(ciphertext)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(plaintext) != "hello, ssh" {
+				t.Errorf("got %q", plaintext)
+			}
+
+			ciphertext[len(ciphertext)-1] ^= 1
+			if _, err := i.Decrypt(ciphertext); err == nil {
+				t.Errorf("tampered ciphertext decrypted")
+			}
+			wrapped[len(wrapped)-1] ^= 1
+			if _, err := i.UnwrapKey(wrapped); !errors.Is(err, xaesssh.ErrIncorrectIdentity) {
+				t.Errorf("expected ErrIncorrectIdentity, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWrongIdentity(t *testing.T) {
+	_, k1, _ := ed25519.GenerateKey(rand.Reader)
+	_, k2, _ := ed25519.GenerateKey(rand.Reader)
+	i1, err := xaesssh.NewIdentity(k1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i2, err := xaesssh.NewIdentity(k2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := i1.Recipient().Encrypt([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := i2.Decrypt(ciphertext); !errors.Is(err, xaesssh.ErrIncorrectIdentity) {
+		t.Errorf("expected ErrIncorrectIdentity, got %v", err)
+	}
+}
+
+func TestUnsupportedKey(t *testing.T) {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk, err := ssh.NewPublicKey(&k.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := xaesssh.NewRecipient(pk); err == nil {
+		t.Errorf("RSA key accepted")
+	}
+	if _, err := xaesssh.NewIdentity(k); err == nil {
+		t.Errorf("RSA key accepted")
+	}
+}